@@ -0,0 +1,150 @@
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Maximum length of NULL-terminated strings read from the image.
+const maxStringLen = 4096
+
+// readHeader reads size bytes of the header structName at the given file
+// offset. In tolerant mode, bytes past the end of the file are zero-filled and
+// recorded as an anomaly.
+func (file *File) readHeader(structName string, off, size int64) ([]byte, error) {
+	if err := file.checkAlloc(structName, size); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	n, err := file.r.ReadAt(buf, off)
+	if int64(n) == size {
+		return buf, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+		if file.conf.tolerant {
+			file.addAnomaly(AnomalyTruncated, SeverityError, off, "%s truncated; zero-filled %d of %d bytes", structName, size-int64(n), size)
+			return buf, nil
+		}
+	}
+	return nil, readError(structName, off, err)
+}
+
+// tolerate reports whether the given error of a partially parsed structure is
+// tolerated, in which case it is recorded as an anomaly. Errors are only
+// tolerated in tolerant mode.
+func (file *File) tolerate(err error) bool {
+	if !file.conf.tolerant {
+		return false
+	}
+	off := int64(-1)
+	if e, ok := err.(*FormatError); ok {
+		off = e.Offset
+	}
+	file.addAnomaly(AnomalyMalformed, SeverityError, off, "%v", err)
+	return true
+}
+
+// relAddrToOffset returns the file offset of the given address, relative to the
+// image base, and the number of bytes readable from the file at that offset
+// before reaching the end of the enclosing section.
+func (file *File) relAddrToOffset(relAddr uint32) (off int64, n int64, err error) {
+	sectHdr, err := file.SectHeaderByRelAddr(relAddr)
+	if err != nil {
+		return 0, 0, err
+	}
+	if sectHdr != nil {
+		delta := int64(relAddr - sectHdr.RelAddr)
+		start, size := file.sectExtent(sectHdr)
+		if delta >= size {
+			return 0, 0, &AddrError{RelAddr: relAddr, Msg: fmt.Sprintf("of section %q not backed by file data", sectHdr.Name)}
+		}
+		return start + delta, size - delta, nil
+	}
+	// Addresses preceding the first section map directly to file offsets of
+	// the headers.
+	hdrEnd, err := file.hdrEnd()
+	if err != nil {
+		return 0, 0, err
+	}
+	if int64(relAddr) < hdrEnd {
+		return int64(relAddr), hdrEnd - int64(relAddr), nil
+	}
+	return 0, 0, &AddrError{RelAddr: relAddr, Msg: "not contained within any section"}
+}
+
+// relAddrReader returns a reader of the contents of file, starting at the given
+// address (relative to the image base) and ending at the end of the enclosing
+// section.
+func (file *File) relAddrReader(relAddr uint32) (*io.SectionReader, error) {
+	off, n, err := file.relAddrToOffset(relAddr)
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(file.r, off, n), nil
+}
+
+// readRelAddr reads the data structure v, named structName, from the given
+// address relative to the image base.
+func (file *File) readRelAddr(relAddr uint32, v interface{}, structName string) error {
+	off, n, err := file.relAddrToOffset(relAddr)
+	if err != nil {
+		return &FormatError{Struct: structName, Offset: -1, Err: err}
+	}
+	sr := io.NewSectionReader(file.r, off, n)
+	if err := binary.Read(sr, binary.LittleEndian, v); err != nil {
+		return readError(structName, off, err)
+	}
+	return nil
+}
+
+// readMemRelAddr reads len(buf) bytes, named structName, from the given address
+// relative to the image base, as seen by the loader; bytes within the virtual
+// size of a section but past its raw data are read as zero.
+func (file *File) readMemRelAddr(relAddr uint32, buf []byte, structName string) error {
+	sectHdr, err := file.SectHeaderByRelAddr(relAddr)
+	if err != nil {
+		return &FormatError{Struct: structName, Offset: -1, Err: err}
+	}
+	if sectHdr == nil {
+		return file.readRelAddr(relAddr, buf, structName)
+	}
+	delta := int64(relAddr - sectHdr.RelAddr)
+	if delta+int64(len(buf)) > int64(sectHdr.virtSize()) {
+		return &FormatError{Struct: structName, Offset: -1, Err: &AddrError{RelAddr: relAddr, Msg: fmt.Sprintf("extends past end of section %q", sectHdr.Name)}}
+	}
+	for i := range buf {
+		buf[i] = 0
+	}
+	start, size := file.sectExtent(sectHdr)
+	if delta >= size {
+		return nil
+	}
+	n := size - delta
+	if n > int64(len(buf)) {
+		n = int64(len(buf))
+	}
+	if _, err := file.r.ReadAt(buf[:n], start+delta); err != nil {
+		return readError(structName, start+delta, err)
+	}
+	return nil
+}
+
+// readStringRelAddr reads a NULL-terminated string, named structName, from the
+// given address relative to the image base.
+func (file *File) readStringRelAddr(relAddr uint32, structName string) (string, error) {
+	off, n, err := file.relAddrToOffset(relAddr)
+	if err != nil {
+		return "", &FormatError{Struct: structName, Offset: -1, Err: err}
+	}
+	if n > maxStringLen {
+		n = maxStringLen
+	}
+	buf := make([]byte, n)
+	m, err := file.r.ReadAt(buf, off)
+	if err != nil && err != io.EOF {
+		return "", readError(structName, off, err)
+	}
+	return parseString(buf[:m]), nil
+}
@@ -0,0 +1,392 @@
+package pe
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// An Anomaly records an oddity of a PE file which does not prevent parsing,
+// such as non-zero reserved fields or inconsistent header values.
+type Anomaly struct {
+	// Anomaly code.
+	Code AnomalyCode
+	// Description of the anomaly.
+	Msg string
+	// File offset of the offending structure; or -1 if unknown.
+	Offset int64
+	// Severity of the anomaly.
+	Severity Severity
+}
+
+func (a *Anomaly) String() string {
+	if a.Offset >= 0 {
+		return fmt.Sprintf("%v: %v at offset 0x%X: %s", a.Severity, a.Code, a.Offset, a.Msg)
+	}
+	return fmt.Sprintf("%v: %v: %s", a.Severity, a.Code, a.Msg)
+}
+
+// Severity specifies the severity of an anomaly.
+type Severity uint8
+
+// Anomaly severities.
+const (
+	// SeverityInfo represents a harmless deviation from the specification.
+	SeverityInfo Severity = iota
+	// SeverityWarning represents a deviation which is tolerated by the Windows
+	// loader, but is uncommon in files produced by standard tools.
+	SeverityWarning
+	// SeverityError represents a deviation which is likely to cause the Windows
+	// loader to reject the image.
+	SeverityError
+)
+
+// severityName is a map from Severity to string description.
+var severityName = map[Severity]string{
+	SeverityInfo:    "info",
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (severity Severity) String() string {
+	if s, ok := severityName[severity]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown severity: %d", uint8(severity))
+}
+
+// AnomalyCode identifies the kind of an anomaly.
+type AnomalyCode uint16
+
+// Anomaly codes.
+const (
+	// AnomalyDOSReserved indicates non-zero reserved fields in the DOS header.
+	AnomalyDOSReserved AnomalyCode = iota + 1
+	// AnomalyPEHdrOffset indicates a PE header which overlaps the DOS header or
+	// is misaligned.
+	AnomalyPEHdrOffset
+	// AnomalyOptReserved indicates a non-zero reserved field in the optional
+	// header.
+	AnomalyOptReserved
+	// AnomalyOptHdrSize indicates an optional header size which is inconsistent
+	// with the optional header state and number of data directories.
+	AnomalyOptHdrSize
+	// AnomalyNDataDir indicates a number of data directories other than 16.
+	AnomalyNDataDir
+	// AnomalyAlign indicates invalid section or file alignment.
+	AnomalyAlign
+	// AnomalyHdrSize indicates a header size which is misaligned, too small to
+	// hold the headers, or overlapping the raw data of sections.
+	AnomalyHdrSize
+	// AnomalyImageSize indicates an image size which is misaligned or doesn't
+	// match the extent of the sections.
+	AnomalyImageSize
+	// AnomalyNoSections indicates an image without sections.
+	AnomalyNoSections
+	// AnomalySectOrder indicates sections not in ascending address order.
+	AnomalySectOrder
+	// AnomalySectOverlap indicates sections which overlap in memory.
+	AnomalySectOverlap
+	// AnomalySectRawOverlap indicates sections with overlapping raw data.
+	AnomalySectRawOverlap
+	// AnomalySectVirtMisaligned indicates a section address which is not a
+	// multiple of the section alignment.
+	AnomalySectVirtMisaligned
+	// AnomalySectRawMisaligned indicates a section file offset or size which
+	// is not a multiple of the file alignment.
+	AnomalySectRawMisaligned
+	// AnomalySectBeyondEOF indicates section raw data extending past the end
+	// of the file.
+	AnomalySectBeyondEOF
+	// AnomalyEntryPoint indicates an entry point outside of any executable
+	// section.
+	AnomalyEntryPoint
+	// AnomalyDataDir indicates a data directory outside of the image.
+	AnomalyDataDir
+	// AnomalyTruncated indicates a header extending past the end of the file,
+	// which was zero-filled in tolerant mode.
+	AnomalyTruncated
+	// AnomalyMalformed indicates a malformed structure, which was partially
+	// parsed in tolerant mode.
+	AnomalyMalformed
+)
+
+// anomalyCodeName is a map from AnomalyCode to string description.
+var anomalyCodeName = map[AnomalyCode]string{
+	AnomalyDOSReserved:        "DOS reserved",
+	AnomalyPEHdrOffset:        "PE header offset",
+	AnomalyOptReserved:        "optional header reserved",
+	AnomalyOptHdrSize:         "optional header size",
+	AnomalyNDataDir:           "number of data directories",
+	AnomalyAlign:              "alignment",
+	AnomalyHdrSize:            "header size",
+	AnomalyImageSize:          "image size",
+	AnomalyNoSections:         "no sections",
+	AnomalySectOrder:          "section order",
+	AnomalySectOverlap:        "section overlap",
+	AnomalySectRawOverlap:     "section raw overlap",
+	AnomalySectVirtMisaligned: "section address misaligned",
+	AnomalySectRawMisaligned:  "section raw data misaligned",
+	AnomalySectBeyondEOF:      "section beyond EOF",
+	AnomalyEntryPoint:         "entry point",
+	AnomalyDataDir:            "data directory",
+	AnomalyTruncated:          "truncated",
+	AnomalyMalformed:          "malformed",
+}
+
+func (code AnomalyCode) String() string {
+	if s, ok := anomalyCodeName[code]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown anomaly: %d", uint16(code))
+}
+
+// Anomalies returns the anomalies of file. The anomalies recorded while parsing
+// headers and directories are combined with those of a structural check of the
+// headers and section table, which is performed on first call.
+func (file *File) Anomalies() ([]*Anomaly, error) {
+	if !file.checked {
+		if err := file.parseStruct(file.checkAnomalies); err != nil {
+			return nil, err
+		}
+	}
+	return file.anomalies, nil
+}
+
+// addAnomaly records an anomaly of file. Anomalies found while parsing a
+// structure are pending until the structure has been parsed successfully (see
+// parseStruct).
+func (file *File) addAnomaly(code AnomalyCode, severity Severity, off int64, format string, args ...interface{}) {
+	a := &Anomaly{
+		Code:     code,
+		Msg:      fmt.Sprintf(format, args...),
+		Offset:   off,
+		Severity: severity,
+	}
+	if file.pending != nil {
+		*file.pending = append(*file.pending, a)
+		return
+	}
+	file.anomalies = append(file.anomalies, a)
+}
+
+// parseStruct parses a structure of file using the given parse function. The
+// anomalies found while parsing are collected, and only recorded once the
+// structure has been parsed successfully; a failed parse is retried on next
+// access, and would otherwise record its anomalies twice. Anomalies of nested
+// structures are recorded as soon as those structures have been parsed, as
+// they remain cached even if the enclosing parse fails.
+func (file *File) parseStruct(parse func() error) error {
+	outer := file.pending
+	var pending []*Anomaly
+	file.pending = &pending
+	err := parse()
+	file.pending = outer
+	if err != nil {
+		return err
+	}
+	file.anomalies = append(file.anomalies, pending...)
+	return nil
+}
+
+// checkAnomalies performs a structural check of the headers and section table
+// of file, and records any anomalies found.
+func (file *File) checkAnomalies() error {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return err
+	}
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return err
+	}
+	size, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	sectHdrsOff, err := file.sectHdrsOffset()
+	if err != nil {
+		return err
+	}
+	sectHdrOff := func(i int) int64 {
+		return sectHdrsOff + int64(i)*sectHdrSize
+	}
+
+	// Check the PE header offset.
+	if !file.obj {
+		doshdr, err := file.DOSHeader()
+		if err != nil {
+			return err
+		}
+		const peHdrOffsetOff = 0x3C
+		if doshdr.PEHdrOffset < dosHdrSize {
+			file.addAnomaly(AnomalyPEHdrOffset, SeverityWarning, peHdrOffsetOff, "PE header at 0x%X overlaps DOS header", doshdr.PEHdrOffset)
+		} else if doshdr.PEHdrOffset%4 != 0 {
+			file.addAnomaly(AnomalyPEHdrOffset, SeverityWarning, peHdrOffsetOff, "PE header at 0x%X not 4-byte aligned", doshdr.PEHdrOffset)
+		}
+	}
+
+	// Check section raw data, which is common to images and object files.
+	for i, sectHdr := range sectHdrs {
+		if start, n := file.sectExtent(sectHdr); n > 0 && start+n > size {
+			file.addAnomaly(AnomalySectBeyondEOF, SeverityError, sectHdrOff(i), "raw data of section %q (0x%X-0x%X) extends past end of file (0x%X)", sectHdr.Name, start, start+n, size)
+		}
+	}
+	file.checkRawOverlap(sectHdrs, sectHdrOff)
+	if fileHdr.OptHdrSize == 0 {
+		file.checked = true
+		return nil
+	}
+
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return err
+	}
+	optoff, err := file.optHdrOffset()
+	if err != nil {
+		return err
+	}
+
+	// Check optional header size.
+	dataDirsOff := int64(optHdr32Size)
+	if opthdr.Is64() {
+		dataDirsOff = optHdr64Size
+	}
+	if want := dataDirsOff + int64(opthdr.NDataDir)*8; int64(fileHdr.OptHdrSize) != want {
+		file.addAnomaly(AnomalyOptHdrSize, SeverityWarning, optoff, "optional header size %d differs from the %d bytes required by its state and %d data directories", fileHdr.OptHdrSize, want, opthdr.NDataDir)
+	}
+	if opthdr.NDataDir != 16 {
+		file.addAnomaly(AnomalyNDataDir, SeverityInfo, optoff+dataDirsOff-4, "%d data directories; expected 16", opthdr.NDataDir)
+	}
+
+	// Check alignment.
+	validAlign := true
+	if opthdr.FileAlign == 0 || bits.OnesCount32(opthdr.FileAlign) != 1 {
+		file.addAnomaly(AnomalyAlign, SeverityError, optoff, "file alignment 0x%X not a power of two", opthdr.FileAlign)
+		validAlign = false
+	} else if opthdr.FileAlign < 512 || opthdr.FileAlign > 0x10000 {
+		file.addAnomaly(AnomalyAlign, SeverityWarning, optoff, "file alignment 0x%X outside of range 0x200-0x10000", opthdr.FileAlign)
+	}
+	if opthdr.SectAlign == 0 || bits.OnesCount32(opthdr.SectAlign) != 1 {
+		file.addAnomaly(AnomalyAlign, SeverityError, optoff, "section alignment 0x%X not a power of two", opthdr.SectAlign)
+		validAlign = false
+	} else if opthdr.SectAlign < opthdr.FileAlign {
+		file.addAnomaly(AnomalyAlign, SeverityError, optoff, "section alignment 0x%X smaller than file alignment 0x%X", opthdr.SectAlign, opthdr.FileAlign)
+	}
+
+	// Check header size.
+	hdrsEnd := sectHdrOff(len(sectHdrs))
+	if int64(opthdr.HdrSize) < hdrsEnd {
+		file.addAnomaly(AnomalyHdrSize, SeverityError, optoff, "header size 0x%X smaller than end of section table (0x%X)", opthdr.HdrSize, hdrsEnd)
+	}
+	if validAlign && opthdr.HdrSize%opthdr.FileAlign != 0 {
+		file.addAnomaly(AnomalyHdrSize, SeverityWarning, optoff, "header size 0x%X not a multiple of file alignment 0x%X", opthdr.HdrSize, opthdr.FileAlign)
+	}
+	for i, sectHdr := range sectHdrs {
+		if sectHdr.Size > 0 && sectHdr.Offset > 0 && sectHdr.Offset < opthdr.HdrSize {
+			file.addAnomaly(AnomalyHdrSize, SeverityWarning, sectHdrOff(i), "raw data of section %q at 0x%X overlaps headers (0x%X bytes)", sectHdr.Name, sectHdr.Offset, opthdr.HdrSize)
+		}
+	}
+
+	// Check sections.
+	if len(sectHdrs) == 0 {
+		file.addAnomaly(AnomalyNoSections, SeverityWarning, -1, "image contains no sections")
+	}
+	for i, sectHdr := range sectHdrs {
+		if validAlign && sectHdr.RelAddr%opthdr.SectAlign != 0 {
+			file.addAnomaly(AnomalySectVirtMisaligned, SeverityWarning, sectHdrOff(i), "address 0x%X of section %q not a multiple of section alignment 0x%X", sectHdr.RelAddr, sectHdr.Name, opthdr.SectAlign)
+		}
+		if validAlign && sectHdr.Offset%opthdr.FileAlign != 0 {
+			file.addAnomaly(AnomalySectRawMisaligned, SeverityWarning, sectHdrOff(i), "file offset 0x%X of section %q not a multiple of file alignment 0x%X", sectHdr.Offset, sectHdr.Name, opthdr.FileAlign)
+		}
+		if validAlign && sectHdr.Size%opthdr.FileAlign != 0 {
+			file.addAnomaly(AnomalySectRawMisaligned, SeverityInfo, sectHdrOff(i), "raw size 0x%X of section %q not a multiple of file alignment 0x%X", sectHdr.Size, sectHdr.Name, opthdr.FileAlign)
+		}
+		if i > 0 {
+			prev := sectHdrs[i-1]
+			prevEnd := uint64(prev.RelAddr) + uint64(prev.virtSize())
+			if sectHdr.RelAddr < prev.RelAddr {
+				file.addAnomaly(AnomalySectOrder, SeverityError, sectHdrOff(i), "section %q at 0x%X precedes previous section %q at 0x%X", sectHdr.Name, sectHdr.RelAddr, prev.Name, prev.RelAddr)
+			} else if uint64(sectHdr.RelAddr) < prevEnd {
+				file.addAnomaly(AnomalySectOverlap, SeverityError, sectHdrOff(i), "section %q at 0x%X overlaps previous section %q (0x%X-0x%X)", sectHdr.Name, sectHdr.RelAddr, prev.Name, prev.RelAddr, prevEnd)
+			}
+		}
+	}
+
+	// Check image size.
+	if validAlign && opthdr.ImageSize%opthdr.SectAlign != 0 {
+		file.addAnomaly(AnomalyImageSize, SeverityWarning, optoff, "image size 0x%X not a multiple of section alignment 0x%X", opthdr.ImageSize, opthdr.SectAlign)
+	}
+	if validAlign && len(sectHdrs) > 0 {
+		var end uint64
+		for _, sectHdr := range sectHdrs {
+			sectEnd := alignUp(uint64(sectHdr.RelAddr)+uint64(sectHdr.virtSize()), uint64(opthdr.SectAlign))
+			if sectEnd > end {
+				end = sectEnd
+			}
+		}
+		if uint64(opthdr.ImageSize) != end {
+			file.addAnomaly(AnomalyImageSize, SeverityWarning, optoff, "image size 0x%X differs from end of last section 0x%X", opthdr.ImageSize, end)
+		}
+	}
+
+	// Check entry point.
+	if opthdr.EntryRelAddr != 0 {
+		sectHdr, err := file.SectHeaderByRelAddr(opthdr.EntryRelAddr)
+		if err != nil {
+			return err
+		}
+		switch {
+		case sectHdr == nil:
+			file.addAnomaly(AnomalyEntryPoint, SeverityWarning, optoff, "entry point 0x%X outside of sections", opthdr.EntryRelAddr)
+		case sectHdr.Flags&(SectFlagCode|SectFlagMemExec) == 0:
+			file.addAnomaly(AnomalyEntryPoint, SeverityWarning, optoff, "entry point 0x%X in non-executable section %q", opthdr.EntryRelAddr, sectHdr.Name)
+		}
+	} else if fileHdr.Flags&FlagDLL == 0 {
+		file.addAnomaly(AnomalyEntryPoint, SeverityWarning, optoff, "executable without entry point")
+	}
+
+	// Check data directories. The certificate table is located by file offset
+	// rather than address.
+	for i, dataDir := range opthdr.DataDirs {
+		if dataDir.RelAddr == 0 && dataDir.Size == 0 {
+			continue
+		}
+		off := optoff + dataDirsOff + int64(i)*8
+		if i == DataDirCertificateTable {
+			if int64(dataDir.RelAddr)+int64(dataDir.Size) > size {
+				file.addAnomaly(AnomalyDataDir, SeverityWarning, off, "certificate table (0x%X-0x%X) extends past end of file (0x%X)", dataDir.RelAddr, int64(dataDir.RelAddr)+int64(dataDir.Size), size)
+			} else if int64(dataDir.RelAddr)+int64(dataDir.Size) < size {
+				file.addAnomaly(AnomalyDataDir, SeverityWarning, off, "data appended after certificate table (0x%X-0x%X)", dataDir.RelAddr, int64(dataDir.RelAddr)+int64(dataDir.Size))
+			}
+			continue
+		}
+		if uint64(dataDir.RelAddr)+uint64(dataDir.Size) > uint64(opthdr.ImageSize) {
+			file.addAnomaly(AnomalyDataDir, SeverityWarning, off, "data directory %d (0x%X-0x%X) extends past end of image (0x%X)", i, dataDir.RelAddr, uint64(dataDir.RelAddr)+uint64(dataDir.Size), opthdr.ImageSize)
+		}
+	}
+
+	file.checked = true
+	return nil
+}
+
+// checkRawOverlap records anomalies of sections with overlapping raw data.
+func (file *File) checkRawOverlap(sectHdrs []*SectHeader, sectHdrOff func(i int) int64) {
+	var indices []int
+	for i, sectHdr := range sectHdrs {
+		if sectHdr.Size > 0 && sectHdr.Offset > 0 {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(a, b int) bool {
+		return sectHdrs[indices[a]].Offset < sectHdrs[indices[b]].Offset
+	})
+	for j := 1; j < len(indices); j++ {
+		prev, cur := sectHdrs[indices[j-1]], sectHdrs[indices[j]]
+		prevEnd := int64(prev.Offset) + int64(prev.Size)
+		if int64(cur.Offset) < prevEnd {
+			file.addAnomaly(AnomalySectRawOverlap, SeverityInfo, sectHdrOff(indices[j]), "raw data of section %q at 0x%X overlaps section %q (0x%X-0x%X)", cur.Name, cur.Offset, prev.Name, prev.Offset, prevEnd)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnomalies(t *testing.T) {
+	const (
+		optHdrOff   = synthPEHdrOffset + 4 + coffHdrSize
+		sectHdrsOff = optHdrOff + optHdr32Size + maxDataDirs*8
+	)
+	golden := []struct {
+		name string
+		// Modifies the synthetic image.
+		modify func(b synthBuffer) synthBuffer
+		// Specifies whether to parse in tolerant mode.
+		tolerant bool
+		// Expected anomalies; other anomalies may also be present.
+		want []Anomaly
+	}{
+		{
+			name:   "dos reserved",
+			modify: func(b synthBuffer) synthBuffer { b.put16(0x28+2*3, 0x1234); return b },
+			want:   []Anomaly{{Code: AnomalyDOSReserved, Offset: 0x28 + 2*3, Severity: SeverityInfo}},
+		},
+		{
+			name:   "opt reserved",
+			modify: func(b synthBuffer) synthBuffer { b.put32(optHdrOff+52, 1); return b },
+			want:   []Anomaly{{Code: AnomalyOptReserved, Offset: optHdrOff + 52, Severity: SeverityWarning}},
+		},
+		{
+			name:   "ndatadir",
+			modify: func(b synthBuffer) synthBuffer { b.put32(optHdrOff+92, 10); return b },
+			want: []Anomaly{
+				{Code: AnomalyOptHdrSize, Offset: optHdrOff, Severity: SeverityWarning},
+				{Code: AnomalyNDataDir, Offset: optHdrOff + 92, Severity: SeverityInfo},
+			},
+		},
+		{
+			name:   "file alignment",
+			modify: func(b synthBuffer) synthBuffer { b.put32(optHdrOff+36, 0x300); return b },
+			want:   []Anomaly{{Code: AnomalyAlign, Offset: optHdrOff, Severity: SeverityError}},
+		},
+		{
+			name:   "image size",
+			modify: func(b synthBuffer) synthBuffer { b.put32(optHdrOff+56, 0x3000); return b },
+			want:   []Anomaly{{Code: AnomalyImageSize, Offset: optHdrOff, Severity: SeverityWarning}},
+		},
+		{
+			name:   "entry point",
+			modify: func(b synthBuffer) synthBuffer { b.put32(optHdrOff+16, 0x1800); return b },
+			want:   []Anomaly{{Code: AnomalyEntryPoint, Offset: optHdrOff, Severity: SeverityWarning}},
+		},
+		{
+			name:   "section beyond eof",
+			modify: func(b synthBuffer) synthBuffer { b.put32(sectHdrsOff+16, 0x800); return b },
+			want:   []Anomaly{{Code: AnomalySectBeyondEOF, Offset: sectHdrsOff, Severity: SeverityError}},
+		},
+		{
+			name: "certificate table beyond eof",
+			modify: func(b synthBuffer) synthBuffer {
+				b.put32(synthCertDataDirOff, uint32(len(b)))
+				b.put32(synthCertDataDirOff+4, 0x100)
+				return b
+			},
+			want: []Anomaly{{Code: AnomalyDataDir, Offset: synthCertDataDirOff, Severity: SeverityWarning}},
+		},
+		{
+			name:     "truncated section table",
+			modify:   func(b synthBuffer) synthBuffer { return b[:sectHdrsOff+8] },
+			tolerant: true,
+			want:     []Anomaly{{Code: AnomalyTruncated, Offset: sectHdrsOff, Severity: SeverityError}},
+		},
+	}
+	for _, g := range golden {
+		data := g.modify(synthBuffer(synthImage{}.bytes()))
+		file, err := New(bytes.NewReader(data), WithTolerant(g.tolerant))
+		if err != nil {
+			t.Errorf("%s: unable to create file; %v", g.name, err)
+			continue
+		}
+		anomalies, err := file.Anomalies()
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		for _, want := range g.want {
+			found := false
+			for _, got := range anomalies {
+				if got.Code == want.Code && got.Offset == want.Offset && got.Severity == want.Severity {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("%s: missing anomaly %v at offset 0x%X (%v); got %v", g.name, want.Code, want.Offset, want.Severity, anomalies)
+			}
+		}
+	}
+}
+
+func TestAnomaliesNone(t *testing.T) {
+	for _, img := range []synthImage{{}, {is64: true}, {rich: true}} {
+		file, err := New(bytes.NewReader(img.bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		anomalies, err := file.Anomalies()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(anomalies) != 0 {
+			t.Errorf("is64=%v,rich=%v: unexpected anomalies; got %v", img.is64, img.rich, anomalies)
+		}
+	}
+}
+
+// TestAnomaliesFailedParse checks that a failed parse, which records anomalies
+// before failing, leaves no anomalies behind when retried.
+func TestAnomaliesFailedParse(t *testing.T) {
+	const optHdrOff = synthPEHdrOffset + 4 + coffHdrSize
+	b := synthBuffer(synthImage{}.bytes())
+	// Non-zero reserved field, recorded as an anomaly, followed by an invalid
+	// number of data directories.
+	b.put32(optHdrOff+52, 1)
+	b.put32(optHdrOff+92, maxDataDirs+1)
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := file.OptHeader(); err == nil {
+			t.Fatalf("expected optional header error")
+		}
+	}
+	if len(file.anomalies) != 0 {
+		t.Errorf("unexpected anomalies of failed parse; got %v", file.anomalies)
+	}
+	// The file header, which was parsed successfully, remains cached.
+	if file.fileHdr == nil {
+		t.Errorf("missing file header")
+	}
+}
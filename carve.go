@@ -0,0 +1,258 @@
+package pe
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CarveSource specifies where an embedded PE file was located.
+type CarveSource uint8
+
+// Locations of embedded PE files.
+const (
+	// Contents of a section.
+	CarveSection CarveSource = iota + 1
+	// Contents of a resource leaf.
+	CarveResource
+	// Overlay.
+	CarveOverlay
+)
+
+// carveSourceName is a map from CarveSource to string description.
+var carveSourceName = map[CarveSource]string{
+	CarveSection:  "section",
+	CarveResource: "resource",
+	CarveOverlay:  "overlay",
+}
+
+func (src CarveSource) String() string {
+	if s, ok := carveSourceName[src]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown carve source: 0x%02X", uint8(src))
+}
+
+// EmbeddedFile represents a PE file embedded within another PE file.
+type EmbeddedFile struct {
+	// Embedded PE file; reading from the parent file.
+	File *File
+	// File offset of the embedded file within the parent file.
+	Offset int64
+	// Size of the embedded file in bytes, as computed from the extents of its
+	// headers and sections.
+	Size int64
+	// Specifies whether the embedded file is truncated; i.e. whether its
+	// computed size extends past the end of the data it was located in.
+	Truncated bool
+	// Location of the embedded file.
+	Source CarveSource
+	// Section name or resource path (e.g. "RCDATA/#101/#1033") of the
+	// location; or empty for the overlay.
+	Location string
+}
+
+// Carve locates PE files embedded within the sections, resource leaves and
+// overlay of file, and returns them ordered by offset. Candidates starting
+// with "MZ" are validated by parsing their headers and section table. Embedded
+// files located within both a resource leaf and its enclosing section are
+// reported once, with the resource as location. PE files nested within embedded
+// files are not reported; use Carve on the embedded files to locate them.
+func (file *File) Carve() ([]*EmbeddedFile, error) {
+	fileSize, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	var embedded []*EmbeddedFile
+	seen := make(map[int64]bool)
+	carve := func(src CarveSource, loc string, off, size int64) error {
+		// Section and resource extents are taken from the headers, and may
+		// extend past the end of the file.
+		if off < 0 || off >= fileSize {
+			return nil
+		}
+		if size > fileSize-off {
+			size = fileSize - off
+		}
+		files, err := file.carveRange(off, off+size)
+		if err != nil {
+			return err
+		}
+		for _, e := range files {
+			if seen[e.Offset] {
+				continue
+			}
+			seen[e.Offset] = true
+			e.Source, e.Location = src, loc
+			embedded = append(embedded, e)
+		}
+		return nil
+	}
+
+	// Resource leaves.
+	if root, err := file.Resources(); err == nil && root != nil {
+		var leafErr error
+		root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+			if leafErr != nil || !node.IsLeaf() {
+				return
+			}
+			off, n, err := file.relAddrToOffset(node.Data.RelAddr)
+			if err != nil {
+				return
+			}
+			size := int64(node.Data.Size)
+			if size > n {
+				size = n
+			}
+			leafErr = carve(CarveResource, resourcePathString(path), off, size)
+		})
+		if leafErr != nil {
+			return nil, leafErr
+		}
+	}
+
+	// Overlay.
+	off, size, err := file.overlayExtent()
+	if err != nil {
+		return nil, err
+	}
+	if err := carve(CarveOverlay, "", off, size); err != nil {
+		return nil, err
+	}
+
+	// Sections.
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	for _, sectHdr := range sectHdrs {
+		off, size := file.sectExtent(sectHdr)
+		if err := carve(CarveSection, sectHdr.Name, off, size); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(embedded, func(i, j int) bool {
+		return embedded[i].Offset < embedded[j].Offset
+	})
+	return embedded, nil
+}
+
+// carveRange locates the PE files embedded within the range [start, end) of
+// file. The search resumes after the end of each located file.
+func (file *File) carveRange(start, end int64) ([]*EmbeddedFile, error) {
+	magics := []overlayMagic{{typ: OverlayPE, magic: []byte("MZ")}}
+	var embedded []*EmbeddedFile
+	for off := start; off < end; {
+		pos, _, err := file.findMagic(off, end, magics)
+		if err != nil {
+			return nil, err
+		}
+		if pos == end {
+			break
+		}
+		// The file itself is not embedded.
+		if pos == 0 {
+			off = pos + 1
+			continue
+		}
+		e, ok := file.carveAt(pos, end)
+		if !ok {
+			off = pos + 1
+			continue
+		}
+		embedded = append(embedded, e)
+		off = pos + e.Size
+		if e.Truncated {
+			break
+		}
+	}
+	return embedded, nil
+}
+
+// carveAt validates the candidate embedded PE file located at off, within data
+// ending at end, and reports whether it is valid.
+func (file *File) carveAt(off, end int64) (*EmbeddedFile, bool) {
+	nested, err := file.newEmbedded(off, end-off)
+	if err != nil {
+		return nil, false
+	}
+	size, err := nested.rawSize()
+	if err != nil || size == 0 {
+		return nil, false
+	}
+	e := &EmbeddedFile{File: nested, Offset: off, Size: size}
+	if size > end-off {
+		e.Truncated = true
+	} else {
+		// Restrict the embedded file to its computed size, so that its overlay
+		// is empty.
+		nested.r = io.NewSectionReader(file.r, off, size)
+	}
+	return e, true
+}
+
+// newEmbedded returns a new File for accessing the PE file embedded at off,
+// within size bytes of file. The headers and section table of the embedded file
+// are parsed to validate it.
+func (file *File) newEmbedded(off, size int64) (*File, error) {
+	opts := []Option{
+		WithTolerant(file.conf.tolerant),
+		WithLimits(file.conf.limits),
+		WithOrdinalDB(file.conf.ordinals),
+	}
+	nested, err := New(io.NewSectionReader(file.r, off, size), opts...)
+	if err != nil {
+		return nil, err
+	}
+	if obj, err := nested.IsObject(); err != nil || obj {
+		return nil, formatError("embedded PE file", off, "not a PE image")
+	}
+	if _, err := nested.OptHeader(); err != nil {
+		return nil, err
+	}
+	if _, err := nested.SectHeaders(); err != nil {
+		return nil, err
+	}
+	return nested, nil
+}
+
+// rawSize returns the size of the file layout image of file, as computed from
+// the extents of its headers and sections; excluding the overlay.
+func (file *File) rawSize() (int64, error) {
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return 0, err
+	}
+	size := int64(opthdr.HdrSize)
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return 0, err
+	}
+	for _, sectHdr := range sectHdrs {
+		if sectHdr.Offset == 0 || sectHdr.Size == 0 {
+			continue
+		}
+		if end := int64(sectHdr.Offset) + int64(sectHdr.Size); end > size {
+			size = end
+		}
+	}
+	return size, nil
+}
+
+// resourcePathString returns a string representation of the given resource
+// path, using symbolic names for predefined resource types.
+func resourcePathString(path []*ResourceNode) string {
+	var names []string
+	for i, node := range path {
+		name := node.String()
+		if i == 0 && len(node.Name) == 0 {
+			if typeName := ResourceTypeName(node.ID); len(typeName) > 0 {
+				name = typeName
+			}
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "/")
+}
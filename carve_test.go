@@ -0,0 +1,73 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCarve(t *testing.T) {
+	img := synthImage{}.bytes()
+	embedded32 := synthImage{rich: true}.bytes()
+	embedded64 := synthImage{is64: true}.bytes()
+	var b []byte
+	b = append(b, img...)
+	b = append(b, "junk MZ junk"...)
+	off32 := int64(len(b))
+	b = append(b, embedded32...)
+	b = append(b, "more junk"...)
+	off64 := int64(len(b))
+	b = append(b, embedded64[:0x400]...)
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := file.Carve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		off       int64
+		size      int64
+		truncated bool
+		is64      bool
+	}{
+		{off: off32, size: int64(len(embedded32)), is64: false},
+		{off: off64, size: int64(len(embedded64)), truncated: true, is64: true},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("expected %d embedded files, got %d", len(want), len(files))
+	}
+	for i, e := range files {
+		w := want[i]
+		if e.Offset != w.off || e.Size != w.size || e.Truncated != w.truncated {
+			t.Errorf("embedded file %d mismatch; expected offset 0x%X, size 0x%X and truncated %v, got offset 0x%X, size 0x%X and truncated %v", i, w.off, w.size, w.truncated, e.Offset, e.Size, e.Truncated)
+		}
+		if e.Source != CarveOverlay {
+			t.Errorf("embedded file %d: source mismatch; expected %v, got %v", i, CarveOverlay, e.Source)
+		}
+		opthdr, err := e.File.OptHeader()
+		if err != nil {
+			t.Fatalf("embedded file %d: %v", i, err)
+		}
+		if opthdr.Is64() != w.is64 {
+			t.Errorf("embedded file %d: Is64 mismatch; expected %v, got %v", i, w.is64, opthdr.Is64())
+		}
+	}
+
+	// The complete embedded file is accessible, without trailing data.
+	imps, err := files[0].File.Imports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imps) != 1 || imps[0].DLL != "kernel32.dll" {
+		t.Errorf("embedded file imports mismatch")
+	}
+	overlay, err := files[0].File.Overlay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(overlay) != 0 {
+		t.Errorf("expected empty overlay of embedded file, got %d bytes", len(overlay))
+	}
+}
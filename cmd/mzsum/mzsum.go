@@ -0,0 +1,105 @@
+// mzsum is a tool which verifies the DOS header checksum of executable files.
+//
+// A checksum of zero is treated as unset and is only reported when the -z flag
+// is given.
+//
+// The exit status is 1 if any file could not be read, 2 if any checksum is
+// invalid, and 0 otherwise.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/mewrev/pe"
+)
+
+func init() {
+	flag.Usage = usage
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "mzsum [OPTION]... FILE...")
+	flag.PrintDefaults()
+}
+
+func main() {
+	var (
+		// all specifies whether to report files with valid checksums.
+		all bool
+		// zero specifies whether to report files with unset (zero) checksums.
+		zero bool
+	)
+	flag.BoolVar(&all, "a", false, "report files with valid checksums")
+	flag.BoolVar(&zero, "z", false, "report files with unset (zero) checksums")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	failed, mismatch := false, false
+	for _, path := range flag.Args() {
+		ok, err := mzsum(path, all, zero)
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			failed = true
+			continue
+		}
+		if !ok {
+			mismatch = true
+		}
+	}
+	switch {
+	case failed:
+		os.Exit(1)
+	case mismatch:
+		os.Exit(2)
+	}
+}
+
+// mzsum verifies the DOS header checksum of the provided executable file, and
+// reports whether the checksum is valid.
+func mzsum(path string, all, zero bool) (ok bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	file, err := pe.New(f)
+	if err != nil {
+		return false, err
+	}
+	doshdr, err := file.DOSHeader()
+	if err != nil {
+		return false, err
+	}
+	if doshdr.Checksum == 0 && !zero {
+		return true, nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	ok, err = doshdr.VerifyChecksum(f)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		// Recompute the checksum for diagnostics.
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		sum, err := pe.DOSChecksum(f)
+		if err != nil {
+			return false, err
+		}
+		fmt.Printf("%s: checksum mismatch (stored 0x%04X, computed 0x%04X)\n", path, doshdr.Checksum, sum)
+		return false, nil
+	}
+	if all {
+		fmt.Printf("%s: OK (0x%04X)\n", path, doshdr.Checksum)
+	}
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mewrev/pe"
+)
+
+// The JSON output of -diff consists of a single JSON object:
+//
+//    {
+//       "schema_version": 1,
+//       "old", "new": string (paths of the compared files),
+//       "differences": [{
+//          "category": string ("headers", "sections", "imports", "exports",
+//                              "resources" or "version info"),
+//          "kind": string ("changed", "added" or "removed"),
+//          "item": string, "old", "new": string (empty if added or removed,
+//                                                respectively)
+//       }]
+//    }
+
+// diffSchemaVersion specifies the version of the JSON output schema of -diff,
+// which is versioned independently of the JSON output of files.
+const diffSchemaVersion = 1
+
+// jsonDiff is the JSON representation of the differences between two files.
+type jsonDiff struct {
+	SchemaVersion int              `json:"schema_version"`
+	Old           string           `json:"old"`
+	New           string           `json:"new"`
+	Differences   []jsonDifference `json:"differences"`
+}
+
+// jsonDifference is the JSON representation of a difference.
+type jsonDifference struct {
+	Category string `json:"category"`
+	Kind     string `json:"kind"`
+	Item     string `json:"item"`
+	Old      string `json:"old"`
+	New      string `json:"new"`
+}
+
+// diff parses and compares the provided Portable Executable (PE) files.
+func diff(oldPath, newPath string, jsonOutput, tolerant, ignoreNoise bool) error {
+	var files [2]*pe.File
+	for i, path := range []string{oldPath, newPath} {
+		file, err := pe.Open(path, pe.WithTolerant(tolerant))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := file.Parse(); err != nil {
+			return err
+		}
+		files[i] = file
+	}
+	diffs, err := pe.Diff(files[0], files[1], pe.DiffIgnoreNoise(ignoreNoise))
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return dumpDiffJSON(os.Stdout, oldPath, newPath, diffs)
+	}
+	reportDiff(os.Stdout, oldPath, newPath, diffs)
+	return nil
+}
+
+// reportDiff writes a formatted report of the given differences to w, grouped
+// by category.
+func reportDiff(w io.Writer, oldPath, newPath string, diffs []*pe.Difference) {
+	fmt.Fprintf(w, "Comparison of %s and %s\n\n", oldPath, newPath)
+	if len(diffs) == 0 {
+		fmt.Fprintln(w, "No differences")
+		return
+	}
+	for i, d := range diffs {
+		if i == 0 || d.Category != diffs[i-1].Category {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintln(w, strings.ToUpper(d.Category.String()))
+		}
+		switch d.Kind {
+		case pe.DiffAdded:
+			fmt.Fprintf(w, "  + %s: %s\n", d.Item, d.New)
+		case pe.DiffRemoved:
+			fmt.Fprintf(w, "  - %s: %s\n", d.Item, d.Old)
+		default:
+			fmt.Fprintf(w, "  ~ %s: %s -> %s\n", d.Item, d.Old, d.New)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// dumpDiffJSON writes the JSON representation of the given differences to w.
+func dumpDiffJSON(w io.Writer, oldPath, newPath string, diffs []*pe.Difference) error {
+	v := &jsonDiff{
+		SchemaVersion: diffSchemaVersion,
+		Old:           oldPath,
+		New:           newPath,
+		Differences:   []jsonDifference{},
+	}
+	for _, d := range diffs {
+		v.Differences = append(v.Differences, jsonDifference{
+			Category: d.Category.String(),
+			Kind:     d.Kind.String(),
+			Item:     d.Item,
+			Old:      d.Old,
+			New:      d.New,
+		})
+	}
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,781 @@
+package main
+
+// The JSON output of peek consists of one JSON object per input file, each on a
+// single line. The schema is versioned by the "schema_version" field, which is
+// incremented whenever a field is renamed, removed or changes its range of
+// values; fields may be added without incrementing the version. Unless
+// otherwise noted, numeric values are emitted in decimal and addresses are
+// relative to the image base.
+//
+// Version 2 widened "image_base" and the stack and heap sizes of the optional
+// header to the full 64-bit values of PE32+ images.
+//
+//    {
+//       "schema_version": 2,
+//       "path": string,
+//       "dos_header": null (COFF object files) | {
+//          "last_page_size", "npage", "nreloc", "nhdr_par", "min_alloc",
+//          "max_alloc", "ss", "sp", "checksum", "ip", "cs",
+//          "reloc_tbl_offset", "overlay_num", "oem_id", "oem_info",
+//          "pe_hdr_offset": number
+//       },
+//       "rich_header": null | {
+//          "offset", "key", "checksum": number,
+//          "valid_checksum": bool,
+//          "entries": [{
+//             "prod_id": number, "product": string, "build": number,
+//             "count": number, "vs_version": string (omitted if unknown)
+//          }]
+//       },
+//       "file_header": {
+//          "arch": number, "arch_name": string, "nsection": number,
+//          "created": number (seconds since the Unix Epoch),
+//          "created_time": string (RFC 3339, UTC),
+//          "sym_tbl_offset", "nsymbol", "opt_hdr_size", "flags": number,
+//          "flag_names": [string]
+//       },
+//       "opt_header": null | {
+//          "state": number, "state_name": string,
+//          "major_link_ver", "minor_link_ver", "code_size", "data_size",
+//          "bss_size", "entry_rel_addr", "code_base", "data_base",
+//          "image_base", "sect_align", "file_align", "major_os_ver",
+//          "minor_os_ver", "major_image_ver", "minor_image_ver",
+//          "major_subsystem_ver", "minor_subsystem_ver", "image_size",
+//          "hdr_size", "checksum": number,
+//          "subsystem": number, "subsystem_name": string,
+//          "dll_flags": number, "dll_flag_names": [string],
+//          "reserve_stack_size", "init_stack_size", "reserve_heap_size",
+//          "init_heap_size", "loader_flags", "ndata_dir": number
+//       },
+//       "data_directories": [{
+//          "index": number, "name": string, "rel_addr", "size": number
+//       }],
+//       "sections": [{
+//          "name": string (long names resolved from the COFF string table),
+//          "raw_name": string (name as stored in the section header),
+//          "virt_size", "rel_addr", "size", "offset",
+//          "relocs_offset", "line_nums_offset", "nreloc", "nline_num",
+//          "flags": number, "flag_names": [string],
+//          "entropy": number (Shannon entropy of the contents, in bits per
+//                             byte),
+//          "chi_square": number (chi-square statistic of the byte
+//                                distribution against a uniform one),
+//          "histogram": [number] (occurrences of each byte value 0-255)
+//       }],
+//       "overlay": {
+//          "size", "entropy", "chi_square": number,
+//          "histogram": [number],
+//          "items": [{
+//             "type": string, "offset", "size": number
+//          }] (payloads succeeding the image, including the certificate table)
+//       },
+//       "imphash": string (empty if no imports),
+//       "exphash": string (empty if no exports),
+//       "imports": [{
+//          "dll": string, "ilt_rel_addr", "created", "forwarder_chain",
+//          "iat_rel_addr": number,
+//          "funcs": [{
+//             "name": string (omitted if imported by ordinal and not in the
+//                             ordinal database),
+//             "hint", "ordinal": number, "by_ordinal": bool,
+//             "iat_rel_addr": number
+//          }]
+//       }],
+//       "exports": null | {
+//          "dll": string, "created", "major_ver", "minor_ver",
+//          "ordinal_base": number,
+//          "funcs": [{
+//             "name": string (omitted if exported by ordinal only),
+//             "ordinal", "rel_addr": number,
+//             "forwarder": string (omitted if not forwarded)
+//          }]
+//       },
+//       "resources": [{
+//          "path": [string] (type, name and language; predefined types by
+//                            symbolic name, integer IDs as "#ID"),
+//          "rel_addr", "size", "code_page": number
+//       }],
+//       "anomalies": [{
+//          "code": number, "code_name": string, "msg": string,
+//          "offset": number (-1 if unknown),
+//          "severity": string ("info", "warning" or "error")
+//       }],
+//       "embedded": [{
+//          "offset", "size": number, "truncated": bool,
+//          "source": string ("section", "resource" or "overlay"),
+//          "location": string (section name or resource path)
+//       }],
+//       "signatures": [{
+//          "name", "kind": string, "score": number,
+//          "evidence": [string]
+//       }] (ranked by score),
+//       "hashes": {
+//          "file": {"md5", "sha1", "sha256": string},
+//          "sections": [{"name", "md5", "sha1", "sha256": string}],
+//          "overlay": null | {"md5", "sha1", "sha256": string},
+//          "authentihash": null (COFF object files) | {
+//             "md5", "sha1", "sha256": string
+//          },
+//          "rich_hash": string (empty if no Rich header),
+//          "lsh": string (locality-sensitive hash; empty if the file is too
+//                         small or too uniform)
+//       }
+//    }
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mewrev/pe"
+)
+
+// jsonSchemaVersion specifies the version of the JSON output schema.
+const jsonSchemaVersion = 2
+
+// jsonFile is the JSON representation of a PE file.
+type jsonFile struct {
+	SchemaVersion int                 `json:"schema_version"`
+	Path          string              `json:"path"`
+	DOSHeader     *jsonDOSHeader      `json:"dos_header"`
+	RichHeader    *jsonRichHeader     `json:"rich_header"`
+	FileHeader    *jsonFileHeader     `json:"file_header"`
+	OptHeader     *jsonOptHeader      `json:"opt_header"`
+	DataDirs      []jsonDataDirectory `json:"data_directories"`
+	Sections      []jsonSection       `json:"sections"`
+	Overlay       jsonOverlay         `json:"overlay"`
+	ImpHash       string              `json:"imphash"`
+	ExpHash       string              `json:"exphash"`
+	Imports       []jsonImport        `json:"imports"`
+	Exports       *jsonExports        `json:"exports"`
+	Resources     []jsonResource      `json:"resources"`
+	Anomalies     []jsonAnomaly       `json:"anomalies"`
+	Embedded      []jsonEmbedded      `json:"embedded"`
+	Signatures    []jsonSignature     `json:"signatures"`
+	Hashes        *jsonHashes         `json:"hashes"`
+}
+
+// jsonDOSHeader is the JSON representation of a DOS header.
+type jsonDOSHeader struct {
+	LastPageSize   uint16 `json:"last_page_size"`
+	NPage          uint16 `json:"npage"`
+	NReloc         uint16 `json:"nreloc"`
+	NHdrPar        uint16 `json:"nhdr_par"`
+	MinAlloc       uint16 `json:"min_alloc"`
+	MaxAlloc       uint16 `json:"max_alloc"`
+	SS             uint16 `json:"ss"`
+	SP             uint16 `json:"sp"`
+	Checksum       uint16 `json:"checksum"`
+	IP             uint16 `json:"ip"`
+	CS             uint16 `json:"cs"`
+	RelocTblOffset uint16 `json:"reloc_tbl_offset"`
+	OverlayNum     uint16 `json:"overlay_num"`
+	OEMID          uint16 `json:"oem_id"`
+	OEMInfo        uint16 `json:"oem_info"`
+	PEHdrOffset    uint32 `json:"pe_hdr_offset"`
+}
+
+// jsonRichHeader is the JSON representation of a Rich header.
+type jsonRichHeader struct {
+	Offset        uint32          `json:"offset"`
+	Key           uint32          `json:"key"`
+	Checksum      uint32          `json:"checksum"`
+	ValidChecksum bool            `json:"valid_checksum"`
+	Entries       []jsonRichEntry `json:"entries"`
+}
+
+// jsonRichEntry is the JSON representation of a Rich header entry.
+type jsonRichEntry struct {
+	ProdID    uint16 `json:"prod_id"`
+	Product   string `json:"product"`
+	Build     uint16 `json:"build"`
+	Count     uint32 `json:"count"`
+	VSVersion string `json:"vs_version,omitempty"`
+}
+
+// jsonFileHeader is the JSON representation of a COFF file header.
+type jsonFileHeader struct {
+	Arch         uint16   `json:"arch"`
+	ArchName     string   `json:"arch_name"`
+	NSection     uint16   `json:"nsection"`
+	Created      uint32   `json:"created"`
+	CreatedTime  string   `json:"created_time"`
+	SymTblOffset uint32   `json:"sym_tbl_offset"`
+	NSymbol      uint32   `json:"nsymbol"`
+	OptHdrSize   uint16   `json:"opt_hdr_size"`
+	Flags        uint16   `json:"flags"`
+	FlagNames    []string `json:"flag_names"`
+}
+
+// jsonOptHeader is the JSON representation of an optional header.
+type jsonOptHeader struct {
+	State             uint16   `json:"state"`
+	StateName         string   `json:"state_name"`
+	MajorLinkVer      uint8    `json:"major_link_ver"`
+	MinorLinkVer      uint8    `json:"minor_link_ver"`
+	CodeSize          uint32   `json:"code_size"`
+	DataSize          uint32   `json:"data_size"`
+	BSSSize           uint32   `json:"bss_size"`
+	EntryRelAddr      uint32   `json:"entry_rel_addr"`
+	CodeBase          uint32   `json:"code_base"`
+	DataBase          uint32   `json:"data_base"`
+	ImageBase         uint64   `json:"image_base"`
+	SectAlign         uint32   `json:"sect_align"`
+	FileAlign         uint32   `json:"file_align"`
+	MajorOSVer        uint16   `json:"major_os_ver"`
+	MinorOSVer        uint16   `json:"minor_os_ver"`
+	MajorImageVer     uint16   `json:"major_image_ver"`
+	MinorImageVer     uint16   `json:"minor_image_ver"`
+	MajorSubsystemVer uint16   `json:"major_subsystem_ver"`
+	MinorSubsystemVer uint16   `json:"minor_subsystem_ver"`
+	ImageSize         uint32   `json:"image_size"`
+	HdrSize           uint32   `json:"hdr_size"`
+	Checksum          uint32   `json:"checksum"`
+	Subsystem         uint16   `json:"subsystem"`
+	SubsystemName     string   `json:"subsystem_name"`
+	DLLFlags          uint16   `json:"dll_flags"`
+	DLLFlagNames      []string `json:"dll_flag_names"`
+	ReserveStackSize  uint64   `json:"reserve_stack_size"`
+	InitStackSize     uint64   `json:"init_stack_size"`
+	ReserveHeapSize   uint64   `json:"reserve_heap_size"`
+	InitHeapSize      uint64   `json:"init_heap_size"`
+	LoaderFlags       uint32   `json:"loader_flags"`
+	NDataDir          uint32   `json:"ndata_dir"`
+}
+
+// jsonDataDirectory is the JSON representation of a data directory.
+type jsonDataDirectory struct {
+	Index   int    `json:"index"`
+	Name    string `json:"name"`
+	RelAddr uint32 `json:"rel_addr"`
+	Size    uint32 `json:"size"`
+}
+
+// jsonSection is the JSON representation of a section header.
+type jsonSection struct {
+	Name           string   `json:"name"`
+	RawName        string   `json:"raw_name"`
+	VirtSize       uint32   `json:"virt_size"`
+	RelAddr        uint32   `json:"rel_addr"`
+	Size           uint32   `json:"size"`
+	Offset         uint32   `json:"offset"`
+	RelocsOffset   uint32   `json:"relocs_offset"`
+	LineNumsOffset uint32   `json:"line_nums_offset"`
+	NReloc         uint16   `json:"nreloc"`
+	NLineNum       uint16   `json:"nline_num"`
+	Flags          uint32   `json:"flags"`
+	FlagNames      []string `json:"flag_names"`
+	jsonStats
+}
+
+// jsonStats is the JSON representation of the byte statistics of a block of
+// data.
+type jsonStats struct {
+	Entropy   float64    `json:"entropy"`
+	ChiSquare float64    `json:"chi_square"`
+	Hist      [256]int64 `json:"histogram"`
+}
+
+// newJSONStats returns the JSON representation of the given byte statistics.
+func newJSONStats(stats *pe.Stats) jsonStats {
+	return jsonStats{
+		Entropy:   stats.Entropy(),
+		ChiSquare: stats.ChiSquare(),
+		Hist:      stats.Hist,
+	}
+}
+
+// jsonOverlay is the JSON representation of the overlay of a PE file.
+type jsonOverlay struct {
+	Size int64 `json:"size"`
+	jsonStats
+	Items []jsonOverlayItem `json:"items"`
+}
+
+// jsonOverlayItem is the JSON representation of an overlay item.
+type jsonOverlayItem struct {
+	Type   string `json:"type"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// jsonImport is the JSON representation of the imports of a single DLL.
+type jsonImport struct {
+	DLL            string           `json:"dll"`
+	ILTRelAddr     uint32           `json:"ilt_rel_addr"`
+	Created        uint32           `json:"created"`
+	ForwarderChain uint32           `json:"forwarder_chain"`
+	IATRelAddr     uint32           `json:"iat_rel_addr"`
+	Funcs          []jsonImportFunc `json:"funcs"`
+}
+
+// jsonImportFunc is the JSON representation of an imported function.
+type jsonImportFunc struct {
+	Name       string `json:"name,omitempty"`
+	Hint       uint16 `json:"hint"`
+	Ordinal    uint16 `json:"ordinal"`
+	ByOrdinal  bool   `json:"by_ordinal"`
+	IATRelAddr uint32 `json:"iat_rel_addr"`
+}
+
+// jsonExports is the JSON representation of the exports of an image.
+type jsonExports struct {
+	DLL         string           `json:"dll"`
+	Created     uint32           `json:"created"`
+	MajorVer    uint16           `json:"major_ver"`
+	MinorVer    uint16           `json:"minor_ver"`
+	OrdinalBase uint32           `json:"ordinal_base"`
+	Funcs       []jsonExportFunc `json:"funcs"`
+}
+
+// jsonExportFunc is the JSON representation of an exported function.
+type jsonExportFunc struct {
+	Name      string `json:"name,omitempty"`
+	Ordinal   uint32 `json:"ordinal"`
+	RelAddr   uint32 `json:"rel_addr"`
+	Forwarder string `json:"forwarder,omitempty"`
+}
+
+// jsonResource is the JSON representation of a resource leaf.
+type jsonResource struct {
+	Path     []string `json:"path"`
+	RelAddr  uint32   `json:"rel_addr"`
+	Size     uint32   `json:"size"`
+	CodePage uint32   `json:"code_page"`
+}
+
+// jsonAnomaly is the JSON representation of an anomaly.
+type jsonAnomaly struct {
+	Code     uint16 `json:"code"`
+	CodeName string `json:"code_name"`
+	Msg      string `json:"msg"`
+	Offset   int64  `json:"offset"`
+	Severity string `json:"severity"`
+}
+
+// jsonEmbedded is the JSON representation of an embedded PE file.
+type jsonEmbedded struct {
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated"`
+	Source    string `json:"source"`
+	Location  string `json:"location"`
+}
+
+// jsonSignature is the JSON representation of a matching signature.
+type jsonSignature struct {
+	Name     string   `json:"name"`
+	Kind     string   `json:"kind"`
+	Score    int      `json:"score"`
+	Evidence []string `json:"evidence"`
+}
+
+// jsonHashes is the JSON representation of the hashes of a file.
+type jsonHashes struct {
+	File         jsonHashSet       `json:"file"`
+	Sections     []jsonSectionHash `json:"sections"`
+	Overlay      *jsonHashSet      `json:"overlay"`
+	Authentihash *jsonHashSet      `json:"authentihash"`
+	RichHash     string            `json:"rich_hash"`
+	LSH          string            `json:"lsh"`
+}
+
+// jsonHashSet is the JSON representation of the MD5, SHA-1 and SHA-256 hashes
+// of data.
+type jsonHashSet struct {
+	MD5    string `json:"md5"`
+	SHA1   string `json:"sha1"`
+	SHA256 string `json:"sha256"`
+}
+
+// newJSONHashSet returns the JSON representation of the given hashes; or nil if
+// not present.
+func newJSONHashSet(h *pe.HashSet) *jsonHashSet {
+	if h == nil {
+		return nil
+	}
+	return &jsonHashSet{MD5: h.MD5, SHA1: h.SHA1, SHA256: h.SHA256}
+}
+
+// jsonSectionHash is the JSON representation of the hashes of a section.
+type jsonSectionHash struct {
+	Name string `json:"name"`
+	jsonHashSet
+}
+
+// dataDirNames specifies the names of data directories, as specified by index.
+var dataDirNames = [...]string{
+	pe.DataDirExportTable:           "export table",
+	pe.DataDirImportTable:           "import table",
+	pe.DataDirResourceTable:         "resource table",
+	pe.DataDirExceptionTable:        "exception table",
+	pe.DataDirCertificateTable:      "certificate table",
+	pe.DataDirBaseRelocationTable:   "base relocation table",
+	pe.DataDirDebug:                 "debug",
+	pe.DataDirArchitecture:          "architecture",
+	pe.DataDirGlobalPtr:             "global pointer",
+	pe.DataDirTLSTable:              "TLS table",
+	pe.DataDirLoadConfigTable:       "load config table",
+	pe.DataDirBoundImport:           "bound import",
+	pe.DataDirIAT:                   "import address table",
+	pe.DataDirDelayImportDescriptor: "delay import descriptor",
+	pe.DataDirCLRHeader:             "CLR header",
+	pe.DataDirReserved:              "reserved",
+}
+
+// dataDirName returns the name of the data directory at the given index.
+func dataDirName(index int) string {
+	if index < len(dataDirNames) {
+		return dataDirNames[index]
+	}
+	return "unknown"
+}
+
+// splitFlags splits the string representation of a bitfield into the names of
+// its individual flags.
+func splitFlags(s string) []string {
+	if s == "none" {
+		return []string{}
+	}
+	return strings.Split(s, "|")
+}
+
+// dumpJSON writes the JSON representation of the parsed PE file to w.
+func dumpJSON(w io.Writer, path string, file *pe.File) error {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return err
+	}
+	obj, err := file.IsObject()
+	if err != nil {
+		return err
+	}
+	v := &jsonFile{
+		SchemaVersion: jsonSchemaVersion,
+		Path:          path,
+		FileHeader: &jsonFileHeader{
+			Arch:         uint16(fileHdr.Arch),
+			ArchName:     fileHdr.Arch.String(),
+			NSection:     fileHdr.NSection,
+			Created:      uint32(fileHdr.Created),
+			CreatedTime:  fileHdr.Created.Time().UTC().Format(time.RFC3339),
+			SymTblOffset: fileHdr.SymTblOffset,
+			NSymbol:      fileHdr.NSymbol,
+			OptHdrSize:   fileHdr.OptHdrSize,
+			Flags:        uint16(fileHdr.Flags),
+			FlagNames:    splitFlags(fileHdr.Flags.String()),
+		},
+		DataDirs: []jsonDataDirectory{},
+		Sections: []jsonSection{},
+	}
+
+	// DOS header.
+	if !obj {
+		doshdr, err := file.DOSHeader()
+		if err != nil {
+			return err
+		}
+		v.DOSHeader = &jsonDOSHeader{
+			LastPageSize:   doshdr.LastPageSize,
+			NPage:          doshdr.NPage,
+			NReloc:         doshdr.NReloc,
+			NHdrPar:        doshdr.NHdrPar,
+			MinAlloc:       doshdr.MinAlloc,
+			MaxAlloc:       doshdr.MaxAlloc,
+			SS:             doshdr.SS,
+			SP:             doshdr.SP,
+			Checksum:       doshdr.Checksum,
+			IP:             doshdr.IP,
+			CS:             doshdr.CS,
+			RelocTblOffset: doshdr.RelocTblOffset,
+			OverlayNum:     doshdr.OverlayNum,
+			OEMID:          doshdr.OEMID,
+			OEMInfo:        doshdr.OEMInfo,
+			PEHdrOffset:    doshdr.PEHdrOffset,
+		}
+	}
+
+	// Rich header.
+	var rich *pe.RichHeader
+	if !obj {
+		rich, err = file.RichHeader()
+		if err != nil {
+			return err
+		}
+	}
+	if rich != nil {
+		r := &jsonRichHeader{
+			Offset:        rich.Offset,
+			Key:           rich.Key,
+			Checksum:      rich.Checksum,
+			ValidChecksum: rich.ValidChecksum(),
+			Entries:       []jsonRichEntry{},
+		}
+		for _, entry := range rich.Entries {
+			e := jsonRichEntry{
+				ProdID:    uint16(entry.ProdID),
+				Product:   entry.ProdID.String(),
+				Build:     entry.Build,
+				Count:     entry.Count,
+				VSVersion: entry.VSVersion(),
+			}
+			r.Entries = append(r.Entries, e)
+		}
+		v.RichHeader = r
+	}
+
+	// Optional header.
+	if fileHdr.OptHdrSize > 0 {
+		opthdr, err := file.OptHeader()
+		if err != nil {
+			return err
+		}
+		v.OptHeader = &jsonOptHeader{
+			State:             uint16(opthdr.State),
+			StateName:         opthdr.State.String(),
+			MajorLinkVer:      opthdr.MajorLinkVer,
+			MinorLinkVer:      opthdr.MinorLinkVer,
+			CodeSize:          opthdr.CodeSize,
+			DataSize:          opthdr.DataSize,
+			BSSSize:           opthdr.BSSSize,
+			EntryRelAddr:      opthdr.EntryRelAddr,
+			CodeBase:          opthdr.CodeBase,
+			DataBase:          opthdr.DataBase,
+			ImageBase:         opthdr.ImageBase64,
+			SectAlign:         opthdr.SectAlign,
+			FileAlign:         opthdr.FileAlign,
+			MajorOSVer:        opthdr.MajorOSVer,
+			MinorOSVer:        opthdr.MinorOSVer,
+			MajorImageVer:     opthdr.MajorImageVer,
+			MinorImageVer:     opthdr.MinorImageVer,
+			MajorSubsystemVer: opthdr.MajorSubsystemVer,
+			MinorSubsystemVer: opthdr.MinorSubsystemVer,
+			ImageSize:         opthdr.ImageSize,
+			HdrSize:           opthdr.HdrSize,
+			Checksum:          opthdr.Checksum,
+			Subsystem:         uint16(opthdr.Subsystem),
+			SubsystemName:     opthdr.Subsystem.String(),
+			DLLFlags:          uint16(opthdr.Flags),
+			DLLFlagNames:      splitFlags(opthdr.Flags.String()),
+			ReserveStackSize:  opthdr.ReserveStackSize64,
+			InitStackSize:     opthdr.InitStackSize64,
+			ReserveHeapSize:   opthdr.ReserveHeapSize64,
+			InitHeapSize:      opthdr.InitHeapSize64,
+			LoaderFlags:       opthdr.LoaderFlags,
+			NDataDir:          opthdr.NDataDir,
+		}
+		for i, dataDir := range opthdr.DataDirs {
+			d := jsonDataDirectory{
+				Index:   i,
+				Name:    dataDirName(i),
+				RelAddr: dataDir.RelAddr,
+				Size:    dataDir.Size,
+			}
+			v.DataDirs = append(v.DataDirs, d)
+		}
+	}
+
+	// Section headers.
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return err
+	}
+	for _, sectHdr := range sectHdrs {
+		s := jsonSection{
+			Name:           sectHdr.Name,
+			RawName:        sectHdr.RawName,
+			VirtSize:       sectHdr.VirtSize,
+			RelAddr:        sectHdr.RelAddr,
+			Size:           sectHdr.Size,
+			Offset:         sectHdr.Offset,
+			RelocsOffset:   sectHdr.RelocsOffset,
+			LineNumsOffset: sectHdr.LineNumsOffset,
+			NReloc:         sectHdr.NReloc,
+			NLineNum:       sectHdr.NLineNum,
+			Flags:          uint32(sectHdr.Flags),
+			FlagNames:      splitFlags(sectHdr.Flags.String()),
+		}
+		stats, err := file.SectionStats(sectHdr)
+		if err != nil {
+			return err
+		}
+		s.jsonStats = newJSONStats(stats)
+		v.Sections = append(v.Sections, s)
+	}
+
+	// Overlay.
+	stats, err := file.OverlayStats()
+	if err != nil {
+		return err
+	}
+	v.Overlay = jsonOverlay{
+		Size:      stats.Size,
+		jsonStats: newJSONStats(stats),
+		Items:     []jsonOverlayItem{},
+	}
+	items, err := file.OverlayItems()
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		i := jsonOverlayItem{
+			Type:   item.Type.String(),
+			Offset: item.Offset,
+			Size:   item.Size,
+		}
+		v.Overlay.Items = append(v.Overlay.Items, i)
+	}
+
+	// Imports.
+	imps, err := file.Imports()
+	if err != nil {
+		return err
+	}
+	v.Imports = []jsonImport{}
+	for _, imp := range imps {
+		i := jsonImport{
+			DLL:            imp.DLL,
+			ILTRelAddr:     imp.ILTRelAddr,
+			Created:        uint32(imp.Created),
+			ForwarderChain: imp.ForwarderChain,
+			IATRelAddr:     imp.IATRelAddr,
+			Funcs:          []jsonImportFunc{},
+		}
+		for _, fn := range imp.Funcs {
+			f := jsonImportFunc{
+				Name:       fn.Name,
+				Hint:       fn.Hint,
+				Ordinal:    fn.Ordinal,
+				ByOrdinal:  fn.ByOrdinal,
+				IATRelAddr: fn.IATRelAddr,
+			}
+			i.Funcs = append(i.Funcs, f)
+		}
+		v.Imports = append(v.Imports, i)
+	}
+
+	if v.ImpHash, err = file.ImpHash(); err != nil {
+		return err
+	}
+
+	// Exports.
+	exps, err := file.Exports()
+	if err != nil {
+		return err
+	}
+	if exps != nil {
+		e := &jsonExports{
+			DLL:         exps.DLL,
+			Created:     uint32(exps.Created),
+			MajorVer:    exps.MajorVer,
+			MinorVer:    exps.MinorVer,
+			OrdinalBase: exps.OrdinalBase,
+			Funcs:       []jsonExportFunc{},
+		}
+		for _, fn := range exps.Funcs {
+			f := jsonExportFunc{
+				Name:      fn.Name,
+				Ordinal:   fn.Ordinal,
+				RelAddr:   fn.RelAddr,
+				Forwarder: fn.Forwarder,
+			}
+			e.Funcs = append(e.Funcs, f)
+		}
+		v.Exports = e
+	}
+	if v.ExpHash, err = file.ExpHash(); err != nil {
+		return err
+	}
+
+	// Resources.
+	root, err := file.Resources()
+	if err != nil {
+		return err
+	}
+	v.Resources = []jsonResource{}
+	if root != nil {
+		root.Walk(func(path []*pe.ResourceNode, node *pe.ResourceNode) {
+			if !node.IsLeaf() {
+				return
+			}
+			r := jsonResource{
+				Path:     resourcePathNames(path),
+				RelAddr:  node.Data.RelAddr,
+				Size:     node.Data.Size,
+				CodePage: node.Data.CodePage,
+			}
+			v.Resources = append(v.Resources, r)
+		})
+	}
+
+	// Anomalies.
+	anomalies, err := file.Anomalies()
+	if err != nil {
+		return err
+	}
+	v.Anomalies = []jsonAnomaly{}
+	for _, anomaly := range anomalies {
+		a := jsonAnomaly{
+			Code:     uint16(anomaly.Code),
+			CodeName: anomaly.Code.String(),
+			Msg:      anomaly.Msg,
+			Offset:   anomaly.Offset,
+			Severity: anomaly.Severity.String(),
+		}
+		v.Anomalies = append(v.Anomalies, a)
+	}
+
+	// Embedded files.
+	embedded, err := file.Carve()
+	if err != nil {
+		return err
+	}
+	v.Embedded = []jsonEmbedded{}
+	for _, e := range embedded {
+		j := jsonEmbedded{
+			Offset:    e.Offset,
+			Size:      e.Size,
+			Truncated: e.Truncated,
+			Source:    e.Source.String(),
+			Location:  e.Location,
+		}
+		v.Embedded = append(v.Embedded, j)
+	}
+
+	// Signatures.
+	matches, err := file.Identify()
+	if err != nil {
+		return err
+	}
+	v.Signatures = []jsonSignature{}
+	for _, m := range matches {
+		sig := jsonSignature{
+			Name:     m.Sig.Name,
+			Kind:     m.Sig.Kind,
+			Score:    m.Score,
+			Evidence: m.Evidence,
+		}
+		v.Signatures = append(v.Signatures, sig)
+	}
+
+	// Hashes.
+	hashes, err := file.Hashes()
+	if err != nil {
+		return err
+	}
+	v.Hashes = &jsonHashes{
+		File:         *newJSONHashSet(&hashes.File),
+		Sections:     []jsonSectionHash{},
+		Overlay:      newJSONHashSet(hashes.Overlay),
+		Authentihash: newJSONHashSet(hashes.Authentihash),
+		RichHash:     hashes.RichHash,
+		LSH:          hashes.LSH,
+	}
+	for _, sect := range hashes.Sections {
+		h := jsonSectionHash{Name: sect.Name, jsonHashSet: *newJSONHashSet(&sect.HashSet)}
+		v.Hashes.Sections = append(v.Hashes.Sections, h)
+	}
+
+	return json.NewEncoder(w).Encode(v)
+}
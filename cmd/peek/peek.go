@@ -1,4 +1,50 @@
 // peek is a tool which parses and pretty prints Portable Executable (PE) files.
+//
+// Usage:
+//
+//	peek [OPTION]... FILE...
+//	peek -diff [-ignore-noise] [-json] OLD NEW
+//
+// Flags:
+//
+//	-json
+//	      output in JSON format (see json.go for the schema)
+//	-tolerant
+//	      parse malformed and truncated files in tolerant mode
+//	-ordinals DLL,...
+//	      name imports by ordinal using the exports of the given DLLs
+//	-headers
+//	      report headers, data directories and section table
+//	-imports
+//	      report imports
+//	-exports
+//	      report exports
+//	-resources
+//	      report resources
+//	-anomalies
+//	      report anomalies
+//	-identify
+//	      report matching signatures of packers, installers and compilers
+//	-carve
+//	      report PE files embedded in sections, resources and overlay
+//	-hashes
+//	      report file, section, overlay, Authenticode, Rich header and
+//	      locality-sensitive hashes
+//	-sigdb FILE,...
+//	      add the signatures of the given signature databases
+//	-all
+//	      report all of the above
+//	-strings
+//	      report printable ASCII and UTF-16LE strings (not included in -all)
+//	-min-len N
+//	      minimum length of reported strings (default 4)
+//	-diff
+//	      report differences in headers, sections, imports, exports, resources
+//	      and version info between two files
+//	-ignore-noise
+//	      ignore timestamps and checksums when comparing files
+//
+// Without flags, the parsed file is dumped in its Go representation.
 package main
 
 import (
@@ -6,6 +52,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/mewrev/pe"
@@ -16,17 +63,93 @@ func init() {
 }
 
 func usage() {
-	fmt.Fprintln(os.Stderr, "peek FILE...")
+	fmt.Fprintln(os.Stderr, "peek [OPTION]... FILE...")
+	fmt.Fprintln(os.Stderr, "peek -diff [-ignore-noise] [-json] OLD NEW")
+	flag.PrintDefaults()
 }
 
 func main() {
+	var (
+		// jsonOutput specifies whether to output in JSON format.
+		jsonOutput bool
+		// tolerant specifies whether to parse malformed files in tolerant mode.
+		tolerant bool
+		// ordinalDLLs specifies a comma-separated list of DLLs whose exports are
+		// registered in the ordinal database.
+		ordinalDLLs string
+		// sigDBs specifies a comma-separated list of signature databases whose
+		// signatures are added to the default signature database.
+		sigDBs string
+		// all specifies whether to report all sections.
+		all bool
+		// sections specifies the sections of the formatted report.
+		sections reportSections
+		// diffFiles specifies whether to compare two files.
+		diffFiles bool
+		// ignoreNoise specifies whether to ignore timestamps and checksums when
+		// comparing files.
+		ignoreNoise bool
+	)
+	flag.BoolVar(&jsonOutput, "json", false, "output in JSON format")
+	flag.BoolVar(&tolerant, "tolerant", false, "parse malformed and truncated files in tolerant mode")
+	flag.StringVar(&ordinalDLLs, "ordinals", "", "comma-separated list of DLLs used to name imports by ordinal")
+	flag.BoolVar(&sections.headers, "headers", false, "report headers, data directories and section table")
+	flag.BoolVar(&sections.imports, "imports", false, "report imports")
+	flag.BoolVar(&sections.exports, "exports", false, "report exports")
+	flag.BoolVar(&sections.resources, "resources", false, "report resources")
+	flag.BoolVar(&sections.anomalies, "anomalies", false, "report anomalies")
+	flag.BoolVar(&sections.identify, "identify", false, "report matching signatures of packers, installers and compilers")
+	flag.BoolVar(&sections.carve, "carve", false, "report PE files embedded in sections, resources and overlay")
+	flag.BoolVar(&sections.hashes, "hashes", false, "report file, section, overlay, Authenticode, Rich header and locality-sensitive hashes")
+	flag.StringVar(&sigDBs, "sigdb", "", "comma-separated list of signature databases to add")
+	flag.BoolVar(&all, "all", false, "report all of the above")
+	flag.BoolVar(&sections.strings, "strings", false, "report printable ASCII and UTF-16LE strings (not included in -all)")
+	flag.IntVar(&sections.minStringLen, "min-len", 4, "minimum length of reported strings")
+	flag.BoolVar(&diffFiles, "diff", false, "report differences between two files")
+	flag.BoolVar(&ignoreNoise, "ignore-noise", false, "ignore timestamps and checksums when comparing files")
 	flag.Parse()
+	if all {
+		sections.headers = true
+		sections.imports = true
+		sections.exports = true
+		sections.resources = true
+		sections.anomalies = true
+		sections.identify = true
+		sections.carve = true
+		sections.hashes = true
+	}
 	if flag.NArg() < 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
+	if len(ordinalDLLs) > 0 {
+		for _, dllPath := range strings.Split(ordinalDLLs, ",") {
+			if err := pe.DefaultOrdinalDB.RegisterExportsFile(dllPath); err != nil {
+				log.Fatalln(err)
+			}
+		}
+	}
+	if len(sigDBs) > 0 {
+		for _, dbPath := range strings.Split(sigDBs, ",") {
+			db, err := pe.LoadSigDB(dbPath)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			pe.DefaultSigDB.Sigs = append(pe.DefaultSigDB.Sigs, db.Sigs...)
+		}
+	}
+	if diffFiles {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		if err := diff(flag.Arg(0), flag.Arg(1), jsonOutput, tolerant, ignoreNoise); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
 	for _, path := range flag.Args() {
-		err := peek(path)
+		err := peek(path, jsonOutput, tolerant, sections)
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -34,8 +157,8 @@ func main() {
 }
 
 // peek parses and pretty prints the provided Portable Executable (PE) file.
-func peek(path string) (err error) {
-	file, err := pe.Open(path)
+func peek(path string, jsonOutput, tolerant bool, sections reportSections) (err error) {
+	file, err := pe.Open(path, pe.WithTolerant(tolerant))
 	if err != nil {
 		return err
 	}
@@ -44,6 +167,12 @@ func peek(path string) (err error) {
 	if err != nil {
 		return err
 	}
+	if jsonOutput {
+		return dumpJSON(os.Stdout, path, file)
+	}
+	if sections.any() {
+		return report(os.Stdout, path, file, sections)
+	}
 	spew.Dump(file)
 	return nil
 }
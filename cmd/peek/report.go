@@ -0,0 +1,458 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mewrev/pe"
+)
+
+// reportSections specifies the sections of a formatted report.
+type reportSections struct {
+	// Headers, section table and data directories.
+	headers bool
+	// Imported functions.
+	imports bool
+	// Exported functions.
+	exports bool
+	// Resource directory tree.
+	resources bool
+	// Anomalies.
+	anomalies bool
+	// Matching signatures.
+	identify bool
+	// Embedded PE files.
+	carve bool
+	// File, section, overlay, Authenticode, Rich header and locality-sensitive
+	// hashes.
+	hashes bool
+	// Printable strings.
+	strings bool
+	// Minimum length of printable strings.
+	minStringLen int
+}
+
+// any reports whether any report section was selected.
+func (sections reportSections) any() bool {
+	return sections.headers || sections.imports || sections.exports || sections.resources || sections.anomalies || sections.identify || sections.carve || sections.hashes || sections.strings
+}
+
+// report writes a formatted report of the selected sections of the parsed PE
+// file to w, in the style of `dumpbin /headers /imports /exports`.
+func report(w io.Writer, path string, file *pe.File, sections reportSections) error {
+	fmt.Fprintf(w, "Dump of file %s\n\n", path)
+	if sections.headers {
+		if err := reportHeaders(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.imports {
+		if err := reportImports(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.exports {
+		if err := reportExports(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.resources {
+		if err := reportResources(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.anomalies {
+		if err := reportAnomalies(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.identify {
+		if err := reportSignatures(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.carve {
+		if err := reportEmbedded(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.hashes {
+		if err := reportHashes(w, file); err != nil {
+			return err
+		}
+	}
+	if sections.strings {
+		if err := reportStrings(w, file, sections.minStringLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportHeaders writes a formatted report of the headers, section table and
+// data directories of file to w.
+func reportHeaders(w io.Writer, file *pe.File) error {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "FILE HEADER VALUES")
+	fmt.Fprintf(w, "%16X machine (%v)\n", uint16(fileHdr.Arch), fileHdr.Arch)
+	fmt.Fprintf(w, "%16X number of sections\n", fileHdr.NSection)
+	fmt.Fprintf(w, "%16X time date stamp (%v)\n", uint32(fileHdr.Created), fileHdr.Created.Time().UTC())
+	fmt.Fprintf(w, "%16X file pointer to symbol table\n", fileHdr.SymTblOffset)
+	fmt.Fprintf(w, "%16X number of symbols\n", fileHdr.NSymbol)
+	fmt.Fprintf(w, "%16X size of optional header\n", fileHdr.OptHdrSize)
+	fmt.Fprintf(w, "%16X characteristics (%v)\n", uint16(fileHdr.Flags), fileHdr.Flags)
+	fmt.Fprintln(w)
+
+	var opthdr *pe.OptHeader
+	if fileHdr.OptHdrSize > 0 {
+		opthdr, err = file.OptHeader()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "OPTIONAL HEADER VALUES")
+		fmt.Fprintf(w, "%16X magic # (%v)\n", uint16(opthdr.State), opthdr.State)
+		fmt.Fprintf(w, "%13d.%02d linker version\n", opthdr.MajorLinkVer, opthdr.MinorLinkVer)
+		fmt.Fprintf(w, "%16X size of code\n", opthdr.CodeSize)
+		fmt.Fprintf(w, "%16X size of initialized data\n", opthdr.DataSize)
+		fmt.Fprintf(w, "%16X size of uninitialized data\n", opthdr.BSSSize)
+		fmt.Fprintf(w, "%16X entry point\n", opthdr.EntryRelAddr)
+		fmt.Fprintf(w, "%16X base of code\n", opthdr.CodeBase)
+		if !opthdr.Is64() {
+			fmt.Fprintf(w, "%16X base of data\n", opthdr.DataBase)
+		}
+		fmt.Fprintf(w, "%16X image base\n", opthdr.ImageBase64)
+		fmt.Fprintf(w, "%16X section alignment\n", opthdr.SectAlign)
+		fmt.Fprintf(w, "%16X file alignment\n", opthdr.FileAlign)
+		fmt.Fprintf(w, "%13d.%02d operating system version\n", opthdr.MajorOSVer, opthdr.MinorOSVer)
+		fmt.Fprintf(w, "%13d.%02d image version\n", opthdr.MajorImageVer, opthdr.MinorImageVer)
+		fmt.Fprintf(w, "%13d.%02d subsystem version\n", opthdr.MajorSubsystemVer, opthdr.MinorSubsystemVer)
+		fmt.Fprintf(w, "%16X size of image\n", opthdr.ImageSize)
+		fmt.Fprintf(w, "%16X size of headers\n", opthdr.HdrSize)
+		fmt.Fprintf(w, "%16X checksum\n", opthdr.Checksum)
+		fmt.Fprintf(w, "%16X subsystem (%v)\n", uint16(opthdr.Subsystem), opthdr.Subsystem)
+		fmt.Fprintf(w, "%16X DLL characteristics (%v)\n", uint16(opthdr.Flags), opthdr.Flags)
+		fmt.Fprintf(w, "%16X size of stack reserve\n", opthdr.ReserveStackSize64)
+		fmt.Fprintf(w, "%16X size of stack commit\n", opthdr.InitStackSize64)
+		fmt.Fprintf(w, "%16X size of heap reserve\n", opthdr.ReserveHeapSize64)
+		fmt.Fprintf(w, "%16X size of heap commit\n", opthdr.InitHeapSize64)
+		fmt.Fprintf(w, "%16X loader flags\n", opthdr.LoaderFlags)
+		fmt.Fprintf(w, "%16X number of directories\n", opthdr.NDataDir)
+		fmt.Fprintln(w)
+
+		fmt.Fprintln(w, "DATA DIRECTORIES")
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "#\tRVA\tSize\tName")
+		for i, dataDir := range opthdr.DataDirs {
+			fmt.Fprintf(tw, "%d\t%08X\t%08X\t%s\n", i, dataDir.RelAddr, dataDir.Size, dataDirName(i))
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "SECTION TABLE")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tName\tVirtSize\tRVA\tRawSize\tRawOffset\tEntropy\tChiSquare\tPacked\tFlags")
+	for i, sectHdr := range sectHdrs {
+		stats, err := file.SectionStats(sectHdr)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%08X\t%08X\t%08X\t%08X\t%.3f\t%.1f\t%s\t%08X (%v)\n", i+1, sectHdr.Name, sectHdr.VirtSize, sectHdr.RelAddr, sectHdr.Size, sectHdr.Offset, stats.Entropy(), stats.ChiSquare(), packedNote(stats), uint32(sectHdr.Flags), sectHdr.Flags)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+
+	// Overlay.
+	stats, err := file.OverlayStats()
+	if err != nil {
+		return err
+	}
+	items, err := file.OverlayItems()
+	if err != nil {
+		return err
+	}
+	if stats.Size > 0 || len(items) > 0 {
+		fmt.Fprintln(w, "OVERLAY")
+		fmt.Fprintf(w, "%16X size\n", stats.Size)
+		fmt.Fprintf(w, "%16.3f entropy\n", stats.Entropy())
+		fmt.Fprintf(w, "%16.1f chi-square\n", stats.ChiSquare())
+		if note := packedNote(stats); note != "-" {
+			fmt.Fprintf(w, "%16s\n", note)
+		}
+		fmt.Fprintln(w)
+		tw = tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "Offset\tSize\tType")
+		for _, item := range items {
+			fmt.Fprintf(tw, "%08X\t%08X\t%v\n", item.Offset, item.Size, item.Type)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// packedNote returns a note flagging data which is likely encrypted or
+// compressed, based on its byte statistics; or "-" otherwise.
+func packedNote(stats *pe.Stats) string {
+	switch {
+	case stats.Uniform():
+		return "encrypted?"
+	case stats.HighEntropy():
+		return "compressed?"
+	default:
+		return "-"
+	}
+}
+
+// reportImports writes a formatted report of the imports of file to w.
+func reportImports(w io.Writer, file *pe.File) error {
+	imps, err := file.Imports()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "IMPORTS")
+	if len(imps) > 0 {
+		impHash, err := file.ImpHash()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  imphash %s\n\n", impHash)
+	}
+	for _, imp := range imps {
+		fmt.Fprintf(w, "  %s\n", imp.DLL)
+		fmt.Fprintf(w, "%16X import address table\n", imp.IATRelAddr)
+		fmt.Fprintf(w, "%16X import name table\n", imp.ILTRelAddr)
+		fmt.Fprintf(w, "%16X time date stamp\n", uint32(imp.Created))
+		fmt.Fprintf(w, "%16X index of first forwarder reference\n", imp.ForwarderChain)
+		fmt.Fprintln(w)
+		for _, fn := range imp.Funcs {
+			if fn.ByOrdinal {
+				fmt.Fprintf(w, "%16X  Ordinal %5d %s\n", fn.IATRelAddr, fn.Ordinal, fn.Name)
+			} else {
+				fmt.Fprintf(w, "%16X  %5X %s\n", fn.IATRelAddr, fn.Hint, fn.Name)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// reportExports writes a formatted report of the exports of file to w.
+func reportExports(w io.Writer, file *pe.File) error {
+	exps, err := file.Exports()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "EXPORTS")
+	if exps == nil {
+		fmt.Fprintln(w)
+		return nil
+	}
+	fmt.Fprintf(w, "  %s\n", exps.DLL)
+	fmt.Fprintf(w, "%16X characteristics\n", exps.Flags)
+	fmt.Fprintf(w, "%16X time date stamp\n", uint32(exps.Created))
+	fmt.Fprintf(w, "%13d.%02d version\n", exps.MajorVer, exps.MinorVer)
+	fmt.Fprintf(w, "%16d ordinal base\n", exps.OrdinalBase)
+	fmt.Fprintf(w, "%16d number of functions\n", exps.NAddr)
+	fmt.Fprintf(w, "%16d number of names\n", exps.NName)
+	expHash, err := file.ExpHash()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "  exphash %s\n", expHash)
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ordinal\tRVA\tname")
+	for _, fn := range exps.Funcs {
+		name := fn.Name
+		if len(name) == 0 {
+			name = "[NONAME]"
+		}
+		if len(fn.Forwarder) > 0 {
+			fmt.Fprintf(tw, "  %d\t\t%s (forwarded to %s)\n", fn.Ordinal, name, fn.Forwarder)
+			continue
+		}
+		fmt.Fprintf(tw, "  %d\t%08X\t%s\n", fn.Ordinal, fn.RelAddr, name)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	return nil
+}
+
+// reportResources writes a formatted report of the resource directory tree of
+// file to w.
+func reportResources(w io.Writer, file *pe.File) error {
+	root, err := file.Resources()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "RESOURCES")
+	if root == nil {
+		fmt.Fprintln(w)
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "  path\tRVA\tsize\tcode page")
+	root.Walk(func(path []*pe.ResourceNode, node *pe.ResourceNode) {
+		if !node.IsLeaf() {
+			return
+		}
+		fmt.Fprintf(tw, "  %s\t%08X\t%08X\t%d\n", resourcePath(path), node.Data.RelAddr, node.Data.Size, node.Data.CodePage)
+	})
+	tw.Flush()
+	fmt.Fprintln(w)
+	return nil
+}
+
+// reportAnomalies writes a formatted report of the anomalies of file to w.
+func reportAnomalies(w io.Writer, file *pe.File) error {
+	anomalies, err := file.Anomalies()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "ANOMALIES")
+	for _, anomaly := range anomalies {
+		fmt.Fprintf(w, "  %v\n", anomaly)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// reportSignatures writes a formatted report of the signatures matching file to
+// w, ranked by score.
+func reportSignatures(w io.Writer, file *pe.File) error {
+	matches, err := file.Identify()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "SIGNATURES")
+	for _, m := range matches {
+		fmt.Fprintf(w, "  %v\n", m)
+		for _, evidence := range m.Evidence {
+			fmt.Fprintf(w, "      %s\n", evidence)
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// reportEmbedded writes a formatted report of the PE files embedded in file to
+// w.
+func reportEmbedded(w io.Writer, file *pe.File) error {
+	embedded, err := file.Carve()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "EMBEDDED FILES")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Offset\tSize\tSource\tLocation\tNote")
+	for _, e := range embedded {
+		note := "-"
+		if e.Truncated {
+			note = "truncated"
+		}
+		loc := e.Location
+		if len(loc) == 0 {
+			loc = "-"
+		}
+		fmt.Fprintf(tw, "%08X\t%08X\t%v\t%s\t%s\n", e.Offset, e.Size, e.Source, loc, note)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	return nil
+}
+
+// reportHashes writes a formatted report of the hashes of file to w.
+func reportHashes(w io.Writer, file *pe.File) error {
+	hashes, err := file.Hashes()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "HASHES")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Data\tMD5\tSHA1\tSHA256")
+	hashLine := func(name string, h *pe.HashSet) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", name, h.MD5, h.SHA1, h.SHA256)
+	}
+	hashLine("file", &hashes.File)
+	if hashes.Authentihash != nil {
+		hashLine("authentihash", hashes.Authentihash)
+	}
+	for _, sect := range hashes.Sections {
+		hashLine(sect.Name, &sect.HashSet)
+	}
+	if hashes.Overlay != nil {
+		hashLine("overlay", hashes.Overlay)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	if len(hashes.RichHash) > 0 {
+		fmt.Fprintf(w, "  rich hash %s\n", hashes.RichHash)
+	}
+	if len(hashes.LSH) > 0 {
+		fmt.Fprintf(w, "  lsh       %s\n", hashes.LSH)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// reportStrings writes a formatted report of the printable strings of at least
+// minLen characters of file to w.
+func reportStrings(w io.Writer, file *pe.File, minLen int) error {
+	strs, err := file.Strings(minLen)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "STRINGS")
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "Offset\tRVA\tSection\tEncoding\tTags\tString")
+	for _, str := range strs {
+		relAddr := "-"
+		if str.Mapped {
+			relAddr = fmt.Sprintf("%08X", str.RelAddr)
+		}
+		sect := str.Section
+		if len(sect) == 0 {
+			sect = "-"
+		}
+		tags := str.Tags.String()
+		if len(tags) == 0 {
+			tags = "-"
+		}
+		fmt.Fprintf(tw, "%08X\t%s\t%s\t%v\t%s\t%q\n", str.Offset, relAddr, sect, str.Encoding, tags, str.Value)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+	return nil
+}
+
+// resourcePath returns a string representation of the given resource path,
+// using symbolic names for predefined resource types.
+func resourcePath(path []*pe.ResourceNode) string {
+	return strings.Join(resourcePathNames(path), "/")
+}
+
+// resourcePathNames returns the names of the nodes of the given resource path,
+// using symbolic names for predefined resource types.
+func resourcePathNames(path []*pe.ResourceNode) []string {
+	var ss []string
+	for i, node := range path {
+		s := node.String()
+		if i == 0 && len(node.Name) == 0 {
+			if name := pe.ResourceTypeName(node.ID); len(name) > 0 {
+				s = name
+			}
+		}
+		ss = append(ss, s)
+	}
+	return ss
+}
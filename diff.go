@@ -0,0 +1,585 @@
+package pe
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// DiffCategory specifies the structure of a difference between two PE files.
+type DiffCategory uint8
+
+// Categories of differences.
+const (
+	// DOS header, file header and optional header.
+	DiffHeaders DiffCategory = iota + 1
+	// Section table and section contents.
+	DiffSections
+	// Imported functions.
+	DiffImports
+	// Exported functions.
+	DiffExports
+	// Resources.
+	DiffResources
+	// Version information.
+	DiffVersionInfo
+)
+
+// diffCategoryName is a map from DiffCategory to string description.
+var diffCategoryName = map[DiffCategory]string{
+	DiffHeaders:     "headers",
+	DiffSections:    "sections",
+	DiffImports:     "imports",
+	DiffExports:     "exports",
+	DiffResources:   "resources",
+	DiffVersionInfo: "version info",
+}
+
+func (cat DiffCategory) String() string {
+	if s, ok := diffCategoryName[cat]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown diff category: 0x%02X", uint8(cat))
+}
+
+// DiffKind specifies the kind of a difference between two PE files.
+type DiffKind uint8
+
+// Kinds of differences.
+const (
+	// Item present in both files, with different values.
+	DiffChanged DiffKind = iota + 1
+	// Item only present in the second file.
+	DiffAdded
+	// Item only present in the first file.
+	DiffRemoved
+)
+
+// diffKindName is a map from DiffKind to string description.
+var diffKindName = map[DiffKind]string{
+	DiffChanged: "changed",
+	DiffAdded:   "added",
+	DiffRemoved: "removed",
+}
+
+func (kind DiffKind) String() string {
+	if s, ok := diffKindName[kind]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown diff kind: 0x%02X", uint8(kind))
+}
+
+// Difference represents a difference between two PE files.
+type Difference struct {
+	// Structure of the differing item.
+	Category DiffCategory
+	// Kind of the difference.
+	Kind DiffKind
+	// Name of the differing item (e.g. "OptHeader.ImageBase", ".text.SHA256",
+	// "kernel32.dll!ExitProcess", "RCDATA/#101/#1033").
+	Item string
+	// Value of the item in the first file; or empty if added.
+	Old string
+	// Value of the item in the second file; or empty if removed.
+	New string
+}
+
+func (d *Difference) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%v: added %s: %s", d.Category, d.Item, d.New)
+	case DiffRemoved:
+		return fmt.Sprintf("%v: removed %s: %s", d.Category, d.Item, d.Old)
+	}
+	return fmt.Sprintf("%v: changed %s: %s -> %s", d.Category, d.Item, d.Old, d.New)
+}
+
+// A DiffOption sets an option of Diff.
+type DiffOption func(conf *diffConfig)
+
+// diffConfig specifies the options of Diff.
+type diffConfig struct {
+	// Specifies whether to ignore fields expected to differ between builds.
+	ignoreNoise bool
+}
+
+// DiffIgnoreNoise returns a diff option which specifies whether to ignore
+// fields expected to differ between builds of the same code; i.e. timestamps
+// and checksums. The contents of sections are compared with the nondeterministic
+// fields zeroed.
+func DiffIgnoreNoise(ignore bool) DiffOption {
+	return func(conf *diffConfig) {
+		conf.ignoreNoise = ignore
+	}
+}
+
+// noiseFields specifies the fields expected to differ between builds of the
+// same code.
+var noiseFields = map[string]bool{
+	"DOSHeader.Checksum":        true,
+	"FileHeader.Created":        true,
+	"OptHeader.Checksum":        true,
+	"Exports.Created":           true,
+	"ResourceDirectory.Created": true,
+	"VersionInfo.FileDateMS":    true,
+	"VersionInfo.FileDateLS":    true,
+	// Certificate table.
+	"OptHeader.DataDirs[4].RelAddr": true,
+	"OptHeader.DataDirs[4].Size":    true,
+}
+
+// Diff compares the structure of two PE files, and returns their differences
+// in headers, section tables and contents, imports, exports, resources and
+// version information. Sections are matched by name, and their contents are
+// compared by SHA-256 hash.
+func Diff(a, b *File, opts ...DiffOption) ([]*Difference, error) {
+	var conf diffConfig
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	d := &differ{conf: conf}
+	steps := []func(a, b *File) error{
+		d.headers,
+		d.sections,
+		d.imports,
+		d.exports,
+		d.resources,
+		d.versionInfo,
+	}
+	for _, step := range steps {
+		if err := step(a, b); err != nil {
+			return nil, err
+		}
+	}
+	return d.diffs, nil
+}
+
+// differ records the differences between two PE files.
+type differ struct {
+	// Diff options.
+	conf diffConfig
+	// Differences recorded so far.
+	diffs []*Difference
+}
+
+// add records a difference of the given item, unless ignored as noise.
+func (d *differ) add(cat DiffCategory, kind DiffKind, item, old, new string) {
+	if d.conf.ignoreNoise && noiseFields[item] {
+		return
+	}
+	d.diffs = append(d.diffs, &Difference{Category: cat, Kind: kind, Item: item, Old: old, New: new})
+}
+
+// compare records a difference of the given item if old and new differ.
+func (d *differ) compare(cat DiffCategory, item, old, new string) {
+	if old != new {
+		d.add(cat, DiffChanged, item, old, new)
+	}
+}
+
+// compareStruct records the differences between the exported fields of the
+// given values of the same struct type, named by prefix. Fields of embedded
+// structs are named as fields of the outer struct.
+func (d *differ) compareStruct(cat DiffCategory, prefix string, a, b interface{}) {
+	d.compareValue(cat, prefix, reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// compareValue records the differences between the given values of the same
+// type, named by item.
+func (d *differ) compareValue(cat DiffCategory, item string, a, b reflect.Value) {
+	switch a.Kind() {
+	case reflect.Ptr:
+		switch {
+		case a.IsNil() && b.IsNil():
+		case a.IsNil():
+			d.add(cat, DiffAdded, item, "", "present")
+		case b.IsNil():
+			d.add(cat, DiffRemoved, item, "present", "")
+		default:
+			d.compareValue(cat, item, a.Elem(), b.Elem())
+		}
+	case reflect.Struct:
+		typ := a.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := item
+			if !field.Anonymous {
+				name += "." + field.Name
+			}
+			d.compareValue(cat, name, a.Field(i), b.Field(i))
+		}
+	case reflect.Slice:
+		n := a.Len()
+		if b.Len() > n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("%s[%d]", item, i)
+			switch {
+			case i >= a.Len():
+				d.add(cat, DiffAdded, name, "", formatValue(b.Index(i)))
+			case i >= b.Len():
+				d.add(cat, DiffRemoved, name, formatValue(a.Index(i)), "")
+			default:
+				d.compareValue(cat, name, a.Index(i), b.Index(i))
+			}
+		}
+	default:
+		d.compare(cat, item, formatValue(a), formatValue(b))
+	}
+}
+
+// formatValue returns the string representation of the given value; integers
+// without a String method are formatted in hexadecimal.
+func formatValue(v reflect.Value) string {
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("0x%X", v.Uint())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// headers records the differences between the headers of a and b. Only the
+// file headers are compared if either file is a COFF object file.
+func (d *differ) headers(a, b *File) error {
+	fileHdrA, err := a.FileHeader()
+	if err != nil {
+		return err
+	}
+	fileHdrB, err := b.FileHeader()
+	if err != nil {
+		return err
+	}
+	objA, err := a.IsObject()
+	if err != nil {
+		return err
+	}
+	objB, err := b.IsObject()
+	if err != nil {
+		return err
+	}
+	if objA || objB {
+		d.compareStruct(DiffHeaders, "FileHeader", fileHdrA, fileHdrB)
+		return nil
+	}
+	dosHdrA, err := a.DOSHeader()
+	if err != nil {
+		return err
+	}
+	dosHdrB, err := b.DOSHeader()
+	if err != nil {
+		return err
+	}
+	d.compareStruct(DiffHeaders, "DOSHeader", dosHdrA, dosHdrB)
+	d.compareStruct(DiffHeaders, "FileHeader", fileHdrA, fileHdrB)
+	optHdrA, err := a.OptHeader()
+	if err != nil {
+		return err
+	}
+	optHdrB, err := b.OptHeader()
+	if err != nil {
+		return err
+	}
+	d.compareStruct(DiffHeaders, "OptHeader", optHdrA, optHdrB)
+	return nil
+}
+
+// sections records the differences between the section tables and section
+// contents of a and b. Sections with duplicate names are matched in order of
+// occurrence, with "#n" appended to the name of the nth duplicate.
+func (d *differ) sections(a, b *File) error {
+	sectsA, err := sectionsByName(a, d.conf.ignoreNoise)
+	if err != nil {
+		return err
+	}
+	sectsB, err := sectionsByName(b, d.conf.ignoreNoise)
+	if err != nil {
+		return err
+	}
+	for _, name := range unionKeys(sectsA, sectsB) {
+		sectA, sectB := sectsA[name], sectsB[name]
+		switch {
+		case sectA == nil:
+			d.add(DiffSections, DiffAdded, name, "", sectB.String())
+		case sectB == nil:
+			d.add(DiffSections, DiffRemoved, name, sectA.String(), "")
+		default:
+			d.compareStruct(DiffSections, name, sectA.hdr, sectB.hdr)
+			d.compare(DiffSections, name+".SHA256", sectA.hash, sectB.hash)
+		}
+	}
+	return nil
+}
+
+// diffSection represents a section compared by Diff.
+type diffSection struct {
+	// Section header.
+	hdr *SectHeader
+	// SHA-256 hash of the section contents.
+	hash string
+}
+
+func (sect *diffSection) String() string {
+	return fmt.Sprintf("address 0x%08X, size 0x%X, SHA256 %s", sect.hdr.RelAddr, sect.hdr.Size, sect.hash)
+}
+
+// sectionsByName returns the sections of file, keyed by name. If normalize is
+// set, the section contents are hashed with the nondeterministic fields zeroed.
+func sectionsByName(file *File, normalize bool) (map[string]*diffSection, error) {
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	var fields []*NormalizedField
+	if normalize {
+		if fields, err = file.normalizedFields(); err != nil {
+			return nil, err
+		}
+	}
+	sects := make(map[string]*diffSection)
+	count := make(map[string]int)
+	for _, sectHdr := range sectHdrs {
+		name := sectHdr.Name
+		if count[name]++; count[name] > 1 {
+			name = fmt.Sprintf("%s#%d", name, count[name])
+		}
+		h := sha256.New()
+		off, size := file.sectExtent(sectHdr)
+		if err := copyNormalized(h, file.r, off, size, fields); err != nil {
+			return nil, err
+		}
+		sects[name] = &diffSection{hdr: sectHdr, hash: hex.EncodeToString(h.Sum(nil))}
+	}
+	return sects, nil
+}
+
+// imports records the differences between the imported functions of a and b.
+// DLL names are compared case-insensitively.
+func (d *differ) imports(a, b *File) error {
+	impsA, err := importSet(a)
+	if err != nil {
+		return err
+	}
+	impsB, err := importSet(b)
+	if err != nil {
+		return err
+	}
+	for _, key := range unionKeys(impsA, impsB) {
+		impA, okA := impsA[key]
+		impB, okB := impsB[key]
+		switch {
+		case !okA:
+			d.add(DiffImports, DiffAdded, key, "", impB)
+		case !okB:
+			d.add(DiffImports, DiffRemoved, key, impA, "")
+		}
+	}
+	return nil
+}
+
+// importSet returns the imported functions of file, as a map from lowercase
+// "dll!func" to the name of the function with the DLL in its original case.
+func importSet(file *File) (map[string]string, error) {
+	imps, err := file.Imports()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]string)
+	for _, imp := range imps {
+		for _, fn := range imp.Funcs {
+			name := imp.DLL + "!" + fn.String()
+			set[strings.ToLower(imp.DLL)+"!"+fn.String()] = name
+		}
+	}
+	return set, nil
+}
+
+// exports records the differences between the exported functions of a and b.
+// Functions are matched by name, or by ordinal if exported by ordinal only.
+func (d *differ) exports(a, b *File) error {
+	expsA, err := a.Exports()
+	if err != nil {
+		return err
+	}
+	expsB, err := b.Exports()
+	if err != nil {
+		return err
+	}
+	if expsA == nil && expsB == nil {
+		return nil
+	}
+	var dirA, dirB *ExportDirectory
+	if expsA != nil {
+		dirA = &expsA.ExportDirectory
+	}
+	if expsB != nil {
+		dirB = &expsB.ExportDirectory
+	}
+	d.compareStruct(DiffExports, "Exports", dirA, dirB)
+	if expsA != nil && expsB != nil {
+		d.compare(DiffExports, "Exports.DLL", expsA.DLL, expsB.DLL)
+	}
+	fnsA, fnsB := exportSet(expsA), exportSet(expsB)
+	for _, key := range unionKeys(fnsA, fnsB) {
+		fnA, fnB := fnsA[key], fnsB[key]
+		switch {
+		case fnA == nil:
+			d.add(DiffExports, DiffAdded, key, "", exportTarget(fnB))
+		case fnB == nil:
+			d.add(DiffExports, DiffRemoved, key, exportTarget(fnA), "")
+		default:
+			d.compare(DiffExports, key, exportTarget(fnA), exportTarget(fnB))
+		}
+	}
+	return nil
+}
+
+// exportSet returns the exported functions of exps, keyed by name; or by "#n"
+// for functions exported by ordinal n only.
+func exportSet(exps *Exports) map[string]*ExportFunc {
+	set := make(map[string]*ExportFunc)
+	if exps == nil {
+		return set
+	}
+	for _, fn := range exps.Funcs {
+		key := fn.Name
+		if len(key) == 0 {
+			key = fmt.Sprintf("#%d", fn.Ordinal)
+		}
+		set[key] = fn
+	}
+	return set
+}
+
+// exportTarget returns the ordinal and address or forwarder of the given
+// exported function.
+func exportTarget(fn *ExportFunc) string {
+	if len(fn.Forwarder) > 0 {
+		return fmt.Sprintf("#%d -> %s", fn.Ordinal, fn.Forwarder)
+	}
+	return fmt.Sprintf("#%d at 0x%08X", fn.Ordinal, fn.RelAddr)
+}
+
+// resources records the differences between the resources of a and b. Resource
+// leaves are matched by path, and their contents are compared by SHA-256 hash.
+func (d *differ) resources(a, b *File) error {
+	rootA, leavesA, err := resourceSet(a)
+	if err != nil {
+		return err
+	}
+	rootB, leavesB, err := resourceSet(b)
+	if err != nil {
+		return err
+	}
+	var dirA, dirB *ResourceDirectory
+	if rootA != nil {
+		dirA = rootA.Dir
+	}
+	if rootB != nil {
+		dirB = rootB.Dir
+	}
+	d.compareStruct(DiffResources, "ResourceDirectory", dirA, dirB)
+	for _, path := range unionKeys(leavesA, leavesB) {
+		leafA, okA := leavesA[path]
+		leafB, okB := leavesB[path]
+		switch {
+		case !okA:
+			d.add(DiffResources, DiffAdded, path, "", leafB)
+		case !okB:
+			d.add(DiffResources, DiffRemoved, path, leafA, "")
+		default:
+			d.compare(DiffResources, path, leafA, leafB)
+		}
+	}
+	return nil
+}
+
+// resourceSet returns the resource tree root of file, and the size and SHA-256
+// hash of its leaves keyed by path. The contents of leaves located outside of
+// the file are hashed as empty.
+func resourceSet(file *File) (*ResourceNode, map[string]string, error) {
+	root, err := file.Resources()
+	if err != nil {
+		return nil, nil, err
+	}
+	leaves := make(map[string]string)
+	if root == nil {
+		return nil, leaves, nil
+	}
+	root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+		if !node.IsLeaf() {
+			return
+		}
+		data, _ := file.ResourceData(node.Data)
+		sum := sha256.Sum256(data)
+		leaves[resourcePathString(path)] = fmt.Sprintf("size 0x%X, SHA256 %s", node.Data.Size, hex.EncodeToString(sum[:]))
+	})
+	return root, leaves, nil
+}
+
+// versionInfo records the differences between the version information of a
+// and b. Strings are matched by language and name.
+func (d *differ) versionInfo(a, b *File) error {
+	infoA, err := a.VersionInfo()
+	if err != nil {
+		return err
+	}
+	infoB, err := b.VersionInfo()
+	if err != nil {
+		return err
+	}
+	var fixedA, fixedB *FixedFileInfo
+	strsA, strsB := make(map[string]string), make(map[string]string)
+	if infoA != nil {
+		fixedA = infoA.Fixed
+		for _, s := range infoA.Strings {
+			strsA[s.Lang+"/"+s.Key] = s.Value
+		}
+	}
+	if infoB != nil {
+		fixedB = infoB.Fixed
+		for _, s := range infoB.Strings {
+			strsB[s.Lang+"/"+s.Key] = s.Value
+		}
+	}
+	d.compareStruct(DiffVersionInfo, "VersionInfo", fixedA, fixedB)
+	for _, key := range unionKeys(strsA, strsB) {
+		strA, okA := strsA[key]
+		strB, okB := strsB[key]
+		switch {
+		case !okA:
+			d.add(DiffVersionInfo, DiffAdded, key, "", strB)
+		case !okB:
+			d.add(DiffVersionInfo, DiffRemoved, key, strA, "")
+		default:
+			d.compare(DiffVersionInfo, key, strA, strB)
+		}
+	}
+	return nil
+}
+
+// unionKeys returns the sorted union of the keys of the maps a and b, which
+// are keyed by string.
+func unionKeys(a, b interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range []interface{}{a, b} {
+		for _, key := range reflect.ValueOf(m).MapKeys() {
+			if !seen[key.String()] {
+				seen[key.String()] = true
+				keys = append(keys, key.String())
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,118 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	const optHdrOff = synthPEHdrOffset + 4 + coffHdrSize
+	a := synthImage{}.bytes()
+	b := append(synthBuffer(nil), a...)
+	b.put32(synthPEHdrOffset+4+4, 0x12345678) // FileHeader.Created
+	b.put16(optHdrOff+0x2C, 2)                // OptHeader.MajorImageVer
+	b.putString(sectOff(synthHintRelAddr)+2, "ExitThread")
+	b.putString(sectOff(synthExpNameRelAddr), "Bar")
+	copy(b[sectOff(synthRsrcDataRelAddr):], "DATB")
+
+	fileA, err := New(bytes.NewReader(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileB, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := []struct {
+		opts []DiffOption
+		want []string
+	}{
+		{
+			want: []string{
+				"headers: changed FileHeader.Created",
+				"headers: changed OptHeader.MajorImageVer",
+				"sections: changed .data.SHA256",
+				"imports: removed kernel32.dll!ExitProcess",
+				"imports: added kernel32.dll!ExitThread",
+				"exports: added Bar",
+				"exports: removed Foo",
+				"resources: changed ICON/ABC/#1033",
+			},
+		},
+		{
+			opts: []DiffOption{DiffIgnoreNoise(true)},
+			want: []string{
+				"headers: changed OptHeader.MajorImageVer",
+				"sections: changed .data.SHA256",
+				"imports: removed kernel32.dll!ExitProcess",
+				"imports: added kernel32.dll!ExitThread",
+				"exports: added Bar",
+				"exports: removed Foo",
+				"resources: changed ICON/ABC/#1033",
+			},
+		},
+	}
+	for _, g := range golden {
+		diffs, err := Diff(fileA, fileB, g.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, d := range diffs {
+			got = append(got, d.Category.String()+": "+d.Kind.String()+" "+d.Item)
+		}
+		if len(got) != len(g.want) {
+			t.Errorf("expected %d differences, got %d: %q", len(g.want), len(got), got)
+			continue
+		}
+		for i := range got {
+			if got[i] != g.want[i] {
+				t.Errorf("difference %d mismatch; expected %q, got %q", i, g.want[i], got[i])
+			}
+		}
+	}
+
+	// Identical files.
+	diffs, err := Diff(fileA, fileA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+}
+
+// TestDiffIgnoreNoise checks that two builds of the same code, which differ only
+// in their nondeterministic fields, are reported as identical.
+func TestDiffIgnoreNoise(t *testing.T) {
+	fileA, err := New(bytes.NewReader(synthReproImage(0x11111111, 0x1234, 0xAA, 0xBB)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileB, err := New(bytes.NewReader(synthReproImage(0x22222222, 0x5678, 0xCC, 0xDD)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := Diff(fileA, fileB, DiffIgnoreNoise(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences, got %v", diffs)
+	}
+
+	// Without the option, the timestamps within sections change their hashes.
+	diffs, err = Diff(fileA, fileB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, d := range diffs {
+		if d.Category == DiffSections && d.Item == ".data.SHA256" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("missing difference of .data.SHA256; got %v", diffs)
+	}
+}
@@ -22,6 +22,33 @@ type ImageDebugDirectory struct {
 	PointerToRawData uint32
 }
 
+// debugDirSize specifies the size of debug directory entries in bytes.
+const debugDirSize = 28
+
+// DebugDirs returns the entries of the debug directory of file, in table order.
+func (file *File) DebugDirs() ([]*ImageDebugDirectory, error) {
+	dataDir, err := file.dataDir(DataDirDebug)
+	if err != nil {
+		return nil, err
+	}
+	if dataDir.RelAddr == 0 || dataDir.Size < debugDirSize {
+		return nil, nil
+	}
+	n := int64(dataDir.Size / debugDirSize)
+	if err := checkLimit(limitDirEntries, "debug directory", n, int64(file.conf.limits.MaxDirEntries)); err != nil {
+		return nil, err
+	}
+	entries := make([]ImageDebugDirectory, n)
+	if err := file.readRelAddr(dataDir.RelAddr, entries, "debug directory"); err != nil {
+		return nil, err
+	}
+	dirs := make([]*ImageDebugDirectory, n)
+	for i := range entries {
+		dirs[i] = &entries[i]
+	}
+	return dirs, nil
+}
+
 //go:generate stringer -trimprefix ImageDebugType -type ImageDebugType
 
 // ImageDebugType specifies the format of the debugging information pointed to
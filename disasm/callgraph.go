@@ -0,0 +1,145 @@
+package disasm
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// Func represents a function discovered by recursive descent disassembly.
+type Func struct {
+	// Start address, name and source of the function.
+	FuncStart
+	// Instructions of the function, ordered by address; i.e. the instructions
+	// reachable from the start address without following calls, or jumps to
+	// the start of other functions.
+	Insts []*Inst
+	// Functions called by the function, ordered by address; including tail
+	// calls through jumps to the start of other functions.
+	Callees []*Func
+	// Functions calling the function, ordered by address.
+	Callers []*Func
+	// Imported functions (e.g. "kernel32.dll!ExitProcess") called by the
+	// function, either directly through their IAT entry or through a jump
+	// thunk; sorted alphabetically.
+	Imports []string
+}
+
+// String returns the name of the function; or "sub_XXXXXXXX", with the address
+// of the function relative to the image base, if unknown.
+func (fn *Func) String() string {
+	if len(fn.Name) > 0 {
+		return fn.Name
+	}
+	return fmt.Sprintf("sub_%08X", fn.RelAddr)
+}
+
+// CallGraph represents the functions of an image and the calls between them.
+type CallGraph struct {
+	// Functions, ordered by address.
+	Funcs []*Func
+}
+
+// Func returns the function starting at the given address relative to the
+// image base; or nil if not present.
+func (g *CallGraph) Func(relAddr uint32) *Func {
+	i := sort.Search(len(g.Funcs), func(i int) bool {
+		return g.Funcs[i].RelAddr >= relAddr
+	})
+	if i < len(g.Funcs) && g.Funcs[i].RelAddr == relAddr {
+		return g.Funcs[i]
+	}
+	return nil
+}
+
+// CallGraph discovers the functions of the image and returns their call graph.
+// Functions are discovered by recursive descent disassembly from the function
+// starts recorded in the headers of the image (see FuncStarts), and from the
+// targets of direct call instructions. Calls of jump thunks to imported
+// functions are recorded as calls of the imported functions, and the thunks
+// themselves are not reported as functions unless recorded in the headers.
+func (d *Disassembler) CallGraph() (*CallGraph, error) {
+	starts, err := d.FuncStarts()
+	if err != nil {
+		return nil, err
+	}
+	funcs := make(map[uint64]*Func)
+	var queue []*Func
+	add := func(start FuncStart) {
+		fn := &Func{FuncStart: start}
+		funcs[d.img.Base+uint64(start.RelAddr)] = fn
+		queue = append(queue, fn)
+	}
+	for _, start := range starts {
+		add(*start)
+	}
+
+	// Discover the targets of direct calls, until no new functions are found.
+	local := func(inst *Inst) bool {
+		return inst.Op != x86asm.CALL && d.executable(inst.Target)
+	}
+	for len(queue) > 0 {
+		fn := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for _, inst := range d.flow([]uint64{d.img.Base + uint64(fn.RelAddr)}, local) {
+			if !d.isCall(inst) || funcs[inst.Target] != nil {
+				continue
+			}
+			add(FuncStart{RelAddr: uint32(inst.Target - d.img.Base), Source: StartCall})
+		}
+	}
+
+	// Disassemble the function bodies, which end at the start of other
+	// functions, as the set of functions is now known.
+	body := func(inst *Inst) bool {
+		return local(inst) && funcs[inst.Target] == nil
+	}
+	g := &CallGraph{}
+	for _, fn := range funcs {
+		g.Funcs = append(g.Funcs, fn)
+	}
+	sort.Slice(g.Funcs, func(i, j int) bool {
+		return g.Funcs[i].RelAddr < g.Funcs[j].RelAddr
+	})
+	for _, fn := range g.Funcs {
+		fn.Insts = d.flow([]uint64{d.img.Base + uint64(fn.RelAddr)}, body)
+		callees := make(map[*Func]bool)
+		imps := make(map[string]bool)
+		for _, inst := range fn.Insts {
+			if inst.Op != x86asm.CALL && inst.Op != x86asm.JMP {
+				continue
+			}
+			if len(inst.Import) > 0 {
+				imps[inst.Import] = true
+				continue
+			}
+			callee := funcs[inst.Target]
+			if callee == nil || isIndirect(inst.Inst) || callees[callee] {
+				continue
+			}
+			// Jumps to the start of the function itself are loops.
+			if callee == fn && inst.Op == x86asm.JMP {
+				continue
+			}
+			// Callers are appended in address order of the calling functions.
+			callees[callee] = true
+			fn.Callees = append(fn.Callees, callee)
+			callee.Callers = append(callee.Callers, fn)
+		}
+		sort.Slice(fn.Callees, func(i, j int) bool {
+			return fn.Callees[i].RelAddr < fn.Callees[j].RelAddr
+		})
+		for imp := range imps {
+			fn.Imports = append(fn.Imports, imp)
+		}
+		sort.Strings(fn.Imports)
+	}
+	return g, nil
+}
+
+// isCall reports whether the instruction is a direct call of a function within
+// an executable section, other than a jump thunk of an imported function.
+func (d *Disassembler) isCall(inst *Inst) bool {
+	return inst.Op == x86asm.CALL && !isIndirect(inst.Inst) && len(inst.Import) == 0 && d.executable(inst.Target)
+}
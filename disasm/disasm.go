@@ -0,0 +1,409 @@
+// Package disasm disassembles the x86 and x64 code of parsed PE images.
+//
+// Code is disassembled either linearly from a given address, or recursively by
+// following the control flow from the function starts of the image; i.e. the
+// entry point, exported functions and the functions of the exception table
+// (.pdata section). Instructions calling or jumping to imported functions
+// through the import address table (IAT) are annotated with the name of the
+// imported function.
+package disasm
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mewrev/pe"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// Disassembler disassembles the code of a PE image.
+type Disassembler struct {
+	// Parsed PE file.
+	file *pe.File
+	// Image mapped at its preferred base address.
+	img *pe.Image
+	// Processor mode; 32 or 64.
+	mode int
+	// Section headers.
+	sectHdrs []*pe.SectHeader
+	// Imported functions, keyed by the virtual address of their IAT entry.
+	imps map[uint64]string
+}
+
+// New returns a new disassembler for the x86 or x64 image of file. The image is
+// mapped into memory at its preferred base address.
+func New(file *pe.File) (*Disassembler, error) {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return nil, err
+	}
+	d := &Disassembler{file: file, imps: make(map[uint64]string)}
+	switch fileHdr.Arch {
+	case pe.ArchI386:
+		d.mode = 32
+	case pe.ArchAMD64:
+		d.mode = 64
+	default:
+		return nil, fmt.Errorf("disasm: unsupported architecture %v", fileHdr.Arch)
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return nil, err
+	}
+	if d.img, err = file.Load(opthdr.ImageBase64); err != nil {
+		return nil, err
+	}
+	if d.sectHdrs, err = file.SectHeaders(); err != nil {
+		return nil, err
+	}
+	imps, err := file.Imports()
+	if err != nil {
+		return nil, err
+	}
+	for _, imp := range imps {
+		for _, fn := range imp.Funcs {
+			name := fn.Name
+			if len(name) == 0 {
+				name = fmt.Sprintf("#%d", fn.Ordinal)
+			}
+			d.imps[d.img.Base+uint64(fn.IATRelAddr)] = imp.DLL + "!" + name
+		}
+	}
+	return d, nil
+}
+
+// StartSource specifies where a function start was located.
+type StartSource uint8
+
+// Sources of function starts.
+const (
+	// Entry point of the image.
+	StartEntryPoint StartSource = iota + 1
+	// Exported function.
+	StartExport
+	// Entry of the exception table.
+	StartRuntimeFunc
+	// TLS callback.
+	StartTLSCallback
+	// Entry of the control flow guard function table.
+	StartGuardCF
+	// Target of a direct call instruction.
+	StartCall
+)
+
+// startSourceName is a map from StartSource to string description.
+var startSourceName = map[StartSource]string{
+	StartEntryPoint:  "entry point",
+	StartExport:      "export",
+	StartRuntimeFunc: "exception table",
+	StartTLSCallback: "TLS callback",
+	StartGuardCF:     "CFG function table",
+	StartCall:        "call target",
+}
+
+func (src StartSource) String() string {
+	if s, ok := startSourceName[src]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown start source: 0x%02X", uint8(src))
+}
+
+// FuncStart represents the start address of a function.
+type FuncStart struct {
+	// Address of the function, relative to the image base.
+	RelAddr uint32
+	// Name of the function; or empty if unknown.
+	Name string
+	// Location of the function start.
+	Source StartSource
+}
+
+// FuncStarts returns the function starts recorded in the headers of the image,
+// ordered by address; i.e. the entry point, exported functions, TLS callbacks
+// and the functions of the exception table and control flow guard function
+// table. Function starts located outside of executable sections (e.g. exported
+// data) are skipped, and each address is reported once, with the source listed
+// first above. Malformed directories are skipped, as the function starts of the
+// other sources remain usable.
+func (d *Disassembler) FuncStarts() ([]*FuncStart, error) {
+	var starts []*FuncStart
+	seen := make(map[uint32]bool)
+	add := func(start *FuncStart) {
+		if seen[start.RelAddr] || !d.executable(d.img.Base+uint64(start.RelAddr)) {
+			return
+		}
+		seen[start.RelAddr] = true
+		starts = append(starts, start)
+	}
+
+	opthdr, err := d.file.OptHeader()
+	if err != nil {
+		return nil, err
+	}
+	if opthdr.EntryRelAddr != 0 {
+		add(&FuncStart{RelAddr: opthdr.EntryRelAddr, Source: StartEntryPoint})
+	}
+	if exps, err := d.file.Exports(); err == nil && exps != nil {
+		for _, fn := range exps.Funcs {
+			if len(fn.Forwarder) > 0 || fn.RelAddr == 0 {
+				continue
+			}
+			name := fn.Name
+			if len(name) == 0 {
+				name = fmt.Sprintf("#%d", fn.Ordinal)
+			}
+			add(&FuncStart{RelAddr: fn.RelAddr, Name: name, Source: StartExport})
+		}
+	}
+	if tls, err := d.file.TLS(); err == nil && tls != nil {
+		for _, relAddr := range tls.Callbacks {
+			add(&FuncStart{RelAddr: relAddr, Source: StartTLSCallback})
+		}
+	}
+	if funcs, err := d.file.RuntimeFuncs(); err == nil {
+		for _, fn := range funcs {
+			add(&FuncStart{RelAddr: fn.BeginRelAddr, Source: StartRuntimeFunc})
+		}
+	}
+	if guardFuncs, err := d.file.GuardCFFuncs(); err == nil {
+		for _, relAddr := range guardFuncs {
+			add(&FuncStart{RelAddr: relAddr, Source: StartGuardCF})
+		}
+	}
+
+	sort.Slice(starts, func(i, j int) bool {
+		return starts[i].RelAddr < starts[j].RelAddr
+	})
+	return starts, nil
+}
+
+// Inst represents a disassembled instruction.
+type Inst struct {
+	// Decoded instruction; or the zero value (Op 0 and Len 1) for bytes which
+	// could not be decoded.
+	x86asm.Inst
+	// Virtual address of the instruction.
+	Addr uint64
+	// Address of the instruction, relative to the image base.
+	RelAddr uint32
+	// Virtual address of the direct branch target or referenced memory
+	// operand; or 0 if not present.
+	Target uint64
+	// Name of the imported function (e.g. "kernel32.dll!ExitProcess") called
+	// or jumped to through its IAT entry, either directly or through a jump
+	// thunk; or empty if not present.
+	Import string
+	// Disassembler of the instruction, used for symbolic names.
+	d *Disassembler
+}
+
+// String returns the instruction in Intel syntax, with references to IAT
+// entries replaced by the names of the imported functions.
+func (inst *Inst) String() string {
+	if inst.Op == 0 {
+		return "(bad)"
+	}
+	s := x86asm.IntelSyntax(inst.Inst, inst.Addr, inst.d.symbol)
+	if len(inst.Import) > 0 && inst.d.imps[inst.Target] == "" {
+		// Direct call of a jump thunk.
+		s += " ; " + inst.Import
+	}
+	return s
+}
+
+// symbol returns the name and base address of the symbol containing the given
+// virtual address, as required by x86asm.SymLookup.
+func (d *Disassembler) symbol(va uint64) (string, uint64) {
+	if name, ok := d.imps[va]; ok {
+		return name, va
+	}
+	return "", 0
+}
+
+// decode decodes the instruction at the given virtual address.
+func (d *Disassembler) decode(va uint64) *Inst {
+	relAddr, _ := d.img.RelAddr(va)
+	inst := &Inst{Addr: va, RelAddr: relAddr, d: d}
+	code := d.img.Mem[relAddr:]
+	x, err := x86asm.Decode(code, d.mode)
+	if err != nil {
+		inst.Len = 1
+		return inst
+	}
+	inst.Inst = x
+	next := va + uint64(x.Len)
+	for _, arg := range x.Args {
+		switch arg := arg.(type) {
+		case x86asm.Rel:
+			inst.Target = next + uint64(int64(arg))
+		case x86asm.Mem:
+			inst.Target = d.memTarget(arg, next)
+		}
+	}
+	if isBranch(x.Op) {
+		if name, ok := d.imps[inst.Target]; ok && isIndirect(x) {
+			inst.Import = name
+		} else if !isIndirect(x) && d.img.Contains(inst.Target) {
+			inst.Import = d.thunkImport(inst.Target)
+		}
+	}
+	return inst
+}
+
+// thunkImport returns the name of the imported function jumped to by the jump
+// thunk at the given virtual address; or empty if not a jump thunk.
+func (d *Disassembler) thunkImport(va uint64) string {
+	relAddr, ok := d.img.RelAddr(va)
+	if !ok {
+		return ""
+	}
+	x, err := x86asm.Decode(d.img.Mem[relAddr:], d.mode)
+	if err != nil || x.Op != x86asm.JMP {
+		return ""
+	}
+	mem, ok := x.Args[0].(x86asm.Mem)
+	if !ok {
+		return ""
+	}
+	return d.imps[d.memTarget(mem, va+uint64(x.Len))]
+}
+
+// memTarget returns the virtual address referenced by the given memory operand
+// of the instruction preceding next; or 0 if not statically known. Absolute
+// addresses are used in 32-bit mode and RIP-relative addresses in 64-bit mode.
+func (d *Disassembler) memTarget(mem x86asm.Mem, next uint64) uint64 {
+	switch {
+	case mem.Base == x86asm.RIP && mem.Index == 0:
+		return next + uint64(mem.Disp)
+	case mem.Base == 0 && mem.Index == 0 && d.mode == 32:
+		return uint64(uint32(mem.Disp))
+	}
+	return 0
+}
+
+// Linear disassembles size bytes of code linearly, starting at the given
+// address relative to the image base. Bytes which cannot be decoded are
+// skipped one at a time.
+func (d *Disassembler) Linear(relAddr uint32, size int) ([]*Inst, error) {
+	end := uint64(relAddr) + uint64(size)
+	if end > uint64(len(d.img.Mem)) {
+		return nil, fmt.Errorf("disasm: range 0x%08X-0x%08X outside of image", relAddr, end)
+	}
+	var insts []*Inst
+	for va := d.img.Base + uint64(relAddr); va < d.img.Base+end; {
+		inst := d.decode(va)
+		insts = append(insts, inst)
+		va += uint64(inst.Len)
+	}
+	return insts, nil
+}
+
+// Recursive disassembles code recursively, following the control flow from
+// the given addresses relative to the image base. Branch and call targets
+// within executable sections are followed, and disassembly of a path stops at
+// returns, unconditional jumps and bytes which cannot be decoded. The
+// instructions are returned ordered by address.
+func (d *Disassembler) Recursive(relAddrs ...uint32) ([]*Inst, error) {
+	var vas []uint64
+	for _, relAddr := range relAddrs {
+		if !d.executable(d.img.Base + uint64(relAddr)) {
+			return nil, fmt.Errorf("disasm: address 0x%08X outside of executable sections", relAddr)
+		}
+		vas = append(vas, d.img.Base+uint64(relAddr))
+	}
+	follow := func(inst *Inst) bool {
+		return d.executable(inst.Target)
+	}
+	return d.flow(vas, follow), nil
+}
+
+// flow disassembles code recursively from the given virtual addresses, and
+// returns the instructions ordered by address. The direct branch and call
+// targets for which follow reports true are disassembled in turn.
+func (d *Disassembler) flow(vas []uint64, follow func(inst *Inst) bool) []*Inst {
+	visited := make(map[uint64]*Inst)
+	queue := append([]uint64(nil), vas...)
+	for len(queue) > 0 {
+		va := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		for d.executable(va) && visited[va] == nil {
+			inst := d.decode(va)
+			visited[va] = inst
+			if inst.Op == 0 {
+				break
+			}
+			if isBranch(inst.Op) && !isIndirect(inst.Inst) && follow(inst) {
+				queue = append(queue, inst.Target)
+			}
+			if isTerminator(inst.Inst) {
+				break
+			}
+			va += uint64(inst.Len)
+		}
+	}
+	insts := make([]*Inst, 0, len(visited))
+	for _, inst := range visited {
+		insts = append(insts, inst)
+	}
+	sort.Slice(insts, func(i, j int) bool {
+		return insts[i].Addr < insts[j].Addr
+	})
+	return insts
+}
+
+// executable reports whether the given virtual address is located within an
+// executable section.
+func (d *Disassembler) executable(va uint64) bool {
+	relAddr, ok := d.img.RelAddr(va)
+	if !ok {
+		return false
+	}
+	for _, sectHdr := range d.sectHdrs {
+		if sectHdr.Flags&(pe.SectFlagMemExec|pe.SectFlagCode) == 0 {
+			continue
+		}
+		size := sectHdr.VirtSize
+		if size == 0 {
+			size = sectHdr.Size
+		}
+		if relAddr >= sectHdr.RelAddr && relAddr-sectHdr.RelAddr < size {
+			return true
+		}
+	}
+	return false
+}
+
+// isBranch reports whether the instruction operation transfers control to a
+// branch target.
+func isBranch(op x86asm.Op) bool {
+	switch op {
+	case x86asm.CALL, x86asm.JMP,
+		x86asm.JA, x86asm.JAE, x86asm.JB, x86asm.JBE, x86asm.JCXZ, x86asm.JE,
+		x86asm.JECXZ, x86asm.JG, x86asm.JGE, x86asm.JL, x86asm.JLE, x86asm.JNE,
+		x86asm.JNO, x86asm.JNP, x86asm.JNS, x86asm.JO, x86asm.JP, x86asm.JRCXZ,
+		x86asm.JS, x86asm.LOOP, x86asm.LOOPE, x86asm.LOOPNE:
+		return true
+	}
+	return false
+}
+
+// isIndirect reports whether the branch target of the instruction is located
+// in a register or memory rather than encoded as a relative offset.
+func isIndirect(x x86asm.Inst) bool {
+	_, ok := x.Args[0].(x86asm.Rel)
+	return !ok
+}
+
+// isTerminator reports whether control never flows from the instruction to the
+// succeeding one.
+func isTerminator(x x86asm.Inst) bool {
+	switch x.Op {
+	case x86asm.RET, x86asm.LRET, x86asm.JMP, x86asm.HLT, x86asm.UD2, x86asm.IRET, x86asm.IRETD, x86asm.IRETQ:
+		return true
+	case x86asm.INT:
+		// int3 is used as padding between functions.
+		imm, ok := x.Args[0].(x86asm.Imm)
+		return ok && imm == 3
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package disasm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/mewrev/pe"
+)
+
+// testdataPath returns the path of the given test executable of the debug/pe
+// package of the Go distribution, skipping the test if not present.
+func testdataPath(t *testing.T, name string) string {
+	path := filepath.Join(runtime.GOROOT(), "src", "debug", "pe", "testdata", name)
+	if _, err := os.Stat(path); err != nil {
+		t.Skipf("test executable %q not present", path)
+	}
+	return path
+}
+
+// openTestdata opens the given test executable of the debug/pe package of the
+// Go distribution, skipping the test if not present.
+func openTestdata(t *testing.T, name string) *pe.File {
+	file, err := pe.Open(testdataPath(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestRecursive(t *testing.T) {
+	d, err := New(openTestdata(t, "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	starts, err := d.FuncStarts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStarts := []FuncStart{
+		{RelAddr: 0x1160, Source: StartEntryPoint},
+		{RelAddr: 0x1380, Source: StartTLSCallback},
+		{RelAddr: 0x13C0, Source: StartTLSCallback},
+	}
+	if len(starts) != len(wantStarts) {
+		t.Fatalf("expected %d function starts, got %d", len(wantStarts), len(starts))
+	}
+	for i, start := range starts {
+		if *start != wantStarts[i] {
+			t.Errorf("function start %d mismatch; expected %+v, got %+v", i, wantStarts[i], *start)
+		}
+	}
+	insts, err := d.Recursive(0x1160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Imported functions called through jump thunks and referenced through
+	// their IAT entry.
+	want := map[string]string{
+		"call 0x401c40 ; KERNEL32.dll!ExitProcess": "KERNEL32.dll!ExitProcess",
+		"mov eax, dword ptr [msvcrt.dll!atexit]":   "",
+		"call 0x401bd0 ; msvcrt.dll!__getmainargs": "msvcrt.dll!__getmainargs",
+	}
+	for _, inst := range insts {
+		s := inst.String()
+		if imp, ok := want[s]; ok {
+			if inst.Import != imp {
+				t.Errorf("%q: import mismatch; expected %q, got %q", s, imp, inst.Import)
+			}
+			delete(want, s)
+		}
+	}
+	for s := range want {
+		t.Errorf("instruction %q not found", s)
+	}
+}
+
+func TestLinear(t *testing.T) {
+	d, err := New(openTestdata(t, "gcc-amd64-mingw-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	starts, err := d.FuncStarts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pdata int
+	for _, start := range starts {
+		if start.Source == StartRuntimeFunc {
+			pdata++
+		}
+	}
+	if pdata == 0 {
+		t.Errorf("expected function starts from the exception table")
+	}
+	insts, err := d.Linear(0x1010, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ss []string
+	for _, inst := range insts {
+		ss = append(ss, inst.String())
+	}
+	if got, want := strings.Join(ss, "; "), "sub rsp, 0x38"; got != want {
+		t.Errorf("instruction mismatch; expected %q, got %q", want, got)
+	}
+	if _, err := d.Linear(0xFFFFFFF0, 0x20); err == nil {
+		t.Errorf("expected error for range outside of image")
+	}
+}
+
+func TestCallGraph(t *testing.T) {
+	d, err := New(openTestdata(t, "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := d.CallGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := g.Func(0x1160)
+	if entry == nil {
+		t.Fatal("entry point function not found")
+	}
+	// The entry point calls the C runtime startup function, which calls the
+	// imported ExitProcess through a jump thunk.
+	var startup *Func
+	for _, callee := range entry.Callees {
+		if callee.RelAddr == 0x1020 {
+			startup = callee
+		}
+	}
+	if startup == nil {
+		t.Fatalf("expected call of sub_00001020 by entry point; got callees %v", entry.Callees)
+	}
+	if startup.Source != StartCall {
+		t.Errorf("source mismatch; expected %v, got %v", StartCall, startup.Source)
+	}
+	var found bool
+	for _, caller := range startup.Callers {
+		found = found || caller == entry
+	}
+	if !found {
+		t.Errorf("expected entry point among callers of %v", startup)
+	}
+	found = false
+	for _, imp := range startup.Imports {
+		found = found || imp == "KERNEL32.dll!ExitProcess"
+	}
+	if !found {
+		t.Errorf("expected call of KERNEL32.dll!ExitProcess; got imports %v", startup.Imports)
+	}
+	// Jump thunks of imported functions are not reported as functions.
+	if fn := g.Func(0x1C40); fn != nil {
+		t.Errorf("unexpected function for jump thunk at 0x00001C40")
+	}
+	if fn := g.Func(0x1380); fn == nil || fn.Source != StartTLSCallback {
+		t.Errorf("expected TLS callback function at 0x00001380")
+	}
+}
+
+// TestFuncStartsMalformed checks that malformed optional directories are
+// skipped rather than failing the discovery of function starts.
+func TestFuncStartsMalformed(t *testing.T) {
+	data, err := os.ReadFile(testdataPath(t, "gcc-386-mingw-exec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Move the TLS directory outside of the image.
+	const coffHdrSize, optHdr32Size = 20, 96
+	peHdrOff := binary.LittleEndian.Uint32(data[0x3C:])
+	dataDirOff := peHdrOff + 4 + coffHdrSize + optHdr32Size + pe.DataDirTLSTable*8
+	binary.LittleEndian.PutUint32(data[dataDirOff:], 0x7FFF0000)
+	file, err := pe.New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.TLS(); err == nil {
+		t.Fatal("expected TLS directory error")
+	}
+	d, err := New(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	starts, err := d.FuncStarts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := FuncStart{RelAddr: 0x1160, Source: StartEntryPoint}
+	if len(starts) != 1 || *starts[0] != want {
+		t.Errorf("function starts mismatch; expected [%+v], got %v", want, starts)
+	}
+	if _, err := d.CallGraph(); err != nil {
+		t.Errorf("unexpected call graph error; %v", err)
+	}
+}
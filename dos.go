@@ -1,15 +1,17 @@
 package pe
 
 import (
+	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
-	"log"
 )
 
 // DOS header size, including signature.
 const dosHdrSize = 64
 
+// DOS signature; "MZ" (Mark Zbikowski).
+const dosMagic = 0x5A4D
+
 // A DOSHeader contains information about the executable environment of 16-bit
 // DOS binaries. It is prepended by the DOS signature: "MZ" (Mark Zbikowski).
 type DOSHeader struct {
@@ -56,7 +58,7 @@ type DOSHeader struct {
 // DOSHeader returns the DOS header of file.
 func (file *File) DOSHeader() (doshdr *DOSHeader, err error) {
 	if file.doshdr == nil {
-		err = file.parseDOSHeader()
+		err = file.parseStruct(file.parseDOSHeader)
 		if err != nil {
 			return nil, err
 		}
@@ -67,61 +69,117 @@ func (file *File) DOSHeader() (doshdr *DOSHeader, err error) {
 
 // parseDOSHeader parses the DOS header of file.
 func (file *File) parseDOSHeader() error {
-	sr := io.NewSectionReader(file.r, 0, dosHdrSize)
+	buf, err := file.readHeader("DOS header", 0, dosHdrSize)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(buf)
 
 	// Verify the DOS signature; "MZ" (Mark Zbikowski).
 	var magic uint16
-	err := binary.Read(sr, binary.LittleEndian, &magic)
-	if err != nil {
-		return fmt.Errorf("pe.File.parseDOSHeader: unable to read signature; %v", err)
-	}
-	const mz = 0x5A4D
-	if magic != mz {
-		return fmt.Errorf("pe.File.parseDOSHeader: invalid signature; expected 0x%04X, got 0x%04X", mz, magic)
+	binary.Read(r, binary.LittleEndian, &magic)
+	if magic != dosMagic {
+		return formatError("DOS header", 0, "invalid signature; expected 0x%04X, got 0x%04X", dosMagic, magic)
 	}
 
 	// Parse DOS header.
-	file.doshdr = new(DOSHeader)
-	err = binary.Read(sr, binary.LittleEndian, file.doshdr)
-	if err != nil {
-		return fmt.Errorf("pe.File.parseDOSHeader: unable to read DOS header; %v", err)
-	}
+	doshdr := new(DOSHeader)
+	binary.Read(r, binary.LittleEndian, doshdr)
 
 	// Verify that the reserved fields are all zero.
-	for i, v := range file.doshdr.Res {
+	const resOff, res2Off = 0x1C, 0x28
+	for i, v := range doshdr.Res {
 		if v != 0 {
-			log.Printf("pe.File.parseDOSHeader: invalid reserved field %d; expected 0, got %v.\n", i, v)
+			file.addAnomaly(AnomalyDOSReserved, SeverityInfo, resOff+int64(i)*2, "reserved field Res[%d]; expected 0, got 0x%04X", i, v)
 		}
 	}
-	for i, v := range file.doshdr.Res2 {
+	for i, v := range doshdr.Res2 {
 		if v != 0 {
-			log.Printf("pe.File.parseDOSHeader: invalid reserved field %d; expected 0, got %v.\n", i, v)
+			file.addAnomaly(AnomalyDOSReserved, SeverityInfo, res2Off+int64(i)*2, "reserved field Res2[%d]; expected 0, got 0x%04X", i, v)
 		}
 	}
 
+	file.doshdr = doshdr
 	return nil
 }
 
 // DOSStub returns the DOS stub of file as a byte slice.
 func (file *File) DOSStub() ([]byte, error) {
-	doshdr, err := file.DOSHeader()
+	sr, err := file.DOSStubReader()
 	if err != nil {
 		return nil, err
 	}
-
-	// Read DOS stub.
-	peoff := doshdr.PEHdrOffset
-	stubSize := int64(peoff - dosHdrSize)
-	if stubSize <= 0 {
+	if sr.Size() == 0 {
 		return nil, nil
 	}
-	stubOff := int64(dosHdrSize)
-	sr := io.NewSectionReader(file.r, stubOff, stubSize)
-	dosStub := make([]byte, stubSize)
-	_, err = io.ReadFull(sr, dosStub)
+	if err := file.checkAlloc("DOS stub", sr.Size()); err != nil {
+		return nil, err
+	}
+	dosStub := make([]byte, sr.Size())
+	if _, err := io.ReadFull(sr, dosStub); err != nil {
+		return nil, readError("DOS stub", dosHdrSize, err)
+	}
+	return dosStub, nil
+}
+
+// DOSStubReader returns a reader of the DOS stub of file; which is empty if
+// the file has no DOS stub.
+func (file *File) DOSStubReader() (*io.SectionReader, error) {
+	doshdr, err := file.DOSHeader()
 	if err != nil {
-		return nil, fmt.Errorf("pe.File.DOSStub: error reading DOS stub; %v", err)
+		return nil, err
+	}
+	// The DOS stub spans from the end of the DOS header to the PE header.
+	stubSize := int64(doshdr.PEHdrOffset) - dosHdrSize
+	if stubSize < 0 {
+		stubSize = 0
 	}
+	return io.NewSectionReader(file.r, dosHdrSize, stubSize), nil
+}
 
-	return dosStub, nil
+// File offset of the checksum field of the DOS header.
+const dosChecksumOffset = 18
+
+// DOSChecksum returns the DOS checksum of the contents of r; i.e. the one's
+// complement of the 16-bit sum of all little-endian words in the file,
+// excluding the checksum field of the DOS header itself. A trailing odd byte is
+// zero-padded to a full word.
+func DOSChecksum(r io.Reader) (uint16, error) {
+	var (
+		sum uint16
+		off int64
+		buf = make([]byte, 32*1024)
+	)
+	for {
+		n, err := io.ReadFull(r, buf)
+		// Pad trailing odd byte.
+		if n%2 != 0 {
+			buf[n] = 0
+			n++
+		}
+		for i := 0; i < n; i += 2 {
+			if off+int64(i) == dosChecksumOffset {
+				continue
+			}
+			sum += binary.LittleEndian.Uint16(buf[i:])
+		}
+		off += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return ^sum, nil
+}
+
+// VerifyChecksum reports whether the checksum of the DOS header matches the DOS
+// checksum of the contents of r, which should cover the entire file.
+func (doshdr *DOSHeader) VerifyChecksum(r io.Reader) (bool, error) {
+	sum, err := DOSChecksum(r)
+	if err != nil {
+		return false, err
+	}
+	return doshdr.Checksum == sum, nil
 }
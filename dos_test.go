@@ -0,0 +1,72 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDOSChecksum(t *testing.T) {
+	// mzHeader returns a DOS header with the given checksum, followed by extra.
+	mzHeader := func(checksum uint16, extra ...byte) []byte {
+		b := make([]byte, dosHdrSize)
+		copy(b, "MZ")
+		binary.LittleEndian.PutUint16(b[dosChecksumOffset:], checksum)
+		return append(b, extra...)
+	}
+	// Large file spanning several read chunks, with an odd length.
+	large := mzHeader(0, bytes.Repeat([]byte{0xFF, 0x01, 0x02}, 0x8001)...)
+	var largeSum uint16 = 0x5A4D
+	for i := dosHdrSize; i < len(large); i += 2 {
+		w := uint16(large[i])
+		if i+1 < len(large) {
+			w |= uint16(large[i+1]) << 8
+		}
+		largeSum += w
+	}
+	golden := []struct {
+		name string
+		data []byte
+		// Expected DOS checksum.
+		want uint16
+		// Specifies whether the checksum of the DOS header is valid.
+		valid bool
+	}{
+		// ^0x5A4D ("MZ")
+		{name: "valid", data: mzHeader(0xA5B2), want: 0xA5B2, valid: true},
+		// ^(0x5A4D + 0x0001); the trailing odd byte is zero-padded.
+		{name: "odd length", data: mzHeader(0xA5B1, 0x01), want: 0xA5B1, valid: true},
+		// ^(0x5A4D + 0xFFFF + 0x0002); the carry out of the sum is discarded.
+		{name: "overflow", data: mzHeader(0xA5B1, 0xFF, 0xFF, 0x02, 0x00), want: 0xA5B1, valid: true},
+		{name: "corrupted", data: mzHeader(0x1234), want: 0xA5B2, valid: false},
+		{name: "large", data: mzHeader(^largeSum, large[dosHdrSize:]...), want: ^largeSum, valid: true},
+	}
+	for _, g := range golden {
+		got, err := DOSChecksum(bytes.NewReader(g.data))
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("%s: checksum mismatch; expected 0x%04X, got 0x%04X", g.name, g.want, got)
+		}
+		file, err := New(bytes.NewReader(g.data))
+		if err != nil {
+			t.Errorf("%s: unable to create file; %v", g.name, err)
+			continue
+		}
+		doshdr, err := file.DOSHeader()
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		valid, err := doshdr.VerifyChecksum(bytes.NewReader(g.data))
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		if valid != g.valid {
+			t.Errorf("%s: validity mismatch; expected %v, got %v", g.name, g.valid, valid)
+		}
+	}
+}
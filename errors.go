@@ -0,0 +1,85 @@
+package pe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrTruncated is wrapped by errors of structures which extend past the end of
+// the file.
+var ErrTruncated = errors.New("truncated file")
+
+// A FormatError reports a malformed or truncated structure of a PE file.
+type FormatError struct {
+	// Name of the structure (e.g. "DOS header").
+	Struct string
+	// File offset of the structure; or -1 if unknown.
+	Offset int64
+	// Description of the error; or empty.
+	Msg string
+	// Underlying error; or nil.
+	Err error
+}
+
+func (e *FormatError) Error() string {
+	var b strings.Builder
+	b.WriteString("pe: invalid ")
+	b.WriteString(e.Struct)
+	if e.Offset >= 0 {
+		fmt.Fprintf(&b, " at offset 0x%X", e.Offset)
+	}
+	if len(e.Msg) > 0 {
+		b.WriteString(": ")
+		b.WriteString(e.Msg)
+	}
+	if e.Err != nil {
+		b.WriteString("; ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error of e.
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// An AddrError reports an address, relative to the image base, which cannot be
+// translated to a file offset.
+type AddrError struct {
+	// Address relative to the image base.
+	RelAddr uint32
+	// Description of the error.
+	Msg string
+}
+
+func (e *AddrError) Error() string {
+	return fmt.Sprintf("pe: address 0x%08X %s", e.RelAddr, e.Msg)
+}
+
+// formatError returns a FormatError of the given structure at the given file
+// offset, with a formatted description of the error.
+func formatError(structName string, off int64, format string, args ...interface{}) *FormatError {
+	return &FormatError{
+		Struct: structName,
+		Offset: off,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// readError returns a FormatError of the given structure at the given file
+// offset, caused by a failed read. End of file errors are reported as
+// ErrTruncated.
+func readError(structName string, off int64, err error) *FormatError {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = ErrTruncated
+	}
+	return &FormatError{
+		Struct: structName,
+		Offset: off,
+		Msg:    "unable to read",
+		Err:    err,
+	}
+}
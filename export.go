@@ -0,0 +1,178 @@
+package pe
+
+import (
+	"fmt"
+)
+
+// ExportDirectory represents the export directory table, which describes the
+// functions exported by an image.
+type ExportDirectory struct {
+	// Reserved.
+	Flags uint32
+	// The time and date that the export data was created.
+	Created Time
+	// Major version number.
+	MajorVer uint16
+	// Minor version number.
+	MinorVer uint16
+	// Address of the NULL-terminated DLL name, relative to the image base.
+	NameRelAddr uint32
+	// The starting ordinal number for exports.
+	OrdinalBase uint32
+	// Number of entries in the export address table.
+	NAddr uint32
+	// Number of entries in the name pointer table and ordinal table.
+	NName uint32
+	// Address of the export address table, relative to the image base.
+	AddrTblRelAddr uint32
+	// Address of the export name pointer table, relative to the image base.
+	NameTblRelAddr uint32
+	// Address of the ordinal table, relative to the image base.
+	OrdinalTblRelAddr uint32
+}
+
+// Exports represents the functions exported by an image.
+type Exports struct {
+	// Export directory table.
+	ExportDirectory
+	// Name of the DLL.
+	DLL string
+	// Exported functions, ordered by ordinal.
+	Funcs []*ExportFunc
+}
+
+// ExportFunc represents an exported function.
+type ExportFunc struct {
+	// Function name; or empty if only exported by ordinal.
+	Name string
+	// Function ordinal.
+	Ordinal uint32
+	// Address of the function, relative to the image base; or zero if
+	// forwarded.
+	RelAddr uint32
+	// Forwarder name (e.g. "NTDLL.RtlAllocateHeap"); or empty if not forwarded.
+	Forwarder string
+}
+
+func (fn *ExportFunc) String() string {
+	if len(fn.Name) > 0 {
+		return fn.Name
+	}
+	return fmt.Sprintf("#%d", fn.Ordinal)
+}
+
+// Exports returns the exports of file, or nil if file has no exports.
+func (file *File) Exports() (exps *Exports, err error) {
+	if !file.expsParsed {
+		err = file.parseStruct(file.parseExports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.exps, nil
+}
+
+// parseExports parses the export table of file.
+func (file *File) parseExports() error {
+	dataDir, err := file.dataDir(DataDirExportTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 {
+		file.expsParsed = true
+		return nil
+	}
+
+	// Parse export directory table.
+	exps := new(Exports)
+	if err := file.readRelAddr(dataDir.RelAddr, &exps.ExportDirectory, "export directory table"); err != nil {
+		return err
+	}
+
+	// In tolerant mode, parsing stops at the first malformed table, keeping the
+	// exports parsed so far.
+	var funcs []*ExportFunc
+	fail := func(err error) error {
+		if !file.tolerate(err) {
+			return err
+		}
+		file.setExports(exps, funcs)
+		return nil
+	}
+
+	if exps.NameRelAddr != 0 {
+		exps.DLL, err = file.readStringRelAddr(exps.NameRelAddr, "export DLL name")
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	// Parse export address table.
+	limits := file.conf.limits
+	if err := checkLimit(limitExports, "export address table", int64(exps.NAddr), int64(limits.MaxExports)); err != nil {
+		return fail(err)
+	}
+	addrs := make([]uint32, exps.NAddr)
+	if err := file.readRelAddr(exps.AddrTblRelAddr, addrs, "export address table"); err != nil {
+		return fail(err)
+	}
+	funcs = make([]*ExportFunc, len(addrs))
+	for i, addr := range addrs {
+		fn := &ExportFunc{
+			Ordinal: exps.OrdinalBase + uint32(i),
+			RelAddr: addr,
+		}
+		funcs[i] = fn
+		// Addresses within the export data directory refer to forwarder names.
+		if addr >= dataDir.RelAddr && addr-dataDir.RelAddr < dataDir.Size {
+			fn.RelAddr = 0
+			fn.Forwarder, err = file.readStringRelAddr(addr, "export forwarder name")
+			if err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	// Parse export name pointer table and ordinal table.
+	if exps.NName > 0 {
+		if err := checkLimit(limitExports, "export name pointer table", int64(exps.NName), int64(limits.MaxExports)); err != nil {
+			return fail(err)
+		}
+		namePtrs := make([]uint32, exps.NName)
+		if err := file.readRelAddr(exps.NameTblRelAddr, namePtrs, "export name pointer table"); err != nil {
+			return fail(err)
+		}
+		ordinals := make([]uint16, exps.NName)
+		if err := file.readRelAddr(exps.OrdinalTblRelAddr, ordinals, "export ordinal table"); err != nil {
+			return fail(err)
+		}
+		for i, namePtr := range namePtrs {
+			index := int(ordinals[i])
+			if index >= len(funcs) {
+				return fail(formatError("export ordinal table", -1, "ordinal index %d exceeds number of exports %d", index, len(funcs)))
+			}
+			name, err := file.readStringRelAddr(namePtr, "export name")
+			if err != nil {
+				return fail(err)
+			}
+			funcs[index].Name = name
+		}
+	}
+
+	file.setExports(exps, funcs)
+	return nil
+}
+
+// setExports caches the given exports, skipping unused entries of the export
+// address table.
+func (file *File) setExports(exps *Exports, funcs []*ExportFunc) {
+	for _, fn := range funcs {
+		if fn == nil || fn.RelAddr == 0 && len(fn.Forwarder) == 0 && len(fn.Name) == 0 {
+			continue
+		}
+		exps.Funcs = append(exps.Funcs, fn)
+	}
+	file.exps = exps
+	file.expsParsed = true
+}
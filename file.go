@@ -1,6 +1,7 @@
 package pe
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -11,6 +12,9 @@ import (
 // File header size, including signature.
 const fileHdrSize = 24
 
+// COFF file header size, excluding signature.
+const coffHdrSize = 20
+
 // FileHeader represents a COFF file header. It is prepended by the PE
 // signature: "PE" (Portable Executable).
 type FileHeader struct {
@@ -37,17 +41,23 @@ type Arch uint16
 const (
 	// ArchI386 represents the Intel 386 and later processors.
 	ArchI386 Arch = 0x014C
+	// ArchARMNT represents the ARM Thumb-2 little endian processor.
+	ArchARMNT Arch = 0x01C4
 	// ArchIA64 represents the Intel Itanium processor.
 	ArchIA64 Arch = 0x0200
 	// ArchAMD64 represents the x64 processor.
 	ArchAMD64 Arch = 0x8664
+	// ArchARM64 represents the ARM64 little endian processor.
+	ArchARM64 Arch = 0xAA64
 )
 
 // archName is a map from Arch to string description.
 var archName = map[Arch]string{
-	ArchI386:  "i368",
+	ArchI386:  "i386",
+	ArchARMNT: "ARMNT",
 	ArchIA64:  "IA64",
 	ArchAMD64: "AMD64",
+	ArchARM64: "ARM64",
 }
 
 func (arch Arch) String() string {
@@ -145,7 +155,7 @@ func (flags Flag) String() string {
 // FileHeader returns the file header of file.
 func (file *File) FileHeader() (fileHdr *FileHeader, err error) {
 	if file.fileHdr == nil {
-		err = file.parseFileHeader()
+		err = file.parseStruct(file.parseFileHeader)
 		if err != nil {
 			return nil, err
 		}
@@ -154,32 +164,79 @@ func (file *File) FileHeader() (fileHdr *FileHeader, err error) {
 	return file.fileHdr, nil
 }
 
-// parseFileHeader parses the COFF file header of file.
+// parseFileHeader parses the COFF file header of file. Files without a DOS
+// header are parsed as COFF object files, in which the file header is located
+// at offset zero and lacks the PE signature.
 func (file *File) parseFileHeader() error {
+	// Identify COFF object files.
+	var dosSig uint16
+	sr := io.NewSectionReader(file.r, 0, 2)
+	if err := binary.Read(sr, binary.LittleEndian, &dosSig); err != nil {
+		return readError("file header", 0, err)
+	}
+	if dosSig != dosMagic {
+		return file.parseObjFileHeader()
+	}
+
 	doshdr, err := file.DOSHeader()
 	if err != nil {
 		return err
 	}
 	peoff := int64(doshdr.PEHdrOffset)
-	sr := io.NewSectionReader(file.r, peoff, fileHdrSize)
+	buf, err := file.readHeader("file header", peoff, fileHdrSize)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(buf)
 
 	// Verify the PE signature; "PE" (Portable Executable).
 	var magic uint32
-	err = binary.Read(sr, binary.LittleEndian, &magic)
-	if err != nil {
-		return fmt.Errorf("pe.File.parseFileHeader: unable to read signature; %v", err)
-	}
+	binary.Read(r, binary.LittleEndian, &magic)
 	const pe = 0x00004550
 	if magic != pe {
-		return fmt.Errorf("pe.File.parseFileHeader: invalid signature; expected 0x%08X, got 0x%08X", pe, magic)
+		return formatError("PE signature", peoff, "expected 0x%08X, got 0x%08X", pe, magic)
 	}
 
 	// Parse COFF file header.
-	file.fileHdr = new(FileHeader)
-	err = binary.Read(sr, binary.LittleEndian, file.fileHdr)
+	fileHdr := new(FileHeader)
+	binary.Read(r, binary.LittleEndian, fileHdr)
+
+	file.fileHdr = fileHdr
+	file.coffHdrOff = peoff + 4
+	return nil
+}
+
+// parseObjFileHeader parses the COFF file header of a COFF object file.
+func (file *File) parseObjFileHeader() error {
+	buf, err := file.readHeader("file header", 0, coffHdrSize)
 	if err != nil {
-		return fmt.Errorf("pe.File.parseFileHeader: unable to read file header; %v", err)
+		return err
 	}
-
+	fileHdr := new(FileHeader)
+	binary.Read(bytes.NewReader(buf), binary.LittleEndian, fileHdr)
+	// Only accept known architectures, as object files lack a signature.
+	if _, ok := archName[fileHdr.Arch]; !ok {
+		return formatError("file header", 0, "neither a PE image (missing DOS signature) nor a COFF object file (unknown architecture 0x%04X)", uint16(fileHdr.Arch))
+	}
+	file.fileHdr = fileHdr
+	file.coffHdrOff = 0
+	file.obj = true
 	return nil
 }
+
+// IsObject reports whether file is a COFF object file, rather than an image.
+func (file *File) IsObject() (bool, error) {
+	if _, err := file.FileHeader(); err != nil {
+		return false, err
+	}
+	return file.obj, nil
+}
+
+// optHdrOffset returns the file offset of the optional header, which directly
+// follows the COFF file header.
+func (file *File) optHdrOffset() (int64, error) {
+	if _, err := file.FileHeader(); err != nil {
+		return 0, err
+	}
+	return file.coffHdrOff + coffHdrSize, nil
+}
@@ -0,0 +1,291 @@
+package pe
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// update specifies whether to regenerate the fuzz corpus in testdata.
+var update = flag.Bool("update", false, "regenerate fuzz corpus in testdata/fuzz")
+
+// fuzzTargets lists the fuzz targets which share the synthetic corpus.
+var fuzzTargets = []string{
+	"FuzzParse",
+	"FuzzRichHeader",
+	"FuzzImports",
+	"FuzzExports",
+	"FuzzResources",
+}
+
+// fuzzLimits specifies the resource limits used while fuzzing, which are lower
+// than the default limits to keep iterations fast.
+var fuzzLimits = Limits{
+	MaxAlloc:         1 << 20,
+	MaxDirEntries:    256,
+	MaxResourceDepth: 8,
+	MaxImports:       1024,
+	MaxExports:       1024,
+}
+
+// addSeeds adds the synthetic corpus to f.
+func addSeeds(f *testing.F) {
+	for _, seed := range synthSeeds() {
+		f.Add(seed.data)
+	}
+}
+
+// fuzzFiles returns a strict and a tolerant File for accessing data.
+func fuzzFiles(data []byte) []*File {
+	var files []*File
+	for _, tolerant := range []bool{false, true} {
+		file, err := New(bytes.NewReader(data), WithTolerant(tolerant), WithLimits(fuzzLimits))
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func FuzzParse(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, file := range fuzzFiles(data) {
+			file.Parse()
+			file.Anomalies()
+			file.Overlay()
+			file.OverlayItems()
+			file.Carve()
+			file.VersionInfo()
+			file.Normalize(io.Discard)
+			file.Hashes()
+			file.DOSStub()
+			if opthdr, err := file.OptHeader(); err == nil {
+				file.Load(opthdr.ImageBase64 + 0x10000)
+			}
+			if sectHdrs, err := file.SectHeaders(); err == nil {
+				for _, sectHdr := range sectHdrs {
+					file.Section(sectHdr)
+				}
+			}
+		}
+		if mapped, err := New(bytes.NewReader(data), WithLayout(LayoutMapped), WithLimits(fuzzLimits)); err == nil {
+			mapped.Parse()
+			mapped.Unmap()
+		}
+	})
+}
+
+func FuzzRichHeader(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		rich, err := ParseRichHeader(data)
+		if err != nil || rich == nil {
+			return
+		}
+		rich.ValidChecksum()
+		for _, entry := range rich.Entries {
+			_ = entry.String()
+			entry.VSVersion()
+		}
+	})
+}
+
+func FuzzImports(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, file := range fuzzFiles(data) {
+			file.Imports()
+		}
+	})
+}
+
+func FuzzExports(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, file := range fuzzFiles(data) {
+			file.Exports()
+		}
+	})
+}
+
+func FuzzResources(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, file := range fuzzFiles(data) {
+			root, err := file.Resources()
+			if err != nil || root == nil {
+				continue
+			}
+			root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+				if node.Data != nil {
+					file.ResourceData(node.Data)
+				}
+			})
+		}
+	})
+}
+
+// TestCorpus checks that the checked-in fuzz corpus is up to date with the
+// synthetic seeds, and that the seeds parse as expected. Run with -update to
+// regenerate the corpus.
+func TestCorpus(t *testing.T) {
+	for _, target := range fuzzTargets {
+		dir := filepath.Join("testdata", "fuzz", target)
+		if *update {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, seed := range synthSeeds() {
+			path := filepath.Join(dir, seed.name)
+			want := []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", seed.data))
+			if *update {
+				if err := os.WriteFile(path, want, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Errorf("%v; run go test -run TestCorpus -update", err)
+				continue
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("corpus file %q out of date; run go test -run TestCorpus -update", path)
+			}
+		}
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	for _, seed := range synthSeeds() {
+		file, err := New(bytes.NewReader(seed.data))
+		if err != nil {
+			t.Errorf("%s: unable to create file; %v", seed.name, err)
+			continue
+		}
+		err = file.Parse()
+		switch {
+		case seed.malformed && err == nil:
+			t.Errorf("%s: expected parse error, got nil", seed.name)
+		case !seed.malformed && err != nil:
+			t.Errorf("%s: unexpected parse error; %v", seed.name, err)
+		}
+	}
+}
+
+func TestSynthImage(t *testing.T) {
+	for _, img := range []synthImage{{}, {is64: true}, {rich: true}, {is64: true, rich: true}} {
+		name := fmt.Sprintf("is64=%v,rich=%v", img.is64, img.rich)
+		file, err := New(bytes.NewReader(img.bytes()), WithParseMode(ParseEager))
+		if err != nil {
+			t.Errorf("%s: unable to parse; %v", name, err)
+			continue
+		}
+
+		// Optional header.
+		opthdr, err := file.OptHeader()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := opthdr.Is64(); got != img.is64 {
+			t.Errorf("%s: Is64 mismatch; expected %v, got %v", name, img.is64, got)
+		}
+
+		// Rich header.
+		rich, err := file.RichHeader()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		switch {
+		case img.rich && rich == nil:
+			t.Errorf("%s: missing Rich header", name)
+		case img.rich && !rich.ValidChecksum():
+			t.Errorf("%s: invalid Rich header checksum", name)
+		case !img.rich && rich != nil:
+			t.Errorf("%s: unexpected Rich header", name)
+		}
+
+		// Imports.
+		imps, err := file.Imports()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(imps) != 1 || imps[0].DLL != "kernel32.dll" || len(imps[0].Funcs) != 2 {
+			t.Fatalf("%s: imports mismatch; got %d imports", name, len(imps))
+		}
+		if fn := imps[0].Funcs[0]; fn.Name != "ExitProcess" || fn.Hint != 1 || fn.ByOrdinal {
+			t.Errorf("%s: import by name mismatch; got %+v", name, fn)
+		}
+		if fn := imps[0].Funcs[1]; fn.Ordinal != 7 || !fn.ByOrdinal {
+			t.Errorf("%s: import by ordinal mismatch; got %+v", name, fn)
+		}
+
+		// Exports.
+		exps, err := file.Exports()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if exps == nil || exps.DLL != "synth.dll" || len(exps.Funcs) != 2 {
+			t.Fatalf("%s: exports mismatch", name)
+		}
+		if fn := exps.Funcs[0]; fn.Name != "Foo" || fn.Ordinal != 1 || fn.RelAddr != synthSectRelAddr {
+			t.Errorf("%s: named export mismatch; got %+v", name, fn)
+		}
+		if fn := exps.Funcs[1]; fn.Forwarder != "kernel32.ExitProcess" || fn.Ordinal != 2 {
+			t.Errorf("%s: forwarder export mismatch; got %+v", name, fn)
+		}
+
+		// Resources.
+		root, err := file.Resources()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var leaves []*ResourceNode
+		root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+			if node.IsLeaf() {
+				if len(path) != 3 || path[0].ID != ResourceTypeIcon || path[1].Name != "ABC" || path[2].ID != 0x409 {
+					t.Errorf("%s: resource path mismatch", name)
+				}
+				leaves = append(leaves, node)
+			}
+		})
+		if len(leaves) != 1 {
+			t.Fatalf("%s: expected 1 resource, got %d", name, len(leaves))
+		}
+		data, err := file.ResourceData(leaves[0].Data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if string(data) != "DATA" {
+			t.Errorf("%s: resource data mismatch; expected %q, got %q", name, "DATA", data)
+		}
+	}
+}
+
+func TestSynthObject(t *testing.T) {
+	file, err := New(bytes.NewReader(synthObject()), WithParseMode(ParseEager))
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj, err := file.IsObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !obj {
+		t.Errorf("expected object file")
+	}
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sectHdrs) != 1 || sectHdrs[0].Name != ".text$long" || sectHdrs[0].RawName != "/4" {
+		t.Errorf("section name mismatch; got %+v", sectHdrs)
+	}
+}
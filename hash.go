@@ -0,0 +1,277 @@
+package pe
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"io"
+	"sort"
+)
+
+// HashSet holds the MD5, SHA-1 and SHA-256 hashes of data, as lowercase hex
+// strings.
+type HashSet struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+}
+
+// SectionHash holds the hashes of the contents of a section.
+type SectionHash struct {
+	// Section name.
+	Name string
+	// Hashes of the section contents, as stored in the file.
+	HashSet
+}
+
+// Hashes holds the hashes of a file, as computed by File.Hashes.
+type Hashes struct {
+	// Hashes of the file contents.
+	File HashSet
+	// Hashes of the section contents, in section table order.
+	Sections []*SectionHash
+	// Hashes of the overlay; or nil if not present.
+	Overlay *HashSet
+	// Authenticode hashes of the image (Authentihash); or nil for COFF object
+	// files.
+	Authentihash *HashSet
+	// MD5 hash of the decoded Rich header; or an empty string if not present.
+	RichHash string
+	// Locality-sensitive hash of the file contents (see LSHDistance); or an
+	// empty string if the file is too small or too uniform.
+	LSH string
+}
+
+// hashChunkSize specifies the size of the chunks read while hashing.
+const hashChunkSize = 64 * 1024
+
+// Hashes returns the hashes of file; of the whole file, each section, the
+// overlay, the Authenticode hash, the Rich header hash and a locality-sensitive
+// hash. The contents of file are read once, sequentially.
+//
+// The Authenticode hash covers the contents of the file, excluding the checksum
+// of the optional header, the certificate table entry of the data directories
+// and the certificate table.
+//
+// The Rich header hash is the MD5 hash of the decoded Rich header, from the
+// "DanS" signature up to the "Rich" signature.
+func (file *File) Hashes() (*Hashes, error) {
+	size, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	hashes := new(Hashes)
+	var ranges []*hashRange
+	add := func(start, end int64, skip [][2]int64) *multiHash {
+		h := newMultiHash()
+		ranges = append(ranges, &hashRange{start: start, end: end, skip: skip, w: h})
+		return h
+	}
+
+	// Whole file.
+	fileHash := add(0, size, nil)
+	lsh := new(lshWriter)
+	ranges = append(ranges, &hashRange{start: 0, end: size, w: lsh})
+
+	// Sections.
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	sectHashes := make([]*multiHash, len(sectHdrs))
+	for i, sectHdr := range sectHdrs {
+		off, n := file.sectExtent(sectHdr)
+		sectHashes[i] = add(off, off+n, nil)
+	}
+
+	// Overlay.
+	var overlayHash *multiHash
+	overlayOff, overlaySize, err := file.overlayExtent()
+	if err != nil {
+		return nil, err
+	}
+	if overlaySize > 0 {
+		overlayHash = add(overlayOff, overlayOff+overlaySize, nil)
+	}
+
+	// Authenticode hash.
+	var authHash *multiHash
+	obj, err := file.IsObject()
+	if err != nil {
+		return nil, err
+	}
+	if !obj {
+		skip, err := file.authenticodeSkip()
+		if err != nil {
+			return nil, err
+		}
+		authHash = add(0, size, skip)
+	}
+
+	// Hash the contents of the file in a single pass.
+	sr := io.NewSectionReader(file.r, 0, size)
+	buf := make([]byte, hashChunkSize)
+	for off := int64(0); off < size; {
+		n, err := io.ReadFull(sr, buf)
+		if n == 0 {
+			return nil, readError("file contents", off, err)
+		}
+		for _, rng := range ranges {
+			rng.write(off, buf[:n])
+		}
+		off += int64(n)
+	}
+
+	hashes.File = fileHash.sum()
+	for i, sectHdr := range sectHdrs {
+		hashes.Sections = append(hashes.Sections, &SectionHash{Name: sectHdr.Name, HashSet: sectHashes[i].sum()})
+	}
+	if overlayHash != nil {
+		sum := overlayHash.sum()
+		hashes.Overlay = &sum
+	}
+	if authHash != nil {
+		sum := authHash.sum()
+		hashes.Authentihash = &sum
+	}
+	if hashes.RichHash, err = file.RichHash(); err != nil {
+		return nil, err
+	}
+	hashes.LSH = lsh.digest()
+	return hashes, nil
+}
+
+// authenticodeSkip returns the file ranges excluded from the Authenticode hash
+// of file, ordered by offset.
+func (file *File) authenticodeSkip() ([][2]int64, error) {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return nil, err
+	}
+	optoff, err := file.optHdrOffset()
+	if err != nil {
+		return nil, err
+	}
+	var skip [][2]int64
+	// The checksum is located at the same offset of the 32-bit and 64-bit
+	// optional headers.
+	const checksumOff = 64
+	if fileHdr.OptHdrSize >= checksumOff+4 {
+		skip = append(skip, [2]int64{optoff + checksumOff, optoff + checksumOff + 4})
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return nil, err
+	}
+	if opthdr != nil && DataDirCertificateTable < len(opthdr.DataDirs) {
+		off := optoff + optHdr32Size + DataDirCertificateTable*8
+		if opthdr.Is64() {
+			off = optoff + optHdr64Size + DataDirCertificateTable*8
+		}
+		skip = append(skip, [2]int64{off, off + 8})
+	}
+	start, end, err := file.tailExtent()
+	if err != nil {
+		return nil, err
+	}
+	if certOff, certSize := file.certExtent(start, end); certSize > 0 {
+		skip = append(skip, [2]int64{certOff, certOff + certSize})
+	}
+	sort.Slice(skip, func(i, j int) bool {
+		return skip[i][0] < skip[j][0]
+	})
+	return skip, nil
+}
+
+// RichHash returns the MD5 hash of the decoded Rich header of file, from the
+// "DanS" signature up to the "Rich" signature, as a lowercase hex string; or
+// an empty string if file has no Rich header.
+func (file *File) RichHash() (string, error) {
+	rich, err := file.RichHeader()
+	if err != nil {
+		return "", err
+	}
+	if rich == nil {
+		return "", nil
+	}
+	// The "DanS" signature is followed by three zero padding dwords, and then
+	// by pairs of comp.id and count dwords.
+	buf := make([]byte, 16+8*len(rich.Entries))
+	binary.LittleEndian.PutUint32(buf, dansMagic)
+	for i, entry := range rich.Entries {
+		binary.LittleEndian.PutUint32(buf[16+8*i:], entry.CompID())
+		binary.LittleEndian.PutUint32(buf[20+8*i:], entry.Count)
+	}
+	sum := md5.Sum(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// hashRange feeds the contents of a file range to a writer, excluding skipped
+// ranges.
+type hashRange struct {
+	// Start and end file offsets of the range.
+	start, end int64
+	// Start and end file offsets of the skipped ranges, ordered by offset.
+	skip [][2]int64
+	// Writer fed with the contents of the range.
+	w io.Writer
+}
+
+// write feeds the part of buf within the range to the writer of rng, where buf
+// holds the contents of the file at the given offset.
+func (rng *hashRange) write(off int64, buf []byte) {
+	start, end := rng.start, rng.end
+	if start < off {
+		start = off
+	}
+	if bufEnd := off + int64(len(buf)); end > bufEnd {
+		end = bufEnd
+	}
+	pos := start
+	for _, skip := range rng.skip {
+		if skip[1] <= pos {
+			continue
+		}
+		if skip[0] >= end {
+			break
+		}
+		if skip[0] > pos {
+			rng.w.Write(buf[pos-off : skip[0]-off])
+		}
+		pos = skip[1]
+	}
+	if pos < end {
+		rng.w.Write(buf[pos-off : end-off])
+	}
+}
+
+// multiHash computes the MD5, SHA-1 and SHA-256 hashes of the data written to
+// it.
+type multiHash struct {
+	md5, sha1, sha256 hash.Hash
+}
+
+// newMultiHash returns a new multiHash.
+func newMultiHash() *multiHash {
+	return &multiHash{md5: md5.New(), sha1: sha1.New(), sha256: sha256.New()}
+}
+
+// Write adds the contents of p to the hashes.
+func (h *multiHash) Write(p []byte) (int, error) {
+	h.md5.Write(p)
+	h.sha1.Write(p)
+	h.sha256.Write(p)
+	return len(p), nil
+}
+
+// sum returns the hashes of the data written so far.
+func (h *multiHash) sum() HashSet {
+	return HashSet{
+		MD5:    hex.EncodeToString(h.md5.Sum(nil)),
+		SHA1:   hex.EncodeToString(h.sha1.Sum(nil)),
+		SHA256: hex.EncodeToString(h.sha256.Sum(nil)),
+	}
+}
@@ -0,0 +1,120 @@
+package pe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashes(t *testing.T) {
+	const checksumOff = synthPEHdrOffset + 4 + coffHdrSize + 64
+	// The overlay spans several chunks, so that the certificate table crosses
+	// a chunk boundary.
+	overlay := make([]byte, hashChunkSize+0x1000)
+	for i := range overlay {
+		overlay[i] = byte(i * 7)
+	}
+	cert := make(synthBuffer, 0x20)
+	cert.put32(0, uint32(len(cert)))
+	cert.putString(8, "CERTIFICATE")
+	b := synthBuffer(synthImage{rich: true}.bytes())
+	certOff := len(b) + len(overlay)
+	b.put32(checksumOff, 0x1234)
+	b.put32(synthCertDataDirOff, uint32(certOff))
+	b.put32(synthCertDataDirOff+4, uint32(len(cert)))
+	data := append(append([]byte(b), overlay...), cert...)
+
+	file, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := file.Hashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Whole file, section and overlay.
+	if want := md5Hex(string(data)); hashes.File.MD5 != want {
+		t.Errorf("file MD5 mismatch; expected %s, got %s", want, hashes.File.MD5)
+	}
+	if want := sha256Hex(data); hashes.File.SHA256 != want {
+		t.Errorf("file SHA256 mismatch; expected %s, got %s", want, hashes.File.SHA256)
+	}
+	if len(hashes.Sections) != 1 || hashes.Sections[0].Name != ".data" {
+		t.Fatalf("section hashes mismatch; got %+v", hashes.Sections)
+	}
+	if want := sha256Hex(data[synthSectOffset : synthSectOffset+synthSectSize]); hashes.Sections[0].SHA256 != want {
+		t.Errorf("section SHA256 mismatch; expected %s, got %s", want, hashes.Sections[0].SHA256)
+	}
+	if hashes.Overlay == nil {
+		t.Fatal("missing overlay hashes")
+	}
+	if want := sha256Hex(overlay); hashes.Overlay.SHA256 != want {
+		t.Errorf("overlay SHA256 mismatch; expected %s, got %s", want, hashes.Overlay.SHA256)
+	}
+
+	// Authenticode hash, excluding the checksum, the certificate table data
+	// directory and the certificate table.
+	var auth []byte
+	auth = append(auth, data[:checksumOff]...)
+	auth = append(auth, data[checksumOff+4:synthCertDataDirOff]...)
+	auth = append(auth, data[synthCertDataDirOff+8:certOff]...)
+	if hashes.Authentihash == nil {
+		t.Fatal("missing Authenticode hashes")
+	}
+	if want := sha256Hex(auth); hashes.Authentihash.SHA256 != want {
+		t.Errorf("Authenticode SHA256 mismatch; expected %s, got %s", want, hashes.Authentihash.SHA256)
+	}
+
+	// Rich header hash, of the decoded Rich header.
+	rich, err := file.RichHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := bytes.Index(data, []byte("Rich"))
+	decoded := make([]byte, end-int(rich.Offset))
+	for i := 0; i < len(decoded); i += 4 {
+		binary.LittleEndian.PutUint32(decoded[i:], binary.LittleEndian.Uint32(data[int(rich.Offset)+i:])^rich.Key)
+	}
+	if want := md5Hex(string(decoded)); hashes.RichHash != want {
+		t.Errorf("Rich header hash mismatch; expected %s, got %s", want, hashes.RichHash)
+	}
+
+	if len(hashes.LSH) != 2*lshDigestSize {
+		t.Errorf("locality-sensitive hash length mismatch; expected %d, got %q", 2*lshDigestSize, hashes.LSH)
+	}
+}
+
+func TestHashesNoOverlay(t *testing.T) {
+	data := synthImage{is64: true}.bytes()
+	file, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := file.Hashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashes.Overlay != nil {
+		t.Errorf("unexpected overlay hashes; got %+v", hashes.Overlay)
+	}
+	if len(hashes.RichHash) != 0 {
+		t.Errorf("unexpected Rich header hash; got %q", hashes.RichHash)
+	}
+	// The checksum and certificate table data directory are zero, and are
+	// excluded from the Authenticode hash.
+	if hashes.Authentihash == nil || hashes.Authentihash.SHA256 == hashes.File.SHA256 {
+		t.Errorf("Authenticode hash mismatch; got %+v", hashes.Authentihash)
+	}
+	if want := sha256Hex(data); hashes.File.SHA256 != want {
+		t.Errorf("file SHA256 mismatch; expected %s, got %s", want, hashes.File.SHA256)
+	}
+}
+
+// sha256Hex returns the SHA-256 hash of data, as a lowercase hex string.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package pe
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// imphashOrdinals maps from lowercase DLL name to the ordinal-to-name table
+// used by the import hash. The set of tables is fixed, as extending it would
+// change the hash of existing files. As in pefile, from which the tables
+// originate, the ordinals of wsock32.dll are translated using the table of
+// ws2_32.dll.
+//
+// ref: https://github.com/erocarrera/pefile/tree/master/ordlookup
+var imphashOrdinals = map[string]map[uint16]string{
+	"ws2_32.dll":   ws2_32Ordinals,
+	"wsock32.dll":  ws2_32Ordinals,
+	"oleaut32.dll": oleaut32Ordinals,
+}
+
+// ImpHash returns the import hash (imphash) of file, as a lowercase hex
+// string; or an empty string if file has no imports.
+//
+// The import hash is the MD5 hash of the comma-separated list of imported
+// functions, in import table order. Each function is formatted as "dll.func",
+// where dll is the lowercase DLL name with any ".dll", ".ocx" or ".sys"
+// extension removed, and func is the lowercase function name. Functions
+// imported by ordinal from ws2_32.dll, wsock32.dll and oleaut32.dll are
+// translated to names, and other ordinals are formatted as "ordN".
+//
+// ref: https://www.mandiant.com/resources/blog/tracking-malware-import-hashing
+func (file *File) ImpHash() (string, error) {
+	imps, err := file.Imports()
+	if err != nil {
+		return "", err
+	}
+	if len(imps) == 0 {
+		return "", nil
+	}
+	var funcs []string
+	for _, imp := range imps {
+		dll := strings.ToLower(imp.DLL)
+		lib := dll
+		if pos := strings.LastIndexByte(dll, '.'); pos != -1 {
+			switch dll[pos+1:] {
+			case "dll", "ocx", "sys":
+				lib = dll[:pos]
+			}
+		}
+		for _, fn := range imp.Funcs {
+			name := fn.Name
+			if fn.ByOrdinal {
+				name = imphashOrdinalName(dll, fn.Ordinal)
+			}
+			funcs = append(funcs, lib+"."+strings.ToLower(name))
+		}
+	}
+	return md5Hex(strings.Join(funcs, ",")), nil
+}
+
+// imphashOrdinalName returns the name of the function with the given ordinal,
+// imported from the given lowercase DLL name, as used by the import hash.
+func imphashOrdinalName(dll string, ordinal uint16) string {
+	if name, ok := imphashOrdinals[dll][ordinal]; ok {
+		return name
+	}
+	return fmt.Sprintf("ord%d", ordinal)
+}
+
+// ExpHash returns the export hash of file, as a lowercase hex string; or an
+// empty string if file has no exports.
+//
+// The export hash is computed analogously to the import hash: the MD5 hash of
+// the comma-separated list of exported functions, in ordinal order. Each
+// function is formatted as its lowercase name, or "ordN" if exported by ordinal
+// only.
+func (file *File) ExpHash() (string, error) {
+	exps, err := file.Exports()
+	if err != nil {
+		return "", err
+	}
+	if exps == nil || len(exps.Funcs) == 0 {
+		return "", nil
+	}
+	funcs := make([]string, len(exps.Funcs))
+	for i, fn := range exps.Funcs {
+		if len(fn.Name) > 0 {
+			funcs[i] = strings.ToLower(fn.Name)
+		} else {
+			funcs[i] = fmt.Sprintf("ord%d", fn.Ordinal)
+		}
+	}
+	return md5Hex(strings.Join(funcs, ",")), nil
+}
+
+// md5Hex returns the MD5 hash of s, as a lowercase hex string.
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestImpHash(t *testing.T) {
+	// byOrdinal returns a copy of the synthetic image importing the functions
+	// of the given ordinals from dll.
+	byOrdinal := func(dll string, ordinals ...uint32) []byte {
+		b := synthBuffer(synthImage{}.bytes())
+		copy(b[sectOff(synthDLLRelAddr):], dll+"\x00")
+		for _, relAddr := range []int{synthILTRelAddr, synthIATRelAddr} {
+			for i, ordinal := range ordinals {
+				b.put32(sectOff(relAddr)+4*i, 1<<31|ordinal)
+			}
+			b.put32(sectOff(relAddr)+4*len(ordinals), 0)
+		}
+		return b
+	}
+	// ws2_32 returns a copy of the synthetic image importing WSAStartup by
+	// ordinal from WS2_32.dll.
+	ws2_32 := func() []byte {
+		b := synthBuffer(synthImage{}.bytes())
+		copy(b[sectOff(synthDLLRelAddr):], "WS2_32.dll\x00\x00")
+		for _, relAddr := range []int{synthILTRelAddr, synthIATRelAddr} {
+			b.put32(sectOff(relAddr)+4, 1<<31|115)
+		}
+		return b
+	}
+	golden := []struct {
+		name    string
+		data    []byte
+		impHash string
+		expHash string
+	}{
+		{
+			name:    "pe32",
+			data:    synthImage{}.bytes(),
+			impHash: "69052387c5b38d0b9c6f3f9fc76cddf4", // kernel32.exitprocess,kernel32.ord7
+			expHash: "a010ad878852cb32802d4e9c1e71c89b", // foo,ord2
+		},
+		{
+			name:    "pe32plus",
+			data:    synthImage{is64: true}.bytes(),
+			impHash: "69052387c5b38d0b9c6f3f9fc76cddf4",
+			expHash: "a010ad878852cb32802d4e9c1e71c89b",
+		},
+		{
+			name:    "ws2_32",
+			data:    ws2_32(),
+			impHash: "8ff71d15931ebb443b8266e884c7c1d4", // ws2_32.exitprocess,ws2_32.wsastartup
+			expHash: "a010ad878852cb32802d4e9c1e71c89b",
+		},
+		// The following import hashes match those of pefile, which translates
+		// the ordinals of wsock32.dll using the table of ws2_32.dll.
+		{
+			name:    "oleaut32",
+			data:    byOrdinal("OLEAUT32.dll", 150, 411, 442),
+			impHash: "23d9828cd8fc2c3e020ecb6c20f2f923", // oleaut32.sysallocstringbytelen,oleaut32.safearraycreatevector,oleaut32.registertypelibforuser
+			expHash: "a010ad878852cb32802d4e9c1e71c89b",
+		},
+		{
+			name:    "ws2_32 high ordinals",
+			data:    byOrdinal("ws2_32.dll", 98, 60, 100),
+			impHash: "8621e4bdc0ab740e7336955231b99e9b", // ws2_32.getaddrinfo,ws2_32.wsaioctl,ws2_32.ord100
+			expHash: "a010ad878852cb32802d4e9c1e71c89b",
+		},
+		{
+			name:    "wsock32",
+			data:    byOrdinal("WSOCK32.dll", 60, 1141, 115),
+			impHash: "7d3aef0d3e8a3b61c00c2988c63b5fb8", // wsock32.wsaioctl,wsock32.ord1141,wsock32.wsastartup
+			expHash: "a010ad878852cb32802d4e9c1e71c89b",
+		},
+		{
+			name:    "obj",
+			data:    synthObject(),
+			impHash: "",
+			expHash: "",
+		},
+	}
+	for _, g := range golden {
+		file, err := New(bytes.NewReader(g.data))
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		impHash, err := file.ImpHash()
+		if err != nil {
+			t.Errorf("%s: unable to compute import hash; %v", g.name, err)
+		} else if impHash != g.impHash {
+			t.Errorf("%s: import hash mismatch; expected %q, got %q", g.name, g.impHash, impHash)
+		}
+		expHash, err := file.ExpHash()
+		if err != nil {
+			t.Errorf("%s: unable to compute export hash; %v", g.name, err)
+		} else if expHash != g.expHash {
+			t.Errorf("%s: export hash mismatch; expected %q, got %q", g.name, g.expHash, expHash)
+		}
+	}
+}
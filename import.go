@@ -0,0 +1,229 @@
+package pe
+
+import (
+	"fmt"
+)
+
+// ImportDesc represents an import directory entry, which describes the
+// functions imported from a single DLL.
+type ImportDesc struct {
+	// Address of the import lookup table, relative to the image base.
+	ILTRelAddr uint32
+	// Zero until the image is bound, after which it is set to the time and date
+	// of the DLL.
+	Created Time
+	// Index of the first forwarder reference.
+	ForwarderChain uint32
+	// Address of the NULL-terminated DLL name, relative to the image base.
+	NameRelAddr uint32
+	// Address of the import address table (IAT), relative to the image base.
+	IATRelAddr uint32
+}
+
+// Import represents the functions imported from a single DLL.
+type Import struct {
+	// Import directory entry.
+	ImportDesc
+	// Name of the DLL.
+	DLL string
+	// Imported functions.
+	Funcs []*ImportFunc
+}
+
+// ImportFunc represents an imported function.
+type ImportFunc struct {
+	// Function name. For functions imported by ordinal, the name is looked up
+	// in the ordinal database when the imports are parsed; or empty if unknown.
+	Name string
+	// Index into the export name pointer table of the DLL, used as a hint
+	// when looking up the function by name.
+	Hint uint16
+	// Function ordinal; only valid if imported by ordinal.
+	Ordinal uint16
+	// Specifies whether the function is imported by ordinal.
+	ByOrdinal bool
+	// Address of the import address table (IAT) entry of the function,
+	// relative to the image base.
+	IATRelAddr uint32
+}
+
+func (fn *ImportFunc) String() string {
+	if fn.ByOrdinal {
+		if len(fn.Name) > 0 {
+			return fmt.Sprintf("%s (#%d)", fn.Name, fn.Ordinal)
+		}
+		return fmt.Sprintf("#%d", fn.Ordinal)
+	}
+	return fn.Name
+}
+
+// Imports returns the imports of file.
+func (file *File) Imports() (imps []*Import, err error) {
+	if file.imps == nil {
+		err = file.parseStruct(file.parseImports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.imps, nil
+}
+
+// parseImports parses the import table of file.
+func (file *File) parseImports() error {
+	dataDir, err := file.dataDir(DataDirImportTable)
+	if err != nil {
+		return err
+	}
+	imps := make([]*Import, 0)
+	if dataDir.RelAddr == 0 {
+		file.imps = imps
+		return nil
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return err
+	}
+
+	// Parse import directory entries, which are terminated by a zero entry. In
+	// tolerant mode, parsing stops at the first malformed entry, keeping the
+	// entries parsed so far.
+	const importDescSize = 20
+	limits := file.conf.limits
+	nfuncs := 0
+	for relAddr := dataDir.RelAddr; ; relAddr += importDescSize {
+		imp := new(Import)
+		if err := checkLimit(limitDirEntries, "import directory entries", int64(len(imps)+1), int64(limits.MaxDirEntries)); err != nil {
+			if file.tolerate(err) {
+				break
+			}
+			return err
+		}
+		if err := file.readRelAddr(relAddr, &imp.ImportDesc, "import directory entry"); err != nil {
+			if file.tolerate(err) {
+				break
+			}
+			return err
+		}
+		if imp.ImportDesc == (ImportDesc{}) {
+			break
+		}
+		imp.DLL, err = file.readStringRelAddr(imp.NameRelAddr, "import DLL name")
+		if err != nil {
+			if file.tolerate(err) {
+				break
+			}
+			return err
+		}
+		// The import lookup table is identical to the IAT until the image is
+		// bound; use the IAT if no import lookup table is present.
+		iltRelAddr := imp.ILTRelAddr
+		if iltRelAddr == 0 {
+			iltRelAddr = imp.IATRelAddr
+		}
+		imp.Funcs, err = file.parseImportFuncs(iltRelAddr, imp.IATRelAddr, opthdr.Is64(), nfuncs)
+		nfuncs += len(imp.Funcs)
+		file.nameOrdinalImports(imp)
+		if err != nil {
+			if file.tolerate(err) {
+				imps = append(imps, imp)
+				break
+			}
+			return err
+		}
+		imps = append(imps, imp)
+	}
+
+	file.imps = imps
+	return nil
+}
+
+// nameOrdinalImports names the functions imported by ordinal from imp, using
+// the ordinal database.
+func (file *File) nameOrdinalImports(imp *Import) {
+	if file.conf.ordinals == nil {
+		return
+	}
+	for _, fn := range imp.Funcs {
+		if !fn.ByOrdinal {
+			continue
+		}
+		if name, ok := file.conf.ordinals.Lookup(imp.DLL, fn.Ordinal); ok {
+			fn.Name = name
+		}
+	}
+}
+
+// parseImportFuncs parses the import lookup table at the given address, where
+// nfuncs functions have already been imported from other DLLs. The functions
+// parsed before encountering an error are returned along with the error.
+func (file *File) parseImportFuncs(iltRelAddr, iatRelAddr uint32, is64 bool, nfuncs int) ([]*ImportFunc, error) {
+	var funcs []*ImportFunc
+	entrySize := uint32(4)
+	if is64 {
+		entrySize = 8
+	}
+	for i := uint32(0); ; i++ {
+		var entry uint64
+		var byOrdinal bool
+		if is64 {
+			if err := file.readRelAddr(iltRelAddr+i*entrySize, &entry, "import lookup table entry"); err != nil {
+				return funcs, err
+			}
+			byOrdinal = entry&(1<<63) != 0
+		} else {
+			var entry32 uint32
+			if err := file.readRelAddr(iltRelAddr+i*entrySize, &entry32, "import lookup table entry"); err != nil {
+				return funcs, err
+			}
+			entry = uint64(entry32)
+			byOrdinal = entry32&(1<<31) != 0
+		}
+		if entry == 0 {
+			break
+		}
+		if err := checkLimit(limitImports, "import lookup table", int64(nfuncs+len(funcs)+1), int64(file.conf.limits.MaxImports)); err != nil {
+			return funcs, err
+		}
+		fn := &ImportFunc{
+			ByOrdinal:  byOrdinal,
+			IATRelAddr: iatRelAddr + i*entrySize,
+		}
+		if byOrdinal {
+			fn.Ordinal = uint16(entry)
+		} else {
+			// Hint/name table entry.
+			hintRelAddr := uint32(entry & 0x7FFFFFFF)
+			if err := file.readRelAddr(hintRelAddr, &fn.Hint, "import hint"); err != nil {
+				return funcs, err
+			}
+			name, err := file.readStringRelAddr(hintRelAddr+2, "import name")
+			if err != nil {
+				return funcs, err
+			}
+			fn.Name = name
+		}
+		funcs = append(funcs, fn)
+	}
+	return funcs, nil
+}
+
+// dataDir returns the data directory at the given index, or a zero data
+// directory if not present.
+func (file *File) dataDir(index int) (DataDirectory, error) {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return DataDirectory{}, err
+	}
+	if fileHdr.OptHdrSize == 0 {
+		return DataDirectory{}, nil
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return DataDirectory{}, err
+	}
+	if index >= len(opthdr.DataDirs) {
+		return DataDirectory{}, nil
+	}
+	return opthdr.DataDirs[index], nil
+}
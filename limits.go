@@ -0,0 +1,79 @@
+package pe
+
+import "fmt"
+
+// Limits specifies upper bounds on the resources consumed while parsing a File,
+// to guard against hostile inputs with crafted size and count fields. A zero
+// field disables the corresponding limit.
+type Limits struct {
+	// Maximum number of bytes allocated for a single structure or data blob
+	// (e.g. section contents, overlay, resource data).
+	MaxAlloc int64
+	// Maximum number of entries of a single directory table (e.g. import
+	// directory, resource directory).
+	MaxDirEntries int
+	// Maximum depth of the resource directory tree.
+	MaxResourceDepth int
+	// Maximum total number of imported functions.
+	MaxImports int
+	// Maximum number of entries of the export address table and export name
+	// pointer table.
+	MaxExports int
+}
+
+// DefaultLimits specifies the limits used unless overridden by WithLimits.
+var DefaultLimits = Limits{
+	MaxAlloc:         256 << 20,
+	MaxDirEntries:    4096,
+	MaxResourceDepth: 8,
+	MaxImports:       65536,
+	MaxExports:       65536,
+}
+
+// WithLimits returns an option which sets the resource limits of a File.
+func WithLimits(limits Limits) Option {
+	return func(conf *config) {
+		conf.limits = limits
+	}
+}
+
+// A LimitError reports that parsing a structure of a PE file would exceed a
+// resource limit.
+type LimitError struct {
+	// Name of the limit (e.g. "MaxAlloc").
+	Limit string
+	// Name of the structure (e.g. "overlay").
+	Struct string
+	// Requested value.
+	Value int64
+	// Maximum value allowed by the limit.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("pe: %s exceeds limit %s (%d > %d)", e.Struct, e.Limit, e.Value, e.Max)
+}
+
+// Names of the resource limits.
+const (
+	limitAlloc         = "MaxAlloc"
+	limitDirEntries    = "MaxDirEntries"
+	limitResourceDepth = "MaxResourceDepth"
+	limitImports       = "MaxImports"
+	limitExports       = "MaxExports"
+)
+
+// checkLimit returns a LimitError if the requested value of the named limit
+// exceeds max. A zero max disables the limit.
+func checkLimit(limit, structName string, value, max int64) error {
+	if max > 0 && value > max {
+		return &LimitError{Limit: limit, Struct: structName, Value: value, Max: max}
+	}
+	return nil
+}
+
+// checkAlloc returns a LimitError if allocating size bytes for the given
+// structure exceeds the MaxAlloc limit.
+func (file *File) checkAlloc(structName string, size int64) error {
+	return checkLimit(limitAlloc, structName, size, file.conf.limits.MaxAlloc)
+}
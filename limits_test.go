@@ -0,0 +1,114 @@
+package pe
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	golden := []struct {
+		// Resource limits.
+		limits Limits
+		// Accesses the structure exceeding the limit.
+		get func(file *File) error
+		// Expected name of the exceeded limit and structure.
+		limit, structName string
+	}{
+		{
+			limits: Limits{MaxAlloc: 0x100},
+			get: func(file *File) error {
+				sectHdrs, err := file.SectHeaders()
+				if err != nil {
+					return err
+				}
+				_, err = file.Section(sectHdrs[0])
+				return err
+			},
+			limit:      "MaxAlloc",
+			structName: "section contents",
+		},
+		{
+			limits:     Limits{MaxDirEntries: 1},
+			get:        func(file *File) error { _, err := file.Imports(); return err },
+			limit:      "MaxDirEntries",
+			structName: "import directory entries",
+		},
+		{
+			limits:     Limits{MaxResourceDepth: 2},
+			get:        func(file *File) error { _, err := file.Resources(); return err },
+			limit:      "MaxResourceDepth",
+			structName: "resource directory tree",
+		},
+		{
+			limits:     Limits{MaxImports: 1},
+			get:        func(file *File) error { _, err := file.Imports(); return err },
+			limit:      "MaxImports",
+			structName: "import lookup table",
+		},
+		{
+			limits:     Limits{MaxExports: 1},
+			get:        func(file *File) error { _, err := file.Exports(); return err },
+			limit:      "MaxExports",
+			structName: "export address table",
+		},
+	}
+	data := synthImage{}.bytes()
+	for _, g := range golden {
+		file, err := New(bytes.NewReader(data), WithLimits(g.limits))
+		if err != nil {
+			t.Errorf("%s: unable to create file; %v", g.limit, err)
+			continue
+		}
+		err = g.get(file)
+		var e *LimitError
+		if !errors.As(err, &e) {
+			t.Errorf("%s: expected *LimitError, got %v", g.limit, err)
+			continue
+		}
+		if e.Limit != g.limit || e.Struct != g.structName {
+			t.Errorf("%s: limit mismatch; expected %s of %q, got %s of %q", g.limit, g.limit, g.structName, e.Limit, e.Struct)
+		}
+		if e.Value <= e.Max {
+			t.Errorf("%s: requested value %d within limit %d", g.limit, e.Value, e.Max)
+		}
+		// The limit also applies to eager parsing.
+		if g.limit != "MaxAlloc" {
+			if _, err := New(bytes.NewReader(data), WithLimits(g.limits), WithParseMode(ParseEager)); !errors.As(err, &e) {
+				t.Errorf("%s: eager: expected *LimitError, got %v", g.limit, err)
+			}
+		}
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	golden := []struct {
+		name string
+		data []byte
+	}{
+		{name: "pe32", data: synthImage{}.bytes()},
+		{name: "pe32plus", data: synthImage{is64: true}.bytes()},
+		{name: "rich", data: synthImage{rich: true}.bytes()},
+		{name: "repro", data: synthReproImage(0x11111111, 0x1234, 0xAA, 0xBB)},
+		{name: "obj", data: synthObject()},
+	}
+	for _, g := range golden {
+		for _, limits := range []Limits{DefaultLimits, {}} {
+			file, err := New(bytes.NewReader(g.data), WithLimits(limits), WithParseMode(ParseEager))
+			if err != nil {
+				t.Errorf("%s: %+v: unexpected error; %v", g.name, limits, err)
+				continue
+			}
+			sectHdrs, err := file.SectHeaders()
+			if err != nil {
+				t.Errorf("%s: %+v: unexpected error; %v", g.name, limits, err)
+				continue
+			}
+			for _, sectHdr := range sectHdrs {
+				if _, err := file.Section(sectHdr); err != nil {
+					t.Errorf("%s: %+v: unexpected error of section %q; %v", g.name, limits, sectHdr.Name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// LoadConfig represents the load configuration directory of an image. Only the
+// fields related to security features are parsed; addresses are virtual
+// addresses (not relative to the image base), widened to 64 bits for 32-bit
+// images. Fields beyond the size of the directory, as recorded by the Size
+// field, are zero.
+type LoadConfig struct {
+	// Size of the load configuration directory in bytes.
+	Size uint32
+	// The time and date the load configuration was created.
+	Created Time
+	// Virtual address of the security cookie used by /GS.
+	SecurityCookie uint64
+	// Virtual address of the table of safe exception handlers (SafeSEH) of
+	// 32-bit images.
+	SEHandlerTable uint64
+	// Number of safe exception handlers.
+	SEHandlerCount uint64
+	// Virtual address of the control flow guard (CFG) check function pointer.
+	GuardCFCheckFunc uint64
+	// Virtual address of the CFG dispatch function pointer.
+	GuardCFDispatchFunc uint64
+	// Virtual address of the CFG function table, listing the valid targets of
+	// indirect calls.
+	GuardCFFuncTable uint64
+	// Number of entries of the CFG function table.
+	GuardCFFuncCount uint64
+	// Control flow guard flags.
+	GuardFlags GuardFlag
+}
+
+// GuardFlag is a bitfield which specifies the control flow guard features of
+// an image.
+type GuardFlag uint32
+
+// Control flow guard flags.
+const (
+	// GuardFlagCFInstrumented indicates that the image performs CFG checks.
+	GuardFlagCFInstrumented GuardFlag = 0x00000100
+	// GuardFlagCFWInstrumented indicates that the image performs CFG write
+	// integrity checks.
+	GuardFlagCFWInstrumented GuardFlag = 0x00000200
+	// GuardFlagCFFuncTablePresent indicates that the image contains a CFG
+	// function table.
+	GuardFlagCFFuncTablePresent GuardFlag = 0x00000400
+	// GuardFlagSecurityCookieUnused indicates that the image does not use the
+	// /GS security cookie.
+	GuardFlagSecurityCookieUnused GuardFlag = 0x00000800
+	// GuardFlagProtectDelayLoadIAT indicates that the image supports read-only
+	// delay load IATs.
+	GuardFlagProtectDelayLoadIAT GuardFlag = 0x00001000
+	// GuardFlagDelayLoadIATInOwnSection indicates that the delay load IAT is
+	// located in a section of its own.
+	GuardFlagDelayLoadIATInOwnSection GuardFlag = 0x00002000
+	// GuardFlagCFExportSuppressionInfoPresent indicates that the image
+	// contains suppressed export information.
+	GuardFlagCFExportSuppressionInfoPresent GuardFlag = 0x00004000
+	// GuardFlagCFEnableExportSuppression indicates that the image enables
+	// suppression of exports.
+	GuardFlagCFEnableExportSuppression GuardFlag = 0x00008000
+	// GuardFlagCFLongJumpTablePresent indicates that the image contains
+	// longjmp target information.
+	GuardFlagCFLongJumpTablePresent GuardFlag = 0x00010000
+	// GuardFlagCFFuncTableSizeMask masks the number of extra bytes of each
+	// CFG function table entry, stored in the upper four bits.
+	GuardFlagCFFuncTableSizeMask GuardFlag = 0xF0000000
+)
+
+// guardFlagName is a map from GuardFlag to string description.
+var guardFlagName = map[GuardFlag]string{
+	GuardFlagCFInstrumented:                 "CF instrumented",
+	GuardFlagCFWInstrumented:                "CFW instrumented",
+	GuardFlagCFFuncTablePresent:             "CF function table present",
+	GuardFlagSecurityCookieUnused:           "security cookie unused",
+	GuardFlagProtectDelayLoadIAT:            "protect delay load IAT",
+	GuardFlagDelayLoadIATInOwnSection:       "delay load IAT in own section",
+	GuardFlagCFExportSuppressionInfoPresent: "CF export suppression info present",
+	GuardFlagCFEnableExportSuppression:      "CF enable export suppression",
+	GuardFlagCFLongJumpTablePresent:         "CF longjmp table present",
+}
+
+func (flags GuardFlag) String() string {
+	var ss []string
+	flags &^= GuardFlagCFFuncTableSizeMask
+	for i := uint(0); i < 28; i++ {
+		mask := GuardFlag(1 << i)
+		if flags&mask != 0 {
+			s, ok := guardFlagName[mask]
+			if !ok {
+				s = fmt.Sprintf("unknown flag: 0x%08X", uint32(mask))
+			}
+			ss = append(ss, s)
+		}
+	}
+	if len(ss) == 0 {
+		return "none"
+	}
+	return strings.Join(ss, "|")
+}
+
+// Offsets of load configuration directory fields, for 32-bit and 64-bit images
+// respectively.
+var (
+	loadCfgOffsets32 = loadCfgOffsets{cookie: 0x3C, seh: 0x40, guard: 0x48, end: 0x5C}
+	loadCfgOffsets64 = loadCfgOffsets{cookie: 0x58, seh: 0x60, guard: 0x70, end: 0x94}
+)
+
+// loadCfgOffsets specifies the offsets of the parsed fields of the load
+// configuration directory.
+type loadCfgOffsets struct {
+	// Offset of SecurityCookie.
+	cookie int
+	// Offset of SEHandlerTable, succeeded by SEHandlerCount.
+	seh int
+	// Offset of GuardCFCheckFunc, succeeded by GuardCFDispatchFunc,
+	// GuardCFFuncTable, GuardCFFuncCount and GuardFlags.
+	guard int
+	// Offset of the end of GuardFlags.
+	end int
+}
+
+// LoadConfig returns the load configuration directory of file; or nil if not
+// present.
+func (file *File) LoadConfig() (loadCfg *LoadConfig, err error) {
+	if !file.loadCfgParsed {
+		err = file.parseStruct(file.parseLoadConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.loadCfg, nil
+}
+
+// parseLoadConfig parses the load configuration directory of file.
+func (file *File) parseLoadConfig() error {
+	dataDir, err := file.dataDir(DataDirLoadConfigTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 {
+		file.loadCfgParsed = true
+		return nil
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return err
+	}
+	offs, ptrSize := loadCfgOffsets32, 4
+	if opthdr.Is64() {
+		offs, ptrSize = loadCfgOffsets64, 8
+	}
+
+	// The Size field of the directory, rather than the size of the data
+	// directory, specifies which fields are present.
+	var hdr struct {
+		Size    uint32
+		Created Time
+	}
+	if err := file.readRelAddr(dataDir.RelAddr, &hdr, "load configuration directory"); err != nil {
+		return err
+	}
+	size := int(hdr.Size)
+	if size < 8 {
+		return formatError("load configuration directory", -1, "invalid size %d", size)
+	}
+	if size > offs.end {
+		size = offs.end
+	}
+	buf := make([]byte, size)
+	if err := file.readRelAddr(dataDir.RelAddr, buf, "load configuration directory"); err != nil {
+		return err
+	}
+	ptr := func(off int) uint64 {
+		if off+ptrSize > len(buf) {
+			return 0
+		}
+		if ptrSize == 8 {
+			return binary.LittleEndian.Uint64(buf[off:])
+		}
+		return uint64(binary.LittleEndian.Uint32(buf[off:]))
+	}
+	loadCfg := &LoadConfig{
+		Size:                hdr.Size,
+		Created:             hdr.Created,
+		SecurityCookie:      ptr(offs.cookie),
+		SEHandlerTable:      ptr(offs.seh),
+		SEHandlerCount:      ptr(offs.seh + ptrSize),
+		GuardCFCheckFunc:    ptr(offs.guard),
+		GuardCFDispatchFunc: ptr(offs.guard + ptrSize),
+		GuardCFFuncTable:    ptr(offs.guard + 2*ptrSize),
+		GuardCFFuncCount:    ptr(offs.guard + 3*ptrSize),
+	}
+	if off := offs.guard + 4*ptrSize; off+4 <= len(buf) {
+		loadCfg.GuardFlags = GuardFlag(binary.LittleEndian.Uint32(buf[off:]))
+	}
+
+	file.loadCfg = loadCfg
+	file.loadCfgParsed = true
+	return nil
+}
+
+// GuardCFFuncs returns the addresses of the functions of the control flow
+// guard function table of file, relative to the image base; or nil if not
+// present.
+func (file *File) GuardCFFuncs() ([]uint32, error) {
+	loadCfg, err := file.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if loadCfg == nil || loadCfg.GuardCFFuncTable == 0 || loadCfg.GuardCFFuncCount == 0 {
+		return nil, nil
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return nil, err
+	}
+	if loadCfg.GuardCFFuncTable < opthdr.ImageBase64 || loadCfg.GuardCFFuncTable-opthdr.ImageBase64 > 0xFFFFFFFF {
+		return nil, formatError("CFG function table", -1, "address 0x%X outside of image", loadCfg.GuardCFFuncTable)
+	}
+	// Each entry holds the address of a function, followed by a number of
+	// bytes of metadata specified by the guard flags.
+	entrySize := 4 + int64(loadCfg.GuardFlags&GuardFlagCFFuncTableSizeMask>>28)
+	if loadCfg.GuardCFFuncCount > 0xFFFFFFFF {
+		return nil, formatError("CFG function table", -1, "invalid function count %d", loadCfg.GuardCFFuncCount)
+	}
+	size := int64(loadCfg.GuardCFFuncCount) * entrySize
+	if err := file.checkAlloc("CFG function table", size); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	if err := file.readRelAddr(uint32(loadCfg.GuardCFFuncTable-opthdr.ImageBase64), buf, "CFG function table"); err != nil {
+		return nil, err
+	}
+	funcs := make([]uint32, 0, loadCfg.GuardCFFuncCount)
+	for off := int64(0); off < size; off += entrySize {
+		funcs = append(funcs, binary.LittleEndian.Uint32(buf[off:]))
+	}
+	return funcs, nil
+}
@@ -0,0 +1,74 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	const (
+		loadCfgRelAddr = 0x1390
+		tableRelAddr   = 0x1C0 // located in the headers.
+		dataDirOff     = synthPEHdrOffset + 4 + coffHdrSize + optHdr32Size + DataDirLoadConfigTable*8
+		flags          = GuardFlagCFInstrumented | GuardFlagCFFuncTablePresent | 1<<28
+	)
+	b := synthBuffer(synthImage{}.bytes())
+	b.put32(dataDirOff, loadCfgRelAddr)
+	b.put32(dataDirOff+4, 0x40)
+	off := sectOff(loadCfgRelAddr)
+	b.put32(off, 0x5C)
+	b.put32(off+4, 0x12345678)
+	b.put32(off+0x3C, synthImageBase+0x13F0)
+	b.put32(off+0x50, synthImageBase+tableRelAddr)
+	b.put32(off+0x54, 2)
+	b.put32(off+0x58, uint32(flags))
+	// Entries hold one byte of metadata each.
+	b.put32(tableRelAddr, 0x1000)
+	b.put32(tableRelAddr+5, 0x1010)
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadCfg, err := file.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LoadConfig{
+		Size:             0x5C,
+		Created:          0x12345678,
+		SecurityCookie:   synthImageBase + 0x13F0,
+		GuardCFFuncTable: synthImageBase + tableRelAddr,
+		GuardCFFuncCount: 2,
+		GuardFlags:       flags,
+	}
+	if loadCfg == nil || *loadCfg != want {
+		t.Fatalf("load configuration mismatch; expected %+v, got %+v", want, loadCfg)
+	}
+	if got, want := loadCfg.GuardFlags.String(), "CF instrumented|CF function table present"; got != want {
+		t.Errorf("guard flags mismatch; expected %q, got %q", want, got)
+	}
+	funcs, err := file.GuardCFFuncs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 2 || funcs[0] != 0x1000 || funcs[1] != 0x1010 {
+		t.Errorf("CFG functions mismatch; expected [0x1000 0x1010], got %#x", funcs)
+	}
+
+	// Fields beyond the size of the directory are zero.
+	b.put32(off, 0x48)
+	file, err = New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loadCfg, err = file.LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if loadCfg.GuardCFFuncTable != 0 || loadCfg.GuardFlags != 0 || loadCfg.SecurityCookie == 0 {
+		t.Errorf("unexpected fields of truncated load configuration; got %+v", loadCfg)
+	}
+	if funcs, err = file.GuardCFFuncs(); err != nil || funcs != nil {
+		t.Errorf("expected no CFG functions; got %#x, %v", funcs, err)
+	}
+}
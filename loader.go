@@ -0,0 +1,320 @@
+package pe
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Image represents a PE image as mapped into memory by the Windows loader, with
+// the headers and sections placed at their virtual addresses and base
+// relocations applied. Nothing is executed and imports are not resolved.
+type Image struct {
+	// Base address of the image.
+	Base uint64
+	// Contents of the image, starting at the base address; ImageSize bytes
+	// rounded up to the section alignment.
+	Mem []byte
+}
+
+// ReadAt reads len(p) bytes from the image, starting at the virtual address
+// va. It implements the io.ReaderAt interface, with virtual addresses used as
+// offsets.
+func (img *Image) ReadAt(p []byte, va int64) (n int, err error) {
+	if va < 0 || uint64(va) < img.Base {
+		return 0, io.EOF
+	}
+	relAddr := uint64(va) - img.Base
+	if relAddr >= uint64(len(img.Mem)) {
+		return 0, io.EOF
+	}
+	n = copy(p, img.Mem[relAddr:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Contains reports whether the virtual address va is contained within the
+// image.
+func (img *Image) Contains(va uint64) bool {
+	return va >= img.Base && va-img.Base < uint64(len(img.Mem))
+}
+
+// RelAddr returns the address of va relative to the image base, and reports
+// whether va is contained within the image.
+func (img *Image) RelAddr(va uint64) (uint32, bool) {
+	if !img.Contains(va) {
+		return 0, false
+	}
+	return uint32(va - img.Base), true
+}
+
+// Page size of the Windows loader. Images with a section alignment smaller than
+// the page size are mapped without rounding the file offsets of sections.
+const pageSize = 0x1000
+
+// Load maps the headers and sections of file into memory as the Windows loader
+// would, and applies base relocations for the given base address. Use the
+// ImageBase64 field of the optional header to load the image at its preferred
+// base address. Loading an image at a different base address fails if its
+// relocations have been stripped.
+//
+// The image is ImageSize bytes, rounded up to the section alignment. Sections
+// are placed at their addresses; the file offset of each section is rounded
+// down to 512 bytes and its raw size is rounded up to the file alignment, as
+// done by the Windows loader. Bytes past the raw data of a section (e.g. BSS)
+// are zero. Images in mapped layout are copied as is.
+func (file *File) Load(base uint64) (*Image, error) {
+	obj, err := file.IsObject()
+	if err != nil {
+		return nil, err
+	}
+	if obj {
+		return nil, errors.New("pe: unable to load COFF object file")
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return nil, err
+	}
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	fileSize, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allocate image.
+	sectAlign := uint64(opthdr.SectAlign)
+	size := alignUp(uint64(opthdr.ImageSize), sectAlign)
+	if err := file.checkAlloc("image", int64(size)); err != nil {
+		return nil, err
+	}
+	img := &Image{
+		Base: base,
+		Mem:  make([]byte, size),
+	}
+
+	// mapRange copies n bytes from the given file offset to the given address
+	// of the image, clipped to the end of the file and image.
+	mapRange := func(relAddr uint64, off, n int64, structName string) error {
+		if off >= fileSize || relAddr >= size {
+			return nil
+		}
+		if n > fileSize-off {
+			n = fileSize - off
+		}
+		if uint64(n) > size-relAddr {
+			n = int64(size - relAddr)
+		}
+		if _, err := file.r.ReadAt(img.Mem[relAddr:relAddr+uint64(n)], off); err != nil && err != io.EOF {
+			return readError(structName, off, err)
+		}
+		return nil
+	}
+
+	// Map headers and sections. Images in mapped layout are copied as is.
+	if file.conf.layout == LayoutMapped {
+		if err := mapRange(0, 0, int64(size), "image"); err != nil {
+			return nil, err
+		}
+		sectHdrs = nil
+	} else if err := mapRange(0, 0, int64(opthdr.HdrSize), "headers"); err != nil {
+		return nil, err
+	}
+	lowAlign := sectAlign < pageSize
+	for _, sectHdr := range sectHdrs {
+		if sectHdr.Size == 0 {
+			continue
+		}
+		off := int64(sectHdr.Offset)
+		rawSize := alignUp(uint64(sectHdr.Size), uint64(opthdr.FileAlign))
+		if !lowAlign {
+			off &^= 0x1FF
+		}
+		if sectHdr.VirtSize != 0 {
+			if virtSize := alignUp(uint64(sectHdr.VirtSize), sectAlign); rawSize > virtSize {
+				rawSize = virtSize
+			}
+		}
+		if err := mapRange(uint64(sectHdr.RelAddr), off, int64(rawSize), "section "+sectHdr.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply base relocations.
+	delta := base - opthdr.ImageBase64
+	if delta == 0 {
+		return img, nil
+	}
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return nil, err
+	}
+	if fileHdr.Flags&FlagNoReloc != 0 {
+		return nil, fmt.Errorf("pe: unable to load image at 0x%X; relocations stripped from image with base 0x%X", base, opthdr.ImageBase64)
+	}
+	relocs, err := file.BaseRelocs()
+	if err != nil {
+		return nil, err
+	}
+	for _, reloc := range relocs {
+		if err := img.relocate(reloc, delta); err != nil {
+			if !file.tolerate(err) {
+				return nil, err
+			}
+		}
+	}
+	return img, nil
+}
+
+// relocate applies the base relocation to the image, adjusting the relocated
+// value by delta.
+func (img *Image) relocate(reloc *BaseReloc, delta uint64) error {
+	size := 0
+	switch reloc.Type {
+	case RelocHigh, RelocLow, RelocHighAdj:
+		size = 2
+	case RelocHighLow:
+		size = 4
+	case RelocDir64:
+		size = 8
+	default:
+		return formatError("base relocation", -1, "unsupported relocation type %v at address 0x%08X", reloc.Type, reloc.RelAddr)
+	}
+	if uint64(reloc.RelAddr)+uint64(size) > uint64(len(img.Mem)) {
+		return formatError("base relocation", -1, "address 0x%08X outside of image", reloc.RelAddr)
+	}
+	b := img.Mem[reloc.RelAddr:]
+	switch reloc.Type {
+	case RelocHigh:
+		v := uint32(binary.LittleEndian.Uint16(b))<<16 + uint32(delta)
+		binary.LittleEndian.PutUint16(b, uint16(v>>16))
+	case RelocLow:
+		binary.LittleEndian.PutUint16(b, binary.LittleEndian.Uint16(b)+uint16(delta))
+	case RelocHighAdj:
+		v := uint32(binary.LittleEndian.Uint16(b))<<16 + uint32(int32(int16(reloc.Param)))
+		v += uint32(delta) + 0x8000
+		binary.LittleEndian.PutUint16(b, uint16(v>>16))
+	case RelocHighLow:
+		binary.LittleEndian.PutUint32(b, binary.LittleEndian.Uint32(b)+uint32(delta))
+	case RelocDir64:
+		binary.LittleEndian.PutUint64(b, binary.LittleEndian.Uint64(b)+delta)
+	}
+	return nil
+}
+
+// alignUp rounds x up to a multiple of align, which need not be a power of two.
+// Alignments of zero leave x unchanged.
+func alignUp(x, align uint64) uint64 {
+	if align == 0 {
+		return x
+	}
+	return (x + align - 1) / align * align
+}
+
+// Unmap returns the contents of a PE file in file layout, reconstructed from
+// the image as loaded into memory. It is primarily used to recover PE files
+// from images in mapped layout, such as modules carved from process memory
+// dumps.
+//
+// The headers are kept as is, and the sections are laid out consecutively
+// after the headers, at offsets aligned to the file alignment. The raw data of
+// each section covers its entire virtual size, except for sections holding
+// only uninitialized data, which have no raw data. The file offsets and raw
+// sizes of the section table are updated accordingly, and the certificate
+// table, which is not loaded into memory, is removed.
+func (file *File) Unmap() ([]byte, error) {
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return nil, err
+	}
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	sectHdrsOff, err := file.sectHdrsOffset()
+	if err != nil {
+		return nil, err
+	}
+	optoff, err := file.optHdrOffset()
+	if err != nil {
+		return nil, err
+	}
+	img, err := file.Load(opthdr.ImageBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compute the file layout of sections.
+	fileAlign := uint64(opthdr.FileAlign)
+	hdrSize := alignUp(uint64(opthdr.HdrSize), fileAlign)
+	offs := make([]uint64, len(sectHdrs))
+	sizes := make([]uint64, len(sectHdrs))
+	end := hdrSize
+	for i, sectHdr := range sectHdrs {
+		if sectHdr.Flags&(SectFlagCode|SectFlagData|SectFlagBSS) == SectFlagBSS {
+			continue
+		}
+		relAddr := uint64(sectHdr.RelAddr)
+		if relAddr >= uint64(len(img.Mem)) {
+			continue
+		}
+		size := uint64(sectHdr.virtSize())
+		if rest := uint64(len(img.Mem)) - relAddr; size > rest {
+			size = rest
+		}
+		if size == 0 {
+			continue
+		}
+		offs[i] = end
+		sizes[i] = alignUp(size, fileAlign)
+		end += sizes[i]
+	}
+	if err := file.checkAlloc("unmapped image", int64(end)); err != nil {
+		return nil, err
+	}
+
+	// Copy headers and sections.
+	buf := make([]byte, end)
+	copy(buf[:opthdr.HdrSize], img.Mem)
+	for i, sectHdr := range sectHdrs {
+		if sizes[i] == 0 {
+			continue
+		}
+		n := copy(buf[offs[i]:offs[i]+sizes[i]], img.Mem[sectHdr.RelAddr:])
+		// Zero the alignment padding, which holds the start of the following
+		// section in memory.
+		size := uint64(sectHdr.virtSize())
+		if size < uint64(n) {
+			for j := offs[i] + size; j < offs[i]+uint64(n); j++ {
+				buf[j] = 0
+			}
+		}
+	}
+
+	// Fix the section table.
+	for i := range sectHdrs {
+		off := uint64(sectHdrsOff) + uint64(i)*sectHdrSize
+		if off+sectHdrSize > uint64(len(buf)) {
+			break
+		}
+		binary.LittleEndian.PutUint32(buf[off+16:], uint32(sizes[i]))
+		binary.LittleEndian.PutUint32(buf[off+20:], uint32(offs[i]))
+	}
+
+	// Remove the certificate table.
+	if DataDirCertificateTable < len(opthdr.DataDirs) {
+		off := uint64(optoff) + optHdr32Size + DataDirCertificateTable*8
+		if opthdr.Is64() {
+			off = uint64(optoff) + optHdr64Size + DataDirCertificateTable*8
+		}
+		if off+8 <= uint64(len(buf)) {
+			binary.LittleEndian.PutUint64(buf[off:], 0)
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,120 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	for _, img := range []synthImage{{}, {is64: true}} {
+		for _, base := range []uint64{synthImageBase, 0x10000000} {
+			name := fmt.Sprintf("is64=%v,base=0x%X", img.is64, base)
+			file, err := New(bytes.NewReader(img.bytes()))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			image, err := file.Load(base)
+			if err != nil {
+				t.Errorf("%s: unable to load image; %v", name, err)
+				continue
+			}
+			if len(image.Mem) != synthImageSize {
+				t.Errorf("%s: image size mismatch; expected 0x%X, got 0x%X", name, synthImageSize, len(image.Mem))
+			}
+
+			// Headers.
+			buf := make([]byte, 2)
+			if _, err := image.ReadAt(buf, int64(base)); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if string(buf) != "MZ" {
+				t.Errorf("%s: DOS signature mismatch; got %q", name, buf)
+			}
+
+			// Section contents.
+			dllName := make([]byte, len("kernel32.dll"))
+			if _, err := image.ReadAt(dllName, int64(base+synthDLLRelAddr)); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if string(dllName) != "kernel32.dll" {
+				t.Errorf("%s: section contents mismatch; got %q", name, dllName)
+			}
+
+			// Relocated pointer.
+			var ptr uint64
+			if img.is64 {
+				ptr = binary.LittleEndian.Uint64(image.Mem[synthPtrRelAddr:])
+			} else {
+				ptr = uint64(binary.LittleEndian.Uint32(image.Mem[synthPtrRelAddr:]))
+			}
+			if want := base + synthSectRelAddr; ptr != want {
+				t.Errorf("%s: relocated pointer mismatch; expected 0x%X, got 0x%X", name, want, ptr)
+			}
+
+			// Zero-filled tail past the section.
+			if !image.Contains(base+synthImageSize-1) || image.Contains(base+synthImageSize) {
+				t.Errorf("%s: image bounds mismatch", name)
+			}
+			for _, b := range image.Mem[synthSectRelAddr+synthSectSize:] {
+				if b != 0 {
+					t.Errorf("%s: non-zero byte past section", name)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestMappedLayout(t *testing.T) {
+	for _, img := range []synthImage{{}, {is64: true}} {
+		name := fmt.Sprintf("is64=%v", img.is64)
+		raw := img.bytes()
+		file, err := New(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		image, err := file.Load(synthImageBase)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// Parse the loaded image in mapped layout.
+		mapped, err := New(bytes.NewReader(image.Mem), WithLayout(LayoutMapped), WithParseMode(ParseEager))
+		if err != nil {
+			t.Fatalf("%s: unable to parse mapped image; %v", name, err)
+		}
+		imps, err := mapped.Imports()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(imps) != 1 || imps[0].DLL != "kernel32.dll" || len(imps[0].Funcs) != 2 {
+			t.Errorf("%s: imports mismatch in mapped layout", name)
+		}
+		sectHdrs, err := mapped.SectHeaders()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		data, err := mapped.Section(sectHdrs[0])
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := raw[synthSectOffset : synthSectOffset+synthSectSize]
+		if !bytes.Equal(data, want) {
+			t.Errorf("%s: section contents mismatch in mapped layout", name)
+		}
+		if off, err := mapped.RelAddrToOffset(synthDLLRelAddr); err != nil || off != synthDLLRelAddr {
+			t.Errorf("%s: address translation mismatch in mapped layout; got 0x%X (%v)", name, off, err)
+		}
+
+		// Reconstruct the file layout.
+		unmapped, err := mapped.Unmap()
+		if err != nil {
+			t.Fatalf("%s: unable to unmap image; %v", name, err)
+		}
+		if !bytes.Equal(unmapped, raw) {
+			t.Errorf("%s: unmapped image differs from original", name)
+		}
+	}
+}
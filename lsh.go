@@ -0,0 +1,220 @@
+package pe
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The locality-sensitive hash follows the design of TLSH; byte triplets of a
+// sliding window are counted in 128 buckets, and the digest encodes the
+// quartile of each bucket count together with a header holding a checksum, the
+// logarithm of the data length and the ratios between the quartiles. The
+// Pearson table differs from the one of the reference implementation, so
+// digests are not interchangeable with those of TLSH tools.
+//
+// ref: https://github.com/trendmicro/tlsh
+
+const (
+	// lshWindowSize specifies the size of the sliding window.
+	lshWindowSize = 5
+	// lshBuckets specifies the number of buckets encoded in the digest.
+	lshBuckets = 128
+	// lshMinSize specifies the minimum length of hashed data.
+	lshMinSize = 50
+	// lshDigestSize specifies the size of the digest in bytes; the checksum,
+	// length and quartile ratios followed by two bits per bucket.
+	lshDigestSize = 3 + lshBuckets/4
+)
+
+// lshTable is the Pearson table used to map byte triplets to buckets; a fixed
+// permutation of 0-255, generated by a Fisher-Yates shuffle driven by a linear
+// congruential generator. The table must never change, as that would change
+// the hash of existing files.
+var lshTable = func() (t [256]byte) {
+	for i := range t {
+		t[i] = byte(i)
+	}
+	seed := uint32(1)
+	for i := len(t) - 1; i > 0; i-- {
+		seed = seed*1103515245 + 12345
+		j := int(seed>>16) % (i + 1)
+		t[i], t[j] = t[j], t[i]
+	}
+	return t
+}()
+
+// lshPearson returns the Pearson hash of the given salt and byte triplet.
+func lshPearson(salt, a, b, c byte) byte {
+	h := lshTable[salt]
+	h = lshTable[h^a]
+	h = lshTable[h^b]
+	return lshTable[h^c]
+}
+
+// lshWriter computes the locality-sensitive hash of the data written to it.
+type lshWriter struct {
+	// Sliding window; a ring buffer of the most recent bytes.
+	window [lshWindowSize]byte
+	// Number of bytes written.
+	n int64
+	// Checksum of the data.
+	checksum byte
+	// Bucket counts.
+	buckets [256]uint32
+}
+
+// Write adds the contents of p to the hash.
+func (l *lshWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		l.window[l.n%lshWindowSize] = b
+		l.n++
+		if l.n < lshWindowSize {
+			continue
+		}
+		// w0 is the most recent byte and w4 the oldest.
+		w0 := b
+		w1 := l.window[(l.n-2)%lshWindowSize]
+		w2 := l.window[(l.n-3)%lshWindowSize]
+		w3 := l.window[(l.n-4)%lshWindowSize]
+		w4 := l.window[(l.n-5)%lshWindowSize]
+		l.checksum = lshPearson(0, w0, w1, l.checksum)
+		l.buckets[lshPearson(2, w0, w1, w2)]++
+		l.buckets[lshPearson(3, w0, w1, w3)]++
+		l.buckets[lshPearson(5, w0, w2, w3)]++
+		l.buckets[lshPearson(7, w0, w2, w4)]++
+		l.buckets[lshPearson(11, w0, w1, w4)]++
+		l.buckets[lshPearson(13, w0, w3, w4)]++
+	}
+	return len(p), nil
+}
+
+// digest returns the locality-sensitive hash of the data written so far, as a
+// lowercase hex string; or an empty string if the data is too small, or too
+// uniform for at least half of the buckets to be used.
+func (l *lshWriter) digest() string {
+	if l.n < lshMinSize {
+		return ""
+	}
+	counts := l.buckets[:lshBuckets]
+	sorted := make([]uint32, lshBuckets)
+	copy(sorted, counts)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	q1, q2, q3 := sorted[lshBuckets/4-1], sorted[lshBuckets/2-1], sorted[lshBuckets*3/4-1]
+	nonzero := 0
+	for _, count := range counts {
+		if count > 0 {
+			nonzero++
+		}
+	}
+	if q3 == 0 || nonzero <= lshBuckets/2 {
+		return ""
+	}
+
+	var buf [lshDigestSize]byte
+	buf[0] = l.checksum
+	buf[1] = lshLength(l.n)
+	buf[2] = byte(uint64(q1)*100/uint64(q3)%16)<<4 | byte(uint64(q2)*100/uint64(q3)%16)
+	for i, count := range counts {
+		var code byte
+		switch {
+		case count <= q1:
+			code = 0
+		case count <= q2:
+			code = 1
+		case count <= q3:
+			code = 2
+		default:
+			code = 3
+		}
+		buf[3+i/4] |= code << (uint(i%4) * 2)
+	}
+	return hex.EncodeToString(buf[:])
+}
+
+// lshLength returns the logarithmic encoding of the given data length.
+func lshLength(n int64) byte {
+	x := float64(n)
+	var l float64
+	switch {
+	case n <= 656:
+		l = math.Log(x) / math.Log(1.5)
+	case n <= 3199:
+		l = math.Log(x)/math.Log(1.3) - 8.72777
+	default:
+		l = math.Log(x)/math.Log(1.1) - 62.5472
+	}
+	return byte(int(math.Floor(l)))
+}
+
+// LSHDistance returns the distance between the given locality-sensitive hashes
+// (see Hashes.LSH); zero for identical data, with larger values for less
+// similar data. Distances below 100 typically indicate closely related files.
+func LSHDistance(a, b string) (int, error) {
+	x, err := decodeLSH(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := decodeLSH(b)
+	if err != nil {
+		return 0, err
+	}
+	dist := 0
+	// Header.
+	if x[0] != y[0] {
+		dist++
+	}
+	switch d := modDiff(int(x[1]), int(y[1]), 256); {
+	case d <= 1:
+		dist += d
+	default:
+		dist += d * 12
+	}
+	for _, shift := range []uint{4, 0} {
+		switch d := modDiff(int(x[2]>>shift&0xF), int(y[2]>>shift&0xF), 16); {
+		case d <= 1:
+			dist += d
+		default:
+			dist += (d - 1) * 12
+		}
+	}
+	// Body; bucket quartiles which differ by more than one are penalized.
+	for i := 3; i < lshDigestSize; i++ {
+		for shift := uint(0); shift < 8; shift += 2 {
+			d := int(x[i]>>shift&3) - int(y[i]>>shift&3)
+			switch {
+			case d == 3 || d == -3:
+				dist += 6
+			case d < 0:
+				dist -= d
+			default:
+				dist += d
+			}
+		}
+	}
+	return dist, nil
+}
+
+// decodeLSH decodes the given locality-sensitive hash.
+func decodeLSH(s string) ([]byte, error) {
+	buf, err := hex.DecodeString(s)
+	if err != nil || len(buf) != lshDigestSize {
+		return nil, fmt.Errorf("pe: invalid locality-sensitive hash %q", s)
+	}
+	return buf, nil
+}
+
+// modDiff returns the distance between x and y on a circle of size r.
+func modDiff(x, y, r int) int {
+	d := x - y
+	if d < 0 {
+		d = -d
+	}
+	if r-d < d {
+		return r - d
+	}
+	return d
+}
@@ -0,0 +1,72 @@
+package pe
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// lshDigest returns the locality-sensitive hash of data.
+func lshDigest(data []byte) string {
+	l := new(lshWriter)
+	l.Write(data)
+	return l.digest()
+}
+
+func TestLSHDistance(t *testing.T) {
+	// Text-like data, with a skewed byte distribution.
+	rnd := rand.New(rand.NewSource(1))
+	data := make([]byte, 8192)
+	for i := range data {
+		data[i] = byte('a' + rnd.Intn(16))
+	}
+	orig := lshDigest(data)
+	if len(orig) != 2*lshDigestSize {
+		t.Fatalf("digest length mismatch; expected %d, got %q", 2*lshDigestSize, orig)
+	}
+
+	// Writing the data in pieces yields the same digest.
+	l := new(lshWriter)
+	for i := 0; i < len(data); i += 1000 {
+		end := i + 1000
+		if end > len(data) {
+			end = len(data)
+		}
+		l.Write(data[i:end])
+	}
+	if got := l.digest(); got != orig {
+		t.Errorf("chunked digest mismatch; expected %s, got %s", orig, got)
+	}
+
+	// Small modifications yield a small distance.
+	modified := append([]byte(nil), data...)
+	copy(modified[4000:], "modified")
+	near, err := LSHDistance(orig, lshDigest(modified))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unrelated data yields a large distance.
+	other := make([]byte, 8192)
+	rnd.Read(other)
+	far, err := LSHDistance(orig, lshDigest(other))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := LSHDistance(orig, orig); err != nil || same != 0 {
+		t.Errorf("distance to self mismatch; expected 0, got %d (%v)", same, err)
+	}
+	if near >= far || near > 50 {
+		t.Errorf("distance mismatch; expected near (%d) < far (%d) and near <= 50", near, far)
+	}
+
+	// Small and uniform data are not hashed.
+	if got := lshDigest(data[:lshMinSize-1]); len(got) != 0 {
+		t.Errorf("unexpected digest of small data; got %q", got)
+	}
+	if got := lshDigest(make([]byte, 4096)); len(got) != 0 {
+		t.Errorf("unexpected digest of uniform data; got %q", got)
+	}
+
+	if _, err := LSHDistance(orig, "abc"); err == nil {
+		t.Errorf("expected error for invalid digest")
+	}
+}
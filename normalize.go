@@ -0,0 +1,322 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// NormalizedField represents a nondeterministic field of a PE file, which is
+// zeroed by Normalize.
+type NormalizedField struct {
+	// Name of the field (e.g. "FileHeader.Created", "CodeView.GUID").
+	Name string
+	// File offset of the field.
+	Offset int64
+	// Size of the field in bytes.
+	Size int64
+}
+
+// Normalize writes a copy of file to w with the nondeterministic fields zeroed,
+// so that two reproducible builds of the same code may be compared
+// byte-for-byte, and returns the zeroed fields ordered by offset.
+//
+// The zeroed fields are the timestamps of the file header, export directory,
+// resource directories, load configuration directory and debug directory
+// entries; the checksum of the optional header; the GUID, age and timestamp of
+// CodeView debug information; and the hash of Repro debug information. In
+// tolerant mode, malformed structures are skipped and recorded as anomalies.
+func (file *File) Normalize(w io.Writer) ([]*NormalizedField, error) {
+	fields, err := file.normalizedFields()
+	if err != nil {
+		return nil, err
+	}
+	size, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := copyNormalized(w, file.r, 0, size, fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// copyNormalized writes the size bytes at off of r to w, replacing the
+// contents of the given fields, ordered by offset, with zeros.
+func copyNormalized(w io.Writer, r io.ReaderAt, off, size int64, fields []*NormalizedField) error {
+	sr := io.NewSectionReader(r, off, size)
+	var pos int64
+	for _, field := range fields {
+		// Clip the field to the copied range.
+		start, end := field.Offset-off, field.Offset+field.Size-off
+		if end <= pos || start >= size {
+			continue
+		}
+		if start < pos {
+			start = pos
+		}
+		if end > size {
+			end = size
+		}
+		if _, err := io.CopyN(w, sr, start-pos); err != nil {
+			return readError("file contents", off+pos, err)
+		}
+		if _, err := w.Write(make([]byte, end-start)); err != nil {
+			return err
+		}
+		if _, err := sr.Seek(end, io.SeekStart); err != nil {
+			return err
+		}
+		pos = end
+	}
+	if _, err := io.Copy(w, sr); err != nil {
+		return readError("file contents", off+pos, err)
+	}
+	return nil
+}
+
+// normalizer locates the nondeterministic fields of a file.
+type normalizer struct {
+	// PE file.
+	file *File
+	// Size of the file.
+	size int64
+	// Nondeterministic fields located so far.
+	fields []*NormalizedField
+}
+
+// add records the field of the given size at off; fields extending past the
+// end of the file are ignored.
+func (n *normalizer) add(name string, off, size int64) {
+	if off < 0 || off+size > n.size {
+		return
+	}
+	n.fields = append(n.fields, &NormalizedField{Name: name, Offset: off, Size: size})
+}
+
+// addRelAddr records the field of the given size at the given address,
+// relative to the image base.
+func (n *normalizer) addRelAddr(name string, relAddr uint32, size int64) error {
+	off, avail, err := n.file.relAddrToOffset(relAddr)
+	if err != nil {
+		return &FormatError{Struct: name, Offset: -1, Err: err}
+	}
+	if avail < size {
+		return formatError(name, off, "field of %d bytes exceeds section bounds (%d)", size, avail)
+	}
+	n.add(name, off, size)
+	return nil
+}
+
+// normalizedFields returns the nondeterministic fields of file, ordered by
+// offset, with overlapping fields merged.
+func (file *File) normalizedFields() ([]*NormalizedField, error) {
+	size, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	n := &normalizer{file: file, size: size}
+	if err := n.headers(); err != nil {
+		return nil, err
+	}
+	obj, err := file.IsObject()
+	if err != nil {
+		return nil, err
+	}
+	if !obj {
+		// Optional structures; in tolerant mode, malformed structures are
+		// skipped.
+		steps := []func() error{
+			n.exports,
+			n.resources,
+			n.loadConfig,
+			n.debugDirs,
+		}
+		for _, step := range steps {
+			if err := step(); err != nil && !file.tolerate(err) {
+				return nil, err
+			}
+		}
+	}
+
+	sort.Slice(n.fields, func(i, j int) bool {
+		return n.fields[i].Offset < n.fields[j].Offset
+	})
+	// Structures may overlap in malformed files; merge overlapping fields.
+	var fields []*NormalizedField
+	for _, field := range n.fields {
+		if len(fields) > 0 {
+			prev := fields[len(fields)-1]
+			if field.Offset < prev.Offset+prev.Size {
+				if end := field.Offset + field.Size; end > prev.Offset+prev.Size {
+					prev.Size = end - prev.Offset
+				}
+				continue
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// headers records the timestamp of the file header and the checksum of the
+// optional header.
+func (n *normalizer) headers() error {
+	fileHdr, err := n.file.FileHeader()
+	if err != nil {
+		return err
+	}
+	n.add("FileHeader.Created", n.file.coffHdrOff+4, 4)
+	// The checksum is located at the same offset of the 32-bit and 64-bit
+	// optional headers.
+	const checksumOff = 64
+	if fileHdr.OptHdrSize >= checksumOff+4 {
+		n.add("OptHeader.Checksum", n.file.coffHdrOff+coffHdrSize+checksumOff, 4)
+	}
+	return nil
+}
+
+// exports records the timestamp of the export directory.
+func (n *normalizer) exports() error {
+	dataDir, err := n.file.dataDir(DataDirExportTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 {
+		return nil
+	}
+	return n.addRelAddr("ExportDirectory.Created", dataDir.RelAddr+4, 4)
+}
+
+// resources records the timestamps of the resource directories.
+func (n *normalizer) resources() error {
+	root, err := n.file.Resources()
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return nil
+	}
+	nodes := []*ResourceNode{root}
+	root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+		if node.Dir != nil {
+			nodes = append(nodes, node)
+		}
+	})
+	for _, node := range nodes {
+		if err := n.addRelAddr("ResourceDirectory.Created", node.dirRelAddr+4, 4); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadConfig records the timestamp of the load configuration directory.
+func (n *normalizer) loadConfig() error {
+	dataDir, err := n.file.dataDir(DataDirLoadConfigTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 {
+		return nil
+	}
+	return n.addRelAddr("LoadConfig.Created", dataDir.RelAddr+4, 4)
+}
+
+// debugDirs records the timestamps of the debug directory entries, and the
+// nondeterministic fields of CodeView and Repro debug information.
+func (n *normalizer) debugDirs() error {
+	dirs, err := n.file.DebugDirs()
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	dataDir, err := n.file.dataDir(DataDirDebug)
+	if err != nil {
+		return err
+	}
+	for i, dir := range dirs {
+		if err := n.addRelAddr("ImageDebugDirectory.TimeDateStamp", dataDir.RelAddr+uint32(i)*debugDirSize+4, 4); err != nil {
+			return err
+		}
+		switch dir.Type {
+		case ImageDebugTypeCodeView:
+			if err := n.codeView(dir); err != nil {
+				return err
+			}
+		case ImageDebugTypeRepro:
+			if err := n.repro(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// debugData returns the file offset and contents of the debugging information
+// of the given debug directory entry, limited to max bytes.
+func (n *normalizer) debugData(dir *ImageDebugDirectory, max int64) (int64, []byte, error) {
+	off := int64(dir.PointerToRawData)
+	if dir.AddressOfRawData != 0 {
+		relOff, _, err := n.file.relAddrToOffset(dir.AddressOfRawData)
+		if err != nil {
+			return 0, nil, &FormatError{Struct: "debugging information", Offset: -1, Err: err}
+		}
+		off = relOff
+	} else if n.file.conf.layout == LayoutMapped {
+		// Debugging information not mapped into memory.
+		return 0, nil, nil
+	}
+	size := int64(dir.SizeOfData)
+	if size > max {
+		size = max
+	}
+	if off+size > n.size {
+		return 0, nil, formatError("debugging information", off, "size (%d) exceeds file size", size)
+	}
+	buf := make([]byte, size)
+	if _, err := n.file.r.ReadAt(buf, off); err != nil {
+		return 0, nil, readError("debugging information", off, err)
+	}
+	return off, buf, nil
+}
+
+// codeView records the GUID, age and timestamp of CodeView debug information;
+// in the PDB 7.0 ("RSDS") and PDB 2.0 ("NB10") formats.
+func (n *normalizer) codeView(dir *ImageDebugDirectory) error {
+	off, data, err := n.debugData(dir, 24)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(data) >= 24 && bytes.HasPrefix(data, []byte("RSDS")):
+		n.add("CodeView.GUID", off+4, 16)
+		n.add("CodeView.Age", off+20, 4)
+	case len(data) >= 16 && bytes.HasPrefix(data, []byte("NB10")):
+		n.add("CodeView.Created", off+8, 4)
+		n.add("CodeView.Age", off+12, 4)
+	}
+	return nil
+}
+
+// repro records the hash of Repro debug information, which is stored as its
+// size followed by the hash.
+func (n *normalizer) repro(dir *ImageDebugDirectory) error {
+	off, data, err := n.debugData(dir, 4)
+	if err != nil {
+		return err
+	}
+	if len(data) < 4 {
+		return nil
+	}
+	hashSize := int64(binary.LittleEndian.Uint32(data))
+	if hashSize > int64(dir.SizeOfData)-4 {
+		return formatError("Repro debugging information", off, "hash size (%d) exceeds data size (%d)", hashSize, dir.SizeOfData)
+	}
+	n.add("Repro.Hash", off+4, hashSize)
+	return nil
+}
@@ -0,0 +1,105 @@
+package pe
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	a := synthReproImage(0x11111111, 0x1234, 0xAA, 0xBB)
+	b := synthReproImage(0x22222222, 0x5678, 0xCC, 0xDD)
+	var outs [2][]byte
+	for i, data := range [][]byte{a, b} {
+		file, err := New(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		fields, err := file.Normalize(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, field := range fields {
+			names = append(names, field.Name)
+		}
+		want := []string{
+			"FileHeader.Created",
+			"OptHeader.Checksum",
+			"ImageDebugDirectory.TimeDateStamp",
+			"ImageDebugDirectory.TimeDateStamp",
+			"ExportDirectory.Created",
+			"ResourceDirectory.Created",
+			"ResourceDirectory.Created",
+			"ResourceDirectory.Created",
+			"CodeView.GUID",
+			"CodeView.Age",
+			"Repro.Hash",
+		}
+		if len(names) != len(want) {
+			t.Fatalf("expected %d normalized fields, got %d: %q", len(want), len(names), names)
+		}
+		for j := range names {
+			if names[j] != want[j] {
+				t.Errorf("field %d mismatch; expected %q, got %q", j, want[j], names[j])
+			}
+		}
+		if buf.Len() != len(data) {
+			t.Errorf("size mismatch; expected %d, got %d", len(data), buf.Len())
+		}
+		outs[i] = buf.Bytes()
+	}
+	if bytes.Equal(a, b) {
+		t.Fatal("expected builds to differ before normalization")
+	}
+	if !bytes.Equal(outs[0], outs[1]) {
+		for i := range outs[0] {
+			if outs[0][i] != outs[1][i] {
+				t.Fatalf("normalized builds differ at offset 0x%X", i)
+			}
+		}
+	}
+
+	// The normalized copy parses, with zeroed fields.
+	file, err := New(bytes.NewReader(outs[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileHdr.Created != 0 {
+		t.Errorf("expected zero timestamp, got %v", fileHdr.Created)
+	}
+	dirs, err := file.DebugDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 || dirs[1].Type != ImageDebugTypeRepro || dirs[1].TimeDateStamp != 0 {
+		t.Errorf("unexpected debug directory of normalized copy; got %v", dirs)
+	}
+}
+
+// TestNormalizeResourceLoop checks that Normalize and Resources agree on a
+// malformed resource tree.
+func TestNormalizeResourceLoop(t *testing.T) {
+	b := synthBuffer(synthImage{}.bytes())
+	// Point the first entry of the root directory back to the root.
+	b.put32(sectOff(synthResourceRelAddr)+16+4, 0x80000000)
+	for _, tolerant := range []bool{false, true} {
+		file, err := New(bytes.NewReader(b), WithTolerant(tolerant))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, errRsrc := file.Resources()
+		_, errNorm := file.Normalize(io.Discard)
+		if (errRsrc == nil) != (errNorm == nil) {
+			t.Errorf("tolerant=%v: error mismatch; Resources %v, Normalize %v", tolerant, errRsrc, errNorm)
+		}
+		if !tolerant && errNorm == nil {
+			t.Errorf("expected error of resource loop")
+		}
+	}
+}
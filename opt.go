@@ -1,19 +1,39 @@
 package pe
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
-	"log"
 	"strings"
 )
 
 // Maximum optional header size, which includes 16 data directories.
-const maxOptHdrSize = 224
+const maxOptHdrSize = 240
 
-// OptHeader represents an optional header.
+// Optional header sizes, excluding data directories.
+const (
+	optHdr32Size = 96
+	optHdr64Size = 112
+)
+
+// Maximum number of data directories.
+const maxDataDirs = 16
+
+// OptHeader represents an optional header. The optional header of 64-bit
+// images (PE32+) is converted to the layout of 32-bit images, with the full
+// 64-bit values stored in ImageBase64 and the Size64 fields.
 type OptHeader struct {
 	OptHeader32
+	// The base address of the image; holds the full 64-bit value of PE32+
+	// images, and the zero-extended ImageBase of PE32 images.
+	ImageBase64 uint64
+	// The stack and heap sizes of the image; holds the full 64-bit values of
+	// PE32+ images, and the zero-extended values of PE32 images.
+	ReserveStackSize64 uint64
+	InitStackSize64    uint64
+	ReserveHeapSize64  uint64
+	InitHeapSize64     uint64
 	// Data directories contains the location and size of various data
 	// structures. The following is a list of data directories as specified by
 	// index.
@@ -135,6 +155,81 @@ type OptHeader32 struct {
 	NDataDir uint32
 }
 
+// OptHeader64 represents a 64-bit optional header (PE32+).
+type OptHeader64 struct {
+	// The state of the image file.
+	State OptState
+	// Major linker version.
+	MajorLinkVer uint8
+	// Minor linker version.
+	MinorLinkVer uint8
+	// Size of the code section in bytes, or the sum of all such sections if
+	// there are multiple code sections.
+	CodeSize uint32
+	// Size of the data section in bytes, or the sum of all such sections if
+	// there are multiple data sections.
+	DataSize uint32
+	// Size of the uninitialized data section in bytes, or the sum of all such
+	// sections if there are multiple uninitialized data sections.
+	BSSSize uint32
+	// Pointer to the entry point function, relative to the image base.
+	EntryRelAddr uint32
+	// Pointer to the beginning of the code section, relative to the image base.
+	CodeBase uint32
+	// The base address is the starting-address of a memory-mapped EXE or DLL.
+	// The default value for DLLs is 0x180000000 and the default value for
+	// applications is 0x140000000.
+	ImageBase uint64
+	// The virtual address of each section is aligned to a multiple of this
+	// value. The default section alignment is the page size of the system.
+	SectAlign uint32
+	// The file offset of each section is aligned to a multiple of this value.
+	// The default file alignment is 512.
+	FileAlign uint32
+	// Major operating system version.
+	MajorOSVer uint16
+	// Minor operating system version.
+	MinorOSVer uint16
+	// Major image version.
+	MajorImageVer uint16
+	// Minor image version.
+	MinorImageVer uint16
+	// Major subsystem version.
+	MajorSubsystemVer uint16
+	// Minor subsystem version.
+	MinorSubsystemVer uint16
+	// Reserved.
+	Res uint32
+	// Size of the image, in bytes, including all headers. Must be a multiple of
+	// SectAlign.
+	ImageSize uint32
+	// The combined size of all headers, rounded to a multiple of FileAlign.
+	HdrSize uint32
+	// The checksum is an additive checksum of the file.
+	Checksum uint32
+	// The subsystem required to run an image.
+	Subsystem Subsystem
+	// A bitfield which specifies the DLL characteristics of the image.
+	Flags DLLFlag
+	// The number of bytes to reserve for the stack.
+	ReserveStackSize uint64
+	// The size of the stack at load time.
+	InitStackSize uint64
+	// The number of bytes to reserve for the heap.
+	ReserveHeapSize uint64
+	// The size of the heap at load time.
+	InitHeapSize uint64
+	// Obsolete.
+	LoaderFlags uint32
+	// Number of data directories.
+	NDataDir uint32
+}
+
+// Is64 reports whether the optional header is of a 64-bit image (PE32+).
+func (opthdr *OptHeader) Is64() bool {
+	return opthdr.State == OptState64
+}
+
 // OptState specifies the state of the image file.
 type OptState uint16
 
@@ -294,7 +389,7 @@ type DataDirectory struct {
 // OptHeader returns the optional header of file.
 func (file *File) OptHeader() (opthdr *OptHeader, err error) {
 	if file.opthdr == nil {
-		err = file.parseOptHeader()
+		err = file.parseStruct(file.parseOptHeader)
 		if err != nil {
 			return nil, err
 		}
@@ -305,33 +400,119 @@ func (file *File) OptHeader() (opthdr *OptHeader, err error) {
 
 // parseOptHeader parses the optional header of file.
 func (file *File) parseOptHeader() error {
-	doshdr, err := file.DOSHeader()
+	optoff, err := file.optHdrOffset()
+	if err != nil {
+		return err
+	}
+	fileHdr, err := file.FileHeader()
 	if err != nil {
 		return err
 	}
-	optoff := int64(doshdr.PEHdrOffset) + fileHdrSize
-	sr := io.NewSectionReader(file.r, optoff, maxOptHdrSize)
 
-	// Parse optional header.
-	file.opthdr = new(OptHeader)
-	opthdr := file.opthdr
-	err = binary.Read(sr, binary.LittleEndian, &opthdr.OptHeader32)
+	// Read the optional header, as specified by the optional header size of the
+	// file header. Fields not covered by the optional header size are zero.
+	size := int64(fileHdr.OptHdrSize)
+	buf, err := file.readHeader("optional header", optoff, size)
 	if err != nil {
-		return fmt.Errorf("pe.File.parseOptHeader: unable to read optional header; %v", err)
+		return err
+	}
+	if len(buf) < maxOptHdrSize {
+		buf = append(buf, make([]byte, maxOptHdrSize-len(buf))...)
+	}
+	state := OptState(binary.LittleEndian.Uint16(buf))
+	dataDirsOff := int64(optHdr32Size)
+	if state == OptState64 {
+		dataDirsOff = optHdr64Size
+	}
+	if size < dataDirsOff {
+		if !file.conf.tolerant {
+			return formatError("optional header", optoff, "size %d smaller than the %d bytes required by state %v", size, dataDirsOff, state)
+		}
+		file.addAnomaly(AnomalyOptHdrSize, SeverityError, optoff, "size %d smaller than the %d bytes required by state %v; zero-filled", size, dataDirsOff, state)
+	}
+	sr := bytes.NewReader(buf)
+
+	// Parse optional header.
+	opthdr := new(OptHeader)
+	if state == OptState64 {
+		var opthdr64 OptHeader64
+		binary.Read(sr, binary.LittleEndian, &opthdr64)
+		opthdr.OptHeader32 = OptHeader32{
+			State:             opthdr64.State,
+			MajorLinkVer:      opthdr64.MajorLinkVer,
+			MinorLinkVer:      opthdr64.MinorLinkVer,
+			CodeSize:          opthdr64.CodeSize,
+			DataSize:          opthdr64.DataSize,
+			BSSSize:           opthdr64.BSSSize,
+			EntryRelAddr:      opthdr64.EntryRelAddr,
+			CodeBase:          opthdr64.CodeBase,
+			ImageBase:         uint32(opthdr64.ImageBase),
+			SectAlign:         opthdr64.SectAlign,
+			FileAlign:         opthdr64.FileAlign,
+			MajorOSVer:        opthdr64.MajorOSVer,
+			MinorOSVer:        opthdr64.MinorOSVer,
+			MajorImageVer:     opthdr64.MajorImageVer,
+			MinorImageVer:     opthdr64.MinorImageVer,
+			MajorSubsystemVer: opthdr64.MajorSubsystemVer,
+			MinorSubsystemVer: opthdr64.MinorSubsystemVer,
+			Res:               opthdr64.Res,
+			ImageSize:         opthdr64.ImageSize,
+			HdrSize:           opthdr64.HdrSize,
+			Checksum:          opthdr64.Checksum,
+			Subsystem:         opthdr64.Subsystem,
+			Flags:             opthdr64.Flags,
+			ReserveStackSize:  uint32(opthdr64.ReserveStackSize),
+			InitStackSize:     uint32(opthdr64.InitStackSize),
+			ReserveHeapSize:   uint32(opthdr64.ReserveHeapSize),
+			InitHeapSize:      uint32(opthdr64.InitHeapSize),
+			LoaderFlags:       opthdr64.LoaderFlags,
+			NDataDir:          opthdr64.NDataDir,
+		}
+		opthdr.ImageBase64 = opthdr64.ImageBase
+		opthdr.ReserveStackSize64 = opthdr64.ReserveStackSize
+		opthdr.InitStackSize64 = opthdr64.InitStackSize
+		opthdr.ReserveHeapSize64 = opthdr64.ReserveHeapSize
+		opthdr.InitHeapSize64 = opthdr64.InitHeapSize
+	} else {
+		binary.Read(sr, binary.LittleEndian, &opthdr.OptHeader32)
+		opthdr.ImageBase64 = uint64(opthdr.ImageBase)
+		opthdr.ReserveStackSize64 = uint64(opthdr.ReserveStackSize)
+		opthdr.InitStackSize64 = uint64(opthdr.InitStackSize)
+		opthdr.ReserveHeapSize64 = uint64(opthdr.ReserveHeapSize)
+		opthdr.InitHeapSize64 = uint64(opthdr.InitHeapSize)
 	}
 
 	// Verify that the reserved field is zero.
 	if opthdr.Res != 0 {
-		log.Printf("pe.File.parseOptHeader: invalid reserved field; expected 0, got %d.\n", opthdr.Res)
+		const resOff = 52
+		file.addAnomaly(AnomalyOptReserved, SeverityWarning, optoff+resOff, "reserved field; expected 0, got 0x%08X", opthdr.Res)
 	}
 
-	// Parse data directories.
+	// Parse data directories. The Windows loader ignores data directories past
+	// the first 16.
 	// TODO(u): Ignore void/zero data directories (using a for loop).
-	opthdr.DataDirs = make([]DataDirectory, opthdr.NDataDir)
-	err = binary.Read(sr, binary.LittleEndian, &opthdr.DataDirs)
-	if err != nil {
-		return fmt.Errorf("pe.File.parseOptHeader: unable to read data directories; %v", err)
+	n := int64(opthdr.NDataDir)
+	if n > maxDataDirs {
+		if !file.conf.tolerant {
+			return formatError("optional header", optoff, "number of data directories %d exceeds %d", n, maxDataDirs)
+		}
+		file.addAnomaly(AnomalyNDataDir, SeverityWarning, optoff+dataDirsOff-4, "number of data directories %d exceeds %d; clamped", n, maxDataDirs)
+		n = maxDataDirs
+	}
+	if avail := (size - dataDirsOff) / 8; n > avail {
+		if avail < 0 {
+			avail = 0
+		}
+		if !file.conf.tolerant {
+			return formatError("data directories", optoff+dataDirsOff, "%d data directories exceed optional header size %d", n, size)
+		}
+		file.addAnomaly(AnomalyOptHdrSize, SeverityWarning, optoff+dataDirsOff, "%d data directories exceed optional header size %d; clamped to %d", n, size, avail)
+		n = avail
 	}
+	sr.Seek(dataDirsOff, io.SeekStart)
+	opthdr.DataDirs = make([]DataDirectory, n)
+	binary.Read(sr, binary.LittleEndian, &opthdr.DataDirs)
 
+	file.opthdr = opthdr
 	return nil
 }
@@ -0,0 +1,115 @@
+package pe
+
+import (
+	"strings"
+	"sync"
+)
+
+// OrdinalDB is a database of ordinal-to-name tables keyed by DLL name, used to
+// attach symbolic names to functions imported by ordinal. It is safe for
+// concurrent use.
+type OrdinalDB struct {
+	mu sync.RWMutex
+	// Ordinal-to-name tables; keyed by normalized DLL name.
+	tables map[string]map[uint16]string
+}
+
+// DefaultOrdinalDB is the ordinal database used unless overridden by
+// WithOrdinalDB. It is initialized with embedded tables of ws2_32.dll,
+// wsock32.dll, oleaut32.dll and comctl32.dll.
+var DefaultOrdinalDB = newDefaultOrdinalDB()
+
+// newDefaultOrdinalDB returns a new ordinal database holding the embedded
+// ordinal tables.
+func newDefaultOrdinalDB() *OrdinalDB {
+	db := NewOrdinalDB()
+	db.Register("ws2_32.dll", ws2_32Ordinals)
+	db.Register("wsock32.dll", wsock32Ordinals)
+	db.Register("oleaut32.dll", oleaut32Ordinals)
+	db.Register("comctl32.dll", comctl32Ordinals)
+	return db
+}
+
+// NewOrdinalDB returns a new, empty ordinal database.
+func NewOrdinalDB() *OrdinalDB {
+	return &OrdinalDB{tables: make(map[string]map[uint16]string)}
+}
+
+// WithOrdinalDB returns an option which sets the ordinal database used to name
+// functions imported by ordinal. A nil database disables naming.
+func WithOrdinalDB(db *OrdinalDB) Option {
+	return func(conf *config) {
+		conf.ordinals = db
+	}
+}
+
+// Register adds the ordinal-to-name table of the given DLL to the database.
+// Entries of previously registered tables of the DLL are kept unless
+// overridden. DLL names are case-insensitive, and the ".dll" extension is
+// implied if none is present.
+func (db *OrdinalDB) Register(dll string, names map[uint16]string) {
+	key := ordinalKey(dll)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	table, ok := db.tables[key]
+	if !ok {
+		table = make(map[uint16]string, len(names))
+		db.tables[key] = table
+	}
+	for ordinal, name := range names {
+		table[ordinal] = name
+	}
+}
+
+// RegisterExports adds the named exports of the given DLL to the database,
+// under the DLL name of its export directory. Functions exported by ordinal
+// only are skipped.
+func (db *OrdinalDB) RegisterExports(file *File) error {
+	exps, err := file.Exports()
+	if err != nil {
+		return err
+	}
+	if exps == nil || len(exps.DLL) == 0 {
+		return nil
+	}
+	names := make(map[uint16]string)
+	for _, fn := range exps.Funcs {
+		if len(fn.Name) > 0 && fn.Ordinal <= 0xFFFF {
+			names[uint16(fn.Ordinal)] = fn.Name
+		}
+	}
+	db.Register(exps.DLL, names)
+	return nil
+}
+
+// RegisterExportsFile adds the named exports of the DLL at path to the
+// database.
+func (db *OrdinalDB) RegisterExportsFile(path string) error {
+	file, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return db.RegisterExports(file)
+}
+
+// Lookup returns the name of the function exported by the given DLL with the
+// given ordinal, and reports whether it was found.
+func (db *OrdinalDB) Lookup(dll string, ordinal uint16) (string, bool) {
+	key := ordinalKey(dll)
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	name, ok := db.tables[key][ordinal]
+	return name, ok
+}
+
+// ordinalKey returns the normalized DLL name used as key of the ordinal
+// database; the lowercase name with ".dll" appended if no extension is
+// present.
+func ordinalKey(dll string) string {
+	key := strings.ToLower(dll)
+	if !strings.Contains(key, ".") {
+		key += ".dll"
+	}
+	return key
+}
@@ -0,0 +1,80 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOrdinalDB(t *testing.T) {
+	// Embedded tables.
+	golden := []struct {
+		dll     string
+		ordinal uint16
+		name    string
+		ok      bool
+	}{
+		{dll: "WS2_32.dll", ordinal: 115, name: "WSAStartup", ok: true},
+		{dll: "ws2_32", ordinal: 23, name: "socket", ok: true},
+		{dll: "OLEAUT32.DLL", ordinal: 2, name: "SysAllocString", ok: true},
+		{dll: "comctl32.dll", ordinal: 17, name: "InitCommonControls", ok: true},
+		{dll: "comctl32.dll", ordinal: 1, ok: false},
+		{dll: "kernel32.dll", ordinal: 1, ok: false},
+	}
+	for _, g := range golden {
+		name, ok := DefaultOrdinalDB.Lookup(g.dll, g.ordinal)
+		if name != g.name || ok != g.ok {
+			t.Errorf("%s #%d: lookup mismatch; expected %q (%v), got %q (%v)", g.dll, g.ordinal, g.name, g.ok, name, ok)
+		}
+	}
+
+	// Tables registered from exports.
+	db := NewOrdinalDB()
+	dll, err := New(bytes.NewReader(synthImage{}.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RegisterExports(dll); err != nil {
+		t.Fatal(err)
+	}
+	if name, ok := db.Lookup("SYNTH.DLL", 1); !ok || name != "Foo" {
+		t.Errorf("lookup of registered export mismatch; expected %q, got %q (%v)", "Foo", name, ok)
+	}
+	if _, ok := db.Lookup("synth.dll", 2); ok {
+		t.Errorf("unexpected name of export by ordinal only")
+	}
+}
+
+func TestOrdinalImports(t *testing.T) {
+	// Import ordinal 7 of kernel32.dll, named through a custom database.
+	db := NewOrdinalDB()
+	db.Register("KERNEL32", map[uint16]string{7: "Seven"})
+	for _, tc := range []struct {
+		db   *OrdinalDB
+		name string
+		str  string
+	}{
+		{db: db, name: "Seven", str: "Seven (#7)"},
+		{db: nil, name: "", str: "#7"},
+	} {
+		file, err := New(bytes.NewReader(synthImage{}.bytes()), WithOrdinalDB(tc.db))
+		if err != nil {
+			t.Fatal(err)
+		}
+		imps, err := file.Imports()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn := imps[0].Funcs[1]
+		if !fn.ByOrdinal || fn.Name != tc.name || fn.String() != tc.str {
+			t.Errorf("ordinal import mismatch; expected %q, got %q (%q)", tc.name, fn.Name, fn.String())
+		}
+		// The import hash is independent of the ordinal database.
+		impHash, err := file.ImpHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "69052387c5b38d0b9c6f3f9fc76cddf4"; impHash != want {
+			t.Errorf("import hash mismatch; expected %q, got %q", want, impHash)
+		}
+	}
+}
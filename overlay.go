@@ -1,46 +1,419 @@
 package pe
 
 import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
 	"io"
-
-	"github.com/pkg/errors"
+	"sort"
 )
 
 // Overlay returns the overlay of the PE fil (i.e. any optional bytes directly
-// succeeding the image).
+// succeeding the image). The certificate table is not part of the overlay;
+// data appended after a certificate table which follows the overlay is
+// accessible through OverlayItems.
+//
+// The overlay is read into memory on each call; use OverlayReader to process
+// large overlays with bounded memory.
 func (file *File) Overlay() ([]byte, error) {
-	if file.overlay == nil {
-		if err := file.parseOverlay(); err != nil {
-			return nil, err
+	off, size, err := file.overlayExtent()
+	if err != nil {
+		return nil, err
+	}
+	if err := file.checkAlloc("overlay", size); err != nil {
+		return nil, err
+	}
+	overlay := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(file.r, off, size), overlay); err != nil {
+		return nil, readError("overlay", off, err)
+	}
+	return overlay, nil
+}
+
+// OverlayReader returns a reader of the overlay of the PE file.
+func (file *File) OverlayReader() (*io.SectionReader, error) {
+	off, size, err := file.overlayExtent()
+	if err != nil {
+		return nil, err
+	}
+	return io.NewSectionReader(file.r, off, size), nil
+}
+
+// overlayExtent returns the file offset and size of the overlay. Images in
+// mapped layout have no overlay.
+//
+// The overlay spans from the end of the image to the end of the file,
+// excluding the certificate table. A certificate table located directly at the
+// end of the image is skipped, and the overlay ends at the start of any other
+// certificate table.
+func (file *File) overlayExtent() (off, size int64, err error) {
+	start, end, err := file.tailExtent()
+	if err != nil {
+		return 0, 0, err
+	}
+	off = start
+	if certOff, certSize := file.certExtent(start, end); certSize > 0 {
+		if certOff == start {
+			off = certOff + certSize
+		} else {
+			end = certOff
 		}
 	}
-	return file.overlay, nil
+	return off, end - off, nil
 }
 
-// parseOverlay parses the overlay of the PE file.
-func (file *File) parseOverlay() error {
-	// Locate start of overlay (i.e. end of image).
-	overlayStart := int64(0)
+// tailExtent returns the file offsets of the end of the image and the end of
+// the file; the range of data succeeding the image. Images in mapped layout
+// have no such data.
+func (file *File) tailExtent() (start, end int64, err error) {
+	if file.conf.layout == LayoutMapped {
+		return 0, 0, nil
+	}
+	// Locate end of image.
 	sectHdrs, err := file.SectHeaders()
 	if err != nil {
-		return errors.WithStack(err)
+		return 0, 0, err
 	}
 	for _, sectHdr := range sectHdrs {
-		sectStart := int64(sectHdr.Offset + sectHdr.Size)
-		if sectStart > overlayStart {
-			overlayStart = sectStart
+		sectOff, sectSize := file.sectExtent(sectHdr)
+		if sectEnd := sectOff + sectSize; sectEnd > start {
+			start = sectEnd
+		}
+	}
+	// Locate end of file.
+	end, err = file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Sections extending past the end of the file leave no room for trailing
+	// data.
+	if end <= start {
+		return end, end, nil
+	}
+	return start, end, nil
+}
+
+// certExtent returns the file offset and size of the certificate table,
+// clipped to the given range of trailing data; or a zero size if the
+// certificate table is not located within the range.
+func (file *File) certExtent(start, end int64) (off, size int64) {
+	dataDir, err := file.dataDir(DataDirCertificateTable)
+	if err != nil || dataDir.RelAddr == 0 || dataDir.Size == 0 {
+		return 0, 0
+	}
+	// The certificate table is located by file offset rather than address.
+	off = int64(dataDir.RelAddr)
+	certEnd := off + int64(dataDir.Size)
+	if off < start || off >= end {
+		return 0, 0
+	}
+	if certEnd > end {
+		certEnd = end
+	}
+	return off, certEnd - off
+}
+
+// OverlayType specifies the type of an overlay item.
+type OverlayType uint8
+
+// Overlay item types.
+const (
+	// Unidentified data.
+	OverlayUnknown OverlayType = iota
+	// Authenticode signature (certificate table).
+	OverlayAuthenticode
+	// ZIP archive (e.g. ZIP self-extractor).
+	OverlayZIP
+	// 7-Zip archive (e.g. 7-Zip self-extractor).
+	Overlay7z
+	// RAR archive (e.g. WinRAR self-extractor).
+	OverlayRAR
+	// Microsoft Cabinet archive (e.g. IExpress installer).
+	OverlayCAB
+	// NSIS installer data.
+	OverlayNSIS
+	// Inno Setup installer data.
+	OverlayInnoSetup
+	// Embedded PE file.
+	OverlayPE
+)
+
+// overlayTypeName is a map from OverlayType to string description.
+var overlayTypeName = map[OverlayType]string{
+	OverlayUnknown:      "unknown",
+	OverlayAuthenticode: "Authenticode signature",
+	OverlayZIP:          "ZIP archive",
+	Overlay7z:           "7-Zip archive",
+	OverlayRAR:          "RAR archive",
+	OverlayCAB:          "Cabinet archive",
+	OverlayNSIS:         "NSIS data",
+	OverlayInnoSetup:    "Inno Setup data",
+	OverlayPE:           "PE file",
+}
+
+func (typ OverlayType) String() string {
+	if s, ok := overlayTypeName[typ]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown overlay type: 0x%02X", uint8(typ))
+}
+
+// OverlayItem represents an identified payload of the data succeeding the
+// image.
+type OverlayItem struct {
+	// Payload type.
+	Type OverlayType
+	// File offset of the payload.
+	Offset int64
+	// Size of the payload in bytes. Payloads of unknown size extend to the end
+	// of the file or the start of the certificate table.
+	Size int64
+	// Underlying reader.
+	r io.ReaderAt
+}
+
+// Reader returns a new reader of the payload.
+func (item *OverlayItem) Reader() *io.SectionReader {
+	return io.NewSectionReader(item.r, item.Offset, item.Size)
+}
+
+// OverlayItems identifies the payloads of the data succeeding the image,
+// including the certificate table, and returns them ordered by offset. Data
+// which could not be identified is returned as items of type OverlayUnknown.
+func (file *File) OverlayItems() ([]*OverlayItem, error) {
+	start, end, err := file.tailExtent()
+	if err != nil {
+		return nil, err
+	}
+	var items []*OverlayItem
+	// Split the trailing data at the certificate table.
+	ranges := [][2]int64{{start, end}}
+	if certOff, certSize := file.certExtent(start, end); certSize > 0 {
+		items = append(items, &OverlayItem{Type: OverlayAuthenticode, Offset: certOff, Size: certSize, r: file.r})
+		ranges = [][2]int64{{start, certOff}, {certOff + certSize, end}}
+	}
+	for _, rng := range ranges {
+		rngItems, err := file.identifyOverlay(rng[0], rng[1])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, rngItems...)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Offset < items[j].Offset
+	})
+	return items, nil
+}
+
+// overlayMagic specifies the magic bytes identifying a payload type.
+type overlayMagic struct {
+	// Payload type.
+	typ OverlayType
+	// Magic bytes.
+	magic []byte
+	// Offset of the magic bytes from the start of the payload.
+	skip int64
+}
+
+// overlayMagics lists the magic bytes of identified payload types.
+var overlayMagics = []overlayMagic{
+	{typ: OverlayZIP, magic: []byte("PK\x03\x04")},
+	{typ: Overlay7z, magic: []byte("7z\xBC\xAF\x27\x1C")},
+	{typ: OverlayRAR, magic: []byte("Rar!\x1A\x07")},
+	{typ: OverlayCAB, magic: []byte("MSCF\x00\x00\x00\x00")},
+	// NSIS first header: flags, signature and magic.
+	{typ: OverlayNSIS, magic: []byte("\xEF\xBE\xAD\xDENullsoftInst"), skip: 4},
+	{typ: OverlayInnoSetup, magic: []byte("Inno Setup Setup Data")},
+	// Inno Setup loader offset table.
+	{typ: OverlayInnoSetup, magic: []byte("rDlPtS")},
+	{typ: OverlayPE, magic: []byte("MZ")},
+}
+
+// identifyOverlay identifies the payloads within the given range of trailing
+// data.
+func (file *File) identifyOverlay(start, end int64) ([]*OverlayItem, error) {
+	var items []*OverlayItem
+	for off := start; off < end; {
+		pos, typ, err := file.findMagic(off, end, overlayMagics)
+		if err != nil {
+			return nil, err
+		}
+		if pos > off {
+			items = append(items, &OverlayItem{Type: OverlayUnknown, Offset: off, Size: pos - off, r: file.r})
+		}
+		if pos == end {
+			break
+		}
+		size, err := file.overlayItemSize(typ, pos, end)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &OverlayItem{Type: typ, Offset: pos, Size: size, r: file.r})
+		off = pos + size
+	}
+	return items, nil
+}
+
+// findMagic returns the file offset and type of the first payload identified by
+// the given magic bytes located within the range [off, end) of the file; or end
+// if none was located.
+func (file *File) findMagic(off, end int64, magics []overlayMagic) (int64, OverlayType, error) {
+	maxLen := 0
+	for _, m := range magics {
+		if len(m.magic) > maxLen {
+			maxLen = len(m.magic)
 		}
 	}
-	// Locate end of overlay (i.e. end of file).
-	overlayEnd, err := file.r.Seek(0, io.SeekEnd)
+	best, bestType := int64(-1), OverlayUnknown
+	err := scanChunks(file.r, off, end, maxLen-1, func(cur int64, chunk []byte, last bool) bool {
+		for _, m := range magics {
+			for from := 0; from < len(chunk); {
+				i := bytes.Index(chunk[from:], m.magic)
+				if i == -1 {
+					break
+				}
+				i += from
+				from = i + 1
+				// Magic bytes starting in the overlapping tail are located by
+				// the next chunk.
+				if i >= scanChunkSize && !last {
+					break
+				}
+				pos := cur + int64(i) - m.skip
+				if pos < off {
+					continue
+				}
+				if best != -1 && pos >= best {
+					break
+				}
+				if file.validOverlayItem(m.typ, pos, end) {
+					best, bestType = pos, m.typ
+					break
+				}
+			}
+		}
+		return best == -1
+	})
 	if err != nil {
-		return errors.WithStack(err)
+		return 0, 0, err
+	}
+	if best == -1 {
+		return end, OverlayUnknown, nil
+	}
+	return best, bestType, nil
+}
+
+// scanChunkSize specifies the size of the chunks read by scanChunks.
+const scanChunkSize = 1 << 20
+
+// scanChunks reads the range [off, end) of r in chunks, and calls f with the
+// file offset and contents of each chunk until f returns false. Each chunk is
+// followed by overlap bytes of the succeeding chunk, so that byte sequences of
+// up to overlap+1 bytes crossing chunk boundaries are contained within a
+// single chunk. The last chunk, which is marked by last, ends at the end of the
+// range or at the end of the file, whichever comes first.
+func scanChunks(r io.ReaderAt, off, end int64, overlap int, f func(cur int64, chunk []byte, last bool) bool) error {
+	if overlap < 0 {
+		overlap = 0
 	}
-	overlaySize := overlayEnd - overlayStart
-	overlay := make([]byte, overlaySize)
-	if _, err := file.r.ReadAt(overlay, overlayStart); err != nil {
-		return errors.WithStack(err)
+	buf := make([]byte, scanChunkSize+overlap)
+	for cur := off; cur < end; cur += scanChunkSize {
+		n := int64(len(buf))
+		if rem := end - cur; n > rem {
+			n = rem
+		}
+		nread, err := r.ReadAt(buf[:n], cur)
+		if err != nil && err != io.EOF {
+			return readError("data", cur, err)
+		}
+		// A short read marks the end of the file; the remainder of the buffer
+		// holds stale contents of the preceding chunk.
+		last := int64(nread) < n || cur+scanChunkSize >= end
+		if !f(cur, buf[:nread], last) || last {
+			break
+		}
 	}
-	file.overlay = overlay
 	return nil
 }
+
+// validOverlayItem reports whether the payload of the given type located at
+// off has a valid header. Only embedded PE files, whose magic bytes are short,
+// are validated beyond their magic bytes.
+func (file *File) validOverlayItem(typ OverlayType, off, end int64) bool {
+	if typ != OverlayPE {
+		return true
+	}
+	// Validate the PE signature located by the PE header offset of the DOS
+	// header.
+	var peHdrOff uint32
+	if err := binary.Read(io.NewSectionReader(file.r, off+0x3C, 4), binary.LittleEndian, &peHdrOff); err != nil {
+		return false
+	}
+	if peHdrOff < 0x40 || int64(peHdrOff)+4 > end-off {
+		return false
+	}
+	var sig [4]byte
+	if _, err := file.r.ReadAt(sig[:], off+int64(peHdrOff)); err != nil {
+		return false
+	}
+	return string(sig[:]) == "PE\x00\x00"
+}
+
+// overlayItemSize returns the size of the payload of the given type located at
+// off, as recorded in its header; or the size of the remaining data up to end
+// if unknown.
+func (file *File) overlayItemSize(typ OverlayType, off, end int64) (int64, error) {
+	rem := end - off
+	size := int64(-1)
+	readUint := func(relOff int64, v interface{}) bool {
+		return binary.Read(io.NewSectionReader(file.r, off+relOff, rem-relOff), binary.LittleEndian, v) == nil
+	}
+	switch typ {
+	case OverlayZIP:
+		// Locate the last end of central directory record, which is followed by
+		// a comment of at most 64 KiB.
+		const eocdSize = 22
+		n := rem
+		if n > eocdSize+0xFFFF {
+			n = eocdSize + 0xFFFF
+		}
+		buf := make([]byte, n)
+		if _, err := file.r.ReadAt(buf, end-n); err != nil && err != io.EOF {
+			return 0, readError("ZIP archive", end-n, err)
+		}
+		if i := bytes.LastIndex(buf, []byte("PK\x05\x06")); i != -1 && i+eocdSize <= len(buf) {
+			commentLen := int64(binary.LittleEndian.Uint16(buf[i+20:]))
+			size = end - n + int64(i) + eocdSize + commentLen - off
+		}
+	case Overlay7z:
+		// Signature header: signature, version, start header CRC, next header
+		// offset and next header size.
+		var hdr struct {
+			NextHdrOff  uint64
+			NextHdrSize uint64
+		}
+		if readUint(12, &hdr) && hdr.NextHdrOff < uint64(rem) && hdr.NextHdrSize < uint64(rem) {
+			size = 32 + int64(hdr.NextHdrOff) + int64(hdr.NextHdrSize)
+		}
+	case OverlayCAB:
+		var cabSize uint32
+		if readUint(8, &cabSize) {
+			size = int64(cabSize)
+		}
+	case OverlayNSIS:
+		// First header: flags, signature, magic, header size and size of the
+		// installer data including the first header.
+		var dataSize uint32
+		if readUint(24, &dataSize) {
+			size = int64(dataSize)
+		}
+	case OverlayPE:
+		if embedded, err := file.newEmbedded(off, rem); err == nil {
+			size, _ = embedded.rawSize()
+		}
+	}
+	if size <= 0 || size > rem {
+		return rem, nil
+	}
+	return size, nil
+}
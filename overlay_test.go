@@ -0,0 +1,197 @@
+package pe
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// synthCertDataDirOff is the file offset of the certificate table data
+// directory of 32-bit synthetic images.
+const synthCertDataDirOff = synthPEHdrOffset + 4 + coffHdrSize + optHdr32Size + DataDirCertificateTable*8
+
+// synthZIP returns a ZIP archive with a trailing comment.
+func synthZIP(t *testing.T) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.SetComment("comment"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOverlayItems(t *testing.T) {
+	img := synthImage{}.bytes()
+	junk := []byte("junk data of unknown type")
+	zipData := synthZIP(t)
+	cab := make(synthBuffer, 64)
+	cab.putString(0, "MSCF\x00\x00\x00\x00")
+	cab.put32(8, uint32(len(cab)))
+	nsis := make(synthBuffer, 48)
+	nsis.putString(4, "\xEF\xBE\xAD\xDENullsoftInst")
+	nsis.put32(24, uint32(len(nsis)))
+	sevenZip := make(synthBuffer, 48)
+	sevenZip.putString(0, "7z\xBC\xAF\x27\x1C")
+	sevenZip.put64(12, 10)
+	sevenZip.put64(20, 6)
+	embedded := synthImage{is64: true}.bytes()
+	cert := make(synthBuffer, 0x20)
+	cert.put32(0, uint32(len(cert)))
+	cert.put16(4, 0x0200)
+	cert.put16(6, 0x0002)
+	rar := []byte("Rar!\x1A\x07\x00 appended after certificate table")
+
+	want := []struct {
+		typ  OverlayType
+		data []byte
+	}{
+		{typ: OverlayUnknown, data: junk},
+		{typ: OverlayZIP, data: zipData},
+		{typ: OverlayCAB, data: cab},
+		{typ: OverlayNSIS, data: nsis},
+		{typ: Overlay7z, data: sevenZip},
+		{typ: OverlayPE, data: embedded},
+		{typ: OverlayAuthenticode, data: cert},
+		{typ: OverlayRAR, data: rar},
+	}
+	b := append(synthBuffer(nil), img...)
+	for _, item := range want {
+		if item.typ == OverlayAuthenticode {
+			b.put32(synthCertDataDirOff, uint32(len(b)))
+			b.put32(synthCertDataDirOff+4, uint32(len(cert)))
+		}
+		b = append(b, item.data...)
+	}
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := file.OverlayItems()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != len(want) {
+		t.Fatalf("expected %d overlay items, got %d", len(want), len(items))
+	}
+	for i, item := range items {
+		if item.Type != want[i].typ {
+			t.Errorf("item %d: type mismatch; expected %v, got %v", i, want[i].typ, item.Type)
+			continue
+		}
+		data, err := io.ReadAll(item.Reader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want[i].data) {
+			t.Errorf("item %d (%v): data mismatch; expected %d bytes, got %d bytes", i, item.Type, len(want[i].data), len(data))
+		}
+	}
+
+	// The overlay ends at the start of the certificate table.
+	overlay, err := file.Overlay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certOff := binary.LittleEndian.Uint32(b[synthCertDataDirOff:])
+	if want := b[len(img):certOff]; !bytes.Equal(overlay, want) {
+		t.Errorf("overlay mismatch; expected %d bytes, got %d bytes", len(want), len(overlay))
+	}
+}
+
+func TestOverlayCertificate(t *testing.T) {
+	img := synthImage{}.bytes()
+	cert := make([]byte, 0x20)
+	golden := []struct {
+		name string
+		// Certificate table offset relative to the end of the image, and data
+		// succeeding the image.
+		certOff int
+		tail    []byte
+		// Expected overlay.
+		want []byte
+	}{
+		{name: "no overlay", certOff: 0, tail: cert, want: []byte{}},
+		{name: "overlay before", certOff: 3, tail: append([]byte("abc"), cert...), want: []byte("abc")},
+		{name: "overlay after", certOff: 0, tail: append(append([]byte(nil), cert...), "abc"...), want: []byte("abc")},
+		{name: "truncated", certOff: 3, tail: append([]byte("abc"), cert[:4]...), want: []byte("abc")},
+	}
+	for _, g := range golden {
+		b := append(synthBuffer(nil), img...)
+		b.put32(synthCertDataDirOff, uint32(len(img)+g.certOff))
+		b.put32(synthCertDataDirOff+4, uint32(len(cert)))
+		b = append(b, g.tail...)
+		file, err := New(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		overlay, err := file.Overlay()
+		if err != nil {
+			t.Errorf("%s: unexpected error; %v", g.name, err)
+			continue
+		}
+		if !bytes.Equal(overlay, g.want) {
+			t.Errorf("%s: overlay mismatch; expected %q, got %q", g.name, g.want, overlay)
+		}
+		sr, err := file.OverlayReader()
+		if err != nil {
+			t.Errorf("%s: unexpected error; %v", g.name, err)
+			continue
+		}
+		data, err := io.ReadAll(sr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, g.want) {
+			t.Errorf("%s: overlay reader mismatch; expected %q, got %q", g.name, g.want, data)
+		}
+	}
+}
+
+func TestScanChunks(t *testing.T) {
+	const overlap = 3
+	data := make([]byte, scanChunkSize+100)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	// The range extends past the end of the file, which ends the scan.
+	var chunks [][]byte
+	var offs []int64
+	lastSeen := false
+	err := scanChunks(bytes.NewReader(data), 0, 3*scanChunkSize, overlap, func(cur int64, chunk []byte, last bool) bool {
+		if lastSeen {
+			t.Errorf("chunk at 0x%X succeeds last chunk", cur)
+		}
+		lastSeen = last
+		offs = append(offs, cur)
+		chunks = append(chunks, append([]byte(nil), chunk...))
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 || !lastSeen {
+		t.Fatalf("chunk count mismatch; expected 2 chunks ending with the last, got %d (last=%v)", len(chunks), lastSeen)
+	}
+	for i, chunk := range chunks {
+		want := data[offs[i]:]
+		if len(want) > scanChunkSize+overlap {
+			want = want[:scanChunkSize+overlap]
+		}
+		if !bytes.Equal(chunk, want) {
+			t.Errorf("chunk %d at 0x%X mismatch; expected %d bytes of file contents, got %d bytes", i, offs[i], len(want), len(chunk))
+		}
+	}
+}
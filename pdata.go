@@ -0,0 +1,93 @@
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// RuntimeFunc represents an entry of the exception table (.pdata section) of
+// x64 images, which specifies the extent and unwind information of a function.
+type RuntimeFunc struct {
+	// Start address of the function, relative to the image base.
+	BeginRelAddr uint32
+	// End address of the function, relative to the image base.
+	EndRelAddr uint32
+	// Address of the unwind information of the function, relative to the image
+	// base.
+	UnwindRelAddr uint32
+}
+
+func (fn *RuntimeFunc) String() string {
+	return fmt.Sprintf("0x%08X-0x%08X (unwind info at 0x%08X)", fn.BeginRelAddr, fn.EndRelAddr, fn.UnwindRelAddr)
+}
+
+// runtimeFuncSize specifies the size of exception table entries in bytes.
+const runtimeFuncSize = 12
+
+// RuntimeFuncs returns the entries of the exception table of file, in table
+// order. The exception table is only parsed for x64 images, as the format of
+// exception table entries is architecture-specific.
+func (file *File) RuntimeFuncs() (funcs []*RuntimeFunc, err error) {
+	if !file.runtimeFuncsParsed {
+		err = file.parseStruct(file.parseRuntimeFuncs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.runtimeFuncs, nil
+}
+
+// parseRuntimeFuncs parses the exception table of file.
+func (file *File) parseRuntimeFuncs() error {
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return err
+	}
+	dataDir, err := file.dataDir(DataDirExceptionTable)
+	if err != nil {
+		return err
+	}
+	if fileHdr.Arch != ArchAMD64 || dataDir.RelAddr == 0 || dataDir.Size == 0 {
+		file.runtimeFuncsParsed = true
+		return nil
+	}
+	// The number of entries is bounded by the allocation limit rather than the
+	// directory entry limit, as images commonly hold tens of thousands of
+	// functions.
+	n := int64(dataDir.Size / runtimeFuncSize)
+	if err := file.checkAlloc("exception table", n*runtimeFuncSize); err != nil {
+		return err
+	}
+	buf := make([]byte, n*runtimeFuncSize)
+	if err := file.readRelAddr(dataDir.RelAddr, buf, "exception table"); err != nil {
+		return err
+	}
+
+	// Entries are sorted by start address and terminated by the end of the
+	// table. In tolerant mode, parsing stops at the first malformed entry,
+	// keeping the entries parsed so far.
+	var funcs []*RuntimeFunc
+	for off := 0; off+runtimeFuncSize <= len(buf); off += runtimeFuncSize {
+		fn := &RuntimeFunc{
+			BeginRelAddr:  binary.LittleEndian.Uint32(buf[off:]),
+			EndRelAddr:    binary.LittleEndian.Uint32(buf[off+4:]),
+			UnwindRelAddr: binary.LittleEndian.Uint32(buf[off+8:]),
+		}
+		if *fn == (RuntimeFunc{}) {
+			continue
+		}
+		if fn.EndRelAddr < fn.BeginRelAddr {
+			err := formatError("exception table entry", -1, "end address 0x%08X precedes start address 0x%08X", fn.EndRelAddr, fn.BeginRelAddr)
+			if file.tolerate(err) {
+				break
+			}
+			return err
+		}
+		funcs = append(funcs, fn)
+	}
+
+	file.runtimeFuncs = funcs
+	file.runtimeFuncsParsed = true
+	return nil
+}
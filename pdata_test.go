@@ -0,0 +1,61 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRuntimeFuncs(t *testing.T) {
+	const (
+		pdataRelAddr = 0x13C0
+		dataDirOff   = synthPEHdrOffset + 4 + coffHdrSize + optHdr64Size + DataDirExceptionTable*8
+	)
+	want := []*RuntimeFunc{
+		{BeginRelAddr: 0x1000, EndRelAddr: 0x1010, UnwindRelAddr: 0x1100},
+		{BeginRelAddr: 0x1010, EndRelAddr: 0x1040, UnwindRelAddr: 0x1108},
+	}
+	b := synthBuffer(synthImage{is64: true}.bytes())
+	b.put32(dataDirOff, pdataRelAddr)
+	b.put32(dataDirOff+4, uint32(len(want)+1)*runtimeFuncSize)
+	for i, fn := range want {
+		b.putStruct(sectOff(pdataRelAddr)+i*runtimeFuncSize, *fn)
+	}
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := file.RuntimeFuncs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != len(want) {
+		t.Fatalf("expected %d runtime functions, got %d", len(want), len(funcs))
+	}
+	for i, fn := range funcs {
+		if *fn != *want[i] {
+			t.Errorf("runtime function %d mismatch; expected %v, got %v", i, want[i], fn)
+		}
+	}
+
+	// End address preceding the start address.
+	b.put32(sectOff(pdataRelAddr)+runtimeFuncSize+4, 0x1000)
+	file, err = New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.RuntimeFuncs(); err == nil {
+		t.Errorf("expected error for malformed exception table entry")
+	}
+	file, err = New(bytes.NewReader(b), WithTolerant(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err = file.RuntimeFuncs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 1 {
+		t.Errorf("expected 1 runtime function in tolerant mode, got %d", len(funcs))
+	}
+}
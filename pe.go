@@ -5,22 +5,58 @@
 package pe
 
 import (
+	"errors"
 	"io"
 	"os"
 )
 
 // File represents a Portable Executable (PE) file.
+//
+// Headers and directories are parsed on first access and cached. A structure
+// is only cached once it has been parsed successfully; failed parses leave no
+// partial state behind, and are retried on the next access.
 type File struct {
 	// DOS Header.
 	doshdr *DOSHeader
 	// COFF file header.
 	fileHdr *FileHeader
+	// File offset of the COFF file header.
+	coffHdrOff int64
+	// Specifies whether the file is a COFF object file.
+	obj bool
 	// Optional header.
 	opthdr *OptHeader
 	// Section headers.
 	sectHdrs []*SectHeader
-	// Overlay.
-	overlay []byte
+	// Rich header; or nil if not present.
+	richHdr *RichHeader
+	// Imports.
+	imps []*Import
+	// Exports; or nil if not present.
+	exps *Exports
+	// Root of the resource directory tree; or nil if not present.
+	rsrc *ResourceNode
+	// Base relocations.
+	relocs []*BaseReloc
+	// Exception table entries.
+	runtimeFuncs []*RuntimeFunc
+	// Thread local storage.
+	tls *TLS
+	// Load configuration directory.
+	loadCfg *LoadConfig
+	// Specifies which of the optional structures above have been parsed.
+	richParsed, expsParsed, rsrcParsed, relocsParsed, runtimeFuncsParsed bool
+	tlsParsed, loadCfgParsed                                             bool
+	// Anomalies recorded while parsing.
+	anomalies []*Anomaly
+	// Anomalies of the structure currently being parsed, which are committed
+	// to anomalies once it has been parsed successfully; or nil if no structure
+	// is being parsed.
+	pending *[]*Anomaly
+	// Specifies whether the structural check of anomalies has been performed.
+	checked bool
+	// Parser configuration.
+	conf config
 	// Underlying reader.
 	r ReadAtSeeker
 	io.Closer
@@ -29,12 +65,12 @@ type File struct {
 // Open returns a new File for accessing the PE binary at path.
 //
 // Note: The Close method of the file must be called when finished using it.
-func Open(path string) (file *File, err error) {
+func Open(path string, opts ...Option) (file *File, err error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
-	file, err = New(f)
+	file, err = New(f, opts...)
 	if err != nil {
 		f.Close()
 		return nil, err
@@ -50,57 +86,150 @@ type ReadAtSeeker interface {
 }
 
 // New returns a new File for accessing the PE binary of r.
-func New(r ReadAtSeeker) (file *File, err error) {
-	// TODO(u): Figure out which headers that should always be parsed.
-	//    * DOS header
-	//       - Contains no relevant information, but is required to locate the
-	//         file header offset.
-	//    * File header
-	//       - Specifies the intended architecture of the binary, and the number
-	//         of sections contained within the file.
-	//    * Optional header
-	//       - Specifies the code, data and image base addresses, the entry point
-	//         point address, and the data directories.
-	return &File{r: r}, nil
+//
+// By default, headers and directories are parsed lazily on first access. Use
+// WithParseMode(ParseEager) to parse everything up front, in which case New
+// fails if Parse fails. Parsing is subject to DefaultLimits unless overridden
+// by WithLimits.
+func New(r ReadAtSeeker, opts ...Option) (file *File, err error) {
+	file = &File{r: r}
+	file.conf.limits = DefaultLimits
+	file.conf.ordinals = DefaultOrdinalDB
+	for _, opt := range opts {
+		opt(&file.conf)
+	}
+	if file.conf.mode == ParseEager {
+		if err := file.Parse(); err != nil {
+			return nil, err
+		}
+	}
+	return file, nil
 }
 
-// Parse parses all headers of file.
-func (file *File) Parse() error {
-	// Parse DOS header.
-	err := file.parseDOSHeader()
-	if err != nil {
-		return err
+// An Option configures the parsing of a File.
+type Option func(conf *config)
+
+// config specifies the parser configuration of a File.
+type config struct {
+	// Parse mode.
+	mode ParseMode
+	// Specifies whether malformed and truncated structures are tolerated.
+	tolerant bool
+	// Resource limits.
+	limits Limits
+	// Layout of the image in the underlying reader.
+	layout Layout
+	// Ordinal database; or nil if disabled.
+	ordinals *OrdinalDB
+}
+
+// ParseMode specifies when the headers and directories of a File are parsed.
+type ParseMode uint8
+
+// Parse modes.
+const (
+	// ParseLazy parses headers and directories on first access.
+	ParseLazy ParseMode = iota
+	// ParseEager parses all headers and directories when the File is created.
+	ParseEager
+)
+
+// WithParseMode returns an option which sets the parse mode of a File.
+func WithParseMode(mode ParseMode) Option {
+	return func(conf *config) {
+		conf.mode = mode
+	}
+}
+
+// Layout specifies how the image is laid out in the underlying reader of a
+// File.
+type Layout uint8
+
+// Image layouts.
+const (
+	// LayoutFile is the layout of PE files on disk; section contents are
+	// located at the file offsets of the section headers.
+	LayoutFile Layout = iota
+	// LayoutMapped is the layout of images loaded into memory (e.g. modules
+	// carved from process memory dumps); section contents are located at the
+	// addresses of the section headers, relative to the image base.
+	LayoutMapped
+)
+
+// WithLayout returns an option which sets the image layout of a File. All
+// readers translate addresses according to the layout.
+func WithLayout(layout Layout) Option {
+	return func(conf *config) {
+		conf.layout = layout
+	}
+}
+
+// WithTolerant returns an option which enables or disables tolerant parsing of
+// a File.
+//
+// Tolerant parsing mirrors the behaviour of the Windows loader on malformed and
+// truncated images: headers extending past the end of the file are
+// zero-filled, the optional header size is honoured exactly (fields past it are
+// zero), the number of data directories and section headers are clamped to
+// what is present, and malformed directories are partially parsed. Each
+// deviation is recorded as an anomaly instead of causing an error.
+func WithTolerant(tolerant bool) Option {
+	return func(conf *config) {
+		conf.tolerant = tolerant
 	}
+}
 
-	// Parse COFF file header.
-	err = file.parseFileHeader()
+// Parse parses all headers and directories of file.
+//
+// Parsing stops at the first malformed header, as the remaining structures
+// cannot be located without it. Directories are independent of each other, so
+// all directories are parsed and their errors are joined.
+func (file *File) Parse() error {
+	// Parse DOS header and COFF file header.
+	fileHdr, err := file.FileHeader()
 	if err != nil {
 		return err
 	}
 
 	// Parse optional header.
-	if file.fileHdr.OptHdrSize > 0 {
-		err = file.parseOptHeader()
-		if err != nil {
+	if fileHdr.OptHdrSize > 0 {
+		if _, err := file.OptHeader(); err != nil {
 			return err
 		}
 	}
 
 	// Parse section headers.
-	err = file.parseSectHeaders()
-	if err != nil {
+	if _, err := file.SectHeaders(); err != nil {
 		return err
 	}
 
-	// Parse sections.
-
-	//// Parse data directories.
-	//for _, dataDir := range file.OptHdr.DataDirs {
-	//	if dataDir.Size == 0 {
-	//		continue
-	//	}
-	//	// TODO(u): Parse the data directories.
-	//}
-
-	return nil
+	// Parse directories.
+	var errs []error
+	if !file.obj {
+		if _, err := file.RichHeader(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := file.Imports(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := file.Exports(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := file.Resources(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := file.BaseRelocs(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := file.RuntimeFuncs(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := file.TLS(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := file.LoadConfig(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
 }
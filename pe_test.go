@@ -0,0 +1,141 @@
+package pe
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	data := synthImage{}.bytes()
+
+	// Lazy parsing parses nothing up front.
+	file, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.doshdr != nil || file.fileHdr != nil || file.opthdr != nil || file.sectHdrs != nil {
+		t.Errorf("lazy: unexpected headers parsed by New")
+	}
+
+	// Eager parsing parses headers and directories up front.
+	file, err = New(bytes.NewReader(data), WithParseMode(ParseEager))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.doshdr == nil || file.fileHdr == nil || file.opthdr == nil || file.sectHdrs == nil {
+		t.Errorf("eager: missing headers parsed by New")
+	}
+	if !file.expsParsed || !file.rsrcParsed {
+		t.Errorf("eager: missing directories parsed by New")
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	golden := []struct {
+		// Size of the truncated image.
+		size int
+		// Accesses the truncated structure.
+		get func(file *File) error
+		// Expected name and file offset of the truncated structure.
+		structName string
+		off        int64
+	}{
+		{
+			size:       0x20,
+			get:        func(file *File) error { _, err := file.DOSHeader(); return err },
+			structName: "DOS header",
+			off:        0,
+		},
+		{
+			size:       0x90,
+			get:        func(file *File) error { _, err := file.FileHeader(); return err },
+			structName: "file header",
+			off:        synthPEHdrOffset,
+		},
+		{
+			size:       0x110,
+			get:        func(file *File) error { _, err := file.OptHeader(); return err },
+			structName: "optional header",
+			off:        synthPEHdrOffset + 4 + coffHdrSize,
+		},
+		{
+			size:       0x190,
+			get:        func(file *File) error { _, err := file.SectHeaders(); return err },
+			structName: "section table",
+			off:        synthPEHdrOffset + 4 + coffHdrSize + optHdr32Size + maxDataDirs*8,
+		},
+		{
+			size:       0x300,
+			get:        func(file *File) error { _, err := file.Exports(); return err },
+			structName: "export directory table",
+			off:        int64(sectOff(synthExportRelAddr)),
+		},
+	}
+	// check checks that err reports the truncated structure of the given name
+	// and file offset.
+	check := func(mode string, err error, structName string, off int64) {
+		t.Helper()
+		if !errors.Is(err, ErrTruncated) {
+			t.Errorf("%s: %s: expected ErrTruncated, got %v", mode, structName, err)
+		}
+		var e *FormatError
+		if !errors.As(err, &e) {
+			t.Errorf("%s: %s: expected *FormatError, got %T", mode, structName, err)
+			return
+		}
+		if e.Struct != structName || e.Offset != off {
+			t.Errorf("%s: %s: structure mismatch; expected %q at offset 0x%X, got %q at offset 0x%X", mode, structName, structName, off, e.Struct, e.Offset)
+		}
+	}
+	data := synthImage{}.bytes()
+	for _, g := range golden {
+		// Eager parsing fails in New.
+		_, err := New(bytes.NewReader(data[:g.size]), WithParseMode(ParseEager))
+		if err == nil {
+			t.Errorf("eager: %s: expected error", g.structName)
+		} else {
+			check("eager", err, g.structName, g.off)
+		}
+
+		// Lazy parsing fails on access, and consistently so when retried.
+		file, err := New(bytes.NewReader(data[:g.size]))
+		if err != nil {
+			t.Errorf("lazy: %s: unable to create file; %v", g.structName, err)
+			continue
+		}
+		err1 := g.get(file)
+		check("lazy", err1, g.structName, g.off)
+		err2 := g.get(file)
+		if err1 == nil || err2 == nil || err1.Error() != err2.Error() {
+			t.Errorf("lazy: %s: retry mismatch; expected %v, got %v", g.structName, err1, err2)
+		}
+		if err := file.Parse(); err == nil {
+			t.Errorf("lazy: %s: expected Parse error", g.structName)
+		} else {
+			check("lazy Parse", err, g.structName, g.off)
+		}
+	}
+}
+
+func TestParseFailedNotCached(t *testing.T) {
+	const optHdrOff = synthPEHdrOffset + 4 + coffHdrSize
+	b := synthBuffer(synthImage{}.bytes())
+	// Invalid number of data directories.
+	b.put32(optHdrOff+92, maxDataDirs+1)
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.OptHeader(); err == nil {
+		t.Fatal("expected optional header error")
+	}
+	if file.opthdr != nil {
+		t.Errorf("unexpected optional header cached after failed parse")
+	}
+	// Repairing the underlying data makes the retried parse succeed.
+	b.put32(optHdrOff+92, maxDataDirs)
+	if _, err := file.OptHeader(); err != nil {
+		t.Errorf("unexpected error of retried parse; %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BaseReloc represents a base relocation, which specifies an address to adjust
+// when the image is not loaded at its preferred base address.
+type BaseReloc struct {
+	// Relocation type.
+	Type RelocType
+	// Address of the relocated value, relative to the image base.
+	RelAddr uint32
+	// Low 16 bits of the 32-bit address, which is adjusted as a whole; only
+	// valid for RelocHighAdj relocations.
+	Param uint16
+}
+
+func (reloc *BaseReloc) String() string {
+	return fmt.Sprintf("%v at 0x%08X", reloc.Type, reloc.RelAddr)
+}
+
+// RelocType specifies the type of a base relocation.
+type RelocType uint8
+
+// Base relocation types.
+const (
+	// RelocAbsolute is skipped; used to pad relocation blocks.
+	RelocAbsolute RelocType = 0
+	// RelocHigh adds the high 16 bits of the delta to the 16-bit field.
+	RelocHigh RelocType = 1
+	// RelocLow adds the low 16 bits of the delta to the 16-bit field.
+	RelocLow RelocType = 2
+	// RelocHighLow adds the 32-bit delta to the 32-bit field.
+	RelocHighLow RelocType = 3
+	// RelocHighAdj adds the delta to the 32-bit address formed by the 16-bit
+	// field (high bits) and the following entry (low bits), and stores the high
+	// 16 bits of the rounded result.
+	RelocHighAdj RelocType = 4
+	// RelocDir64 adds the 64-bit delta to the 64-bit field.
+	RelocDir64 RelocType = 10
+)
+
+// String returns the string representation of the relocation type.
+func (typ RelocType) String() string {
+	if s, ok := relocTypeName[typ]; ok {
+		return s
+	}
+	return fmt.Sprintf("reloc type %d", uint8(typ))
+}
+
+// relocTypeName is a map from relocation type to string description.
+var relocTypeName = map[RelocType]string{
+	RelocAbsolute: "absolute",
+	RelocHigh:     "high",
+	RelocLow:      "low",
+	RelocHighLow:  "highlow",
+	RelocHighAdj:  "highadj",
+	RelocDir64:    "dir64",
+}
+
+// BaseRelocs returns the base relocations of file, in the order of the base
+// relocation table. RelocAbsolute padding entries are omitted.
+func (file *File) BaseRelocs() (relocs []*BaseReloc, err error) {
+	if !file.relocsParsed {
+		err = file.parseStruct(file.parseBaseRelocs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.relocs, nil
+}
+
+// parseBaseRelocs parses the base relocation table of file.
+func (file *File) parseBaseRelocs() error {
+	dataDir, err := file.dataDir(DataDirBaseRelocationTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 || dataDir.Size == 0 {
+		file.relocsParsed = true
+		return nil
+	}
+	if err := file.checkAlloc("base relocation table", int64(dataDir.Size)); err != nil {
+		return err
+	}
+	buf := make([]byte, dataDir.Size)
+	if err := file.readRelAddr(dataDir.RelAddr, buf, "base relocation table"); err != nil {
+		return err
+	}
+
+	// The table consists of blocks of relocations, one block per 4K page. Each
+	// block starts with the page address and block size, followed by 16-bit
+	// entries holding the relocation type (high 4 bits) and the offset into the
+	// page (low 12 bits). In tolerant mode, parsing stops at the first malformed
+	// block, keeping the relocations parsed so far.
+	const blockHdrSize = 8
+	var relocs []*BaseReloc
+	for off := 0; off+blockHdrSize <= len(buf); {
+		pageRelAddr := binary.LittleEndian.Uint32(buf[off:])
+		size := int(binary.LittleEndian.Uint32(buf[off+4:]))
+		if size < blockHdrSize || size > len(buf)-off {
+			err := formatError("base relocation block", -1, "invalid size %d of block at address 0x%08X", size, dataDir.RelAddr+uint32(off))
+			if file.tolerate(err) {
+				break
+			}
+			return err
+		}
+		for i := off + blockHdrSize; i+2 <= off+size; i += 2 {
+			entry := binary.LittleEndian.Uint16(buf[i:])
+			reloc := &BaseReloc{
+				Type:    RelocType(entry >> 12),
+				RelAddr: pageRelAddr + uint32(entry&0x0FFF),
+			}
+			switch reloc.Type {
+			case RelocAbsolute:
+				continue
+			case RelocHighAdj:
+				// The low 16 bits of the address are stored in the following
+				// entry.
+				i += 2
+				if i+2 > off+size {
+					err := formatError("base relocation block", -1, "missing parameter of highadj relocation at address 0x%08X", reloc.RelAddr)
+					if !file.tolerate(err) {
+						return err
+					}
+					continue
+				}
+				reloc.Param = binary.LittleEndian.Uint16(buf[i:])
+			}
+			relocs = append(relocs, reloc)
+		}
+		off += size
+	}
+
+	file.relocs = relocs
+	file.relocsParsed = true
+	return nil
+}
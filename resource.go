@@ -0,0 +1,268 @@
+package pe
+
+import (
+	"fmt"
+	"unicode/utf16"
+)
+
+// ResourceDirectory represents a resource directory table.
+type ResourceDirectory struct {
+	// Reserved.
+	Flags uint32
+	// The time that the resource data was created by the resource compiler.
+	Created Time
+	// Major version number.
+	MajorVer uint16
+	// Minor version number.
+	MinorVer uint16
+	// Number of directory entries identified by name.
+	NNameEntry uint16
+	// Number of directory entries identified by integer ID.
+	NIDEntry uint16
+}
+
+// resourceDirEntry represents a resource directory entry.
+type resourceDirEntry struct {
+	// Offset of the name string if the high bit is set, and integer ID
+	// otherwise.
+	NameOrID uint32
+	// Offset of a subdirectory if the high bit is set, and of a resource data
+	// entry otherwise. The offset is relative to the start of the resource
+	// section.
+	Offset uint32
+}
+
+// ResourceDataEntry describes the location and size of a resource leaf.
+type ResourceDataEntry struct {
+	// Address of the resource data, relative to the image base.
+	RelAddr uint32
+	// Size of the resource data in bytes.
+	Size uint32
+	// Code page used to decode code point values within the resource data.
+	CodePage uint32
+	// Reserved.
+	Res uint32
+}
+
+// ResourceNode represents a node of the resource directory tree. Inner nodes
+// hold a resource directory table, and leaves hold a resource data entry.
+type ResourceNode struct {
+	// Resource name; or empty if identified by integer ID.
+	Name string
+	// Integer ID; only valid if Name is empty.
+	ID uint32
+	// Resource directory table; or nil if leaf.
+	Dir *ResourceDirectory
+	// Address of the resource directory table, relative to the image base;
+	// only valid if Dir is non-nil.
+	dirRelAddr uint32
+	// Child nodes of the directory; or nil if leaf.
+	Children []*ResourceNode
+	// Resource data entry; or nil if directory.
+	Data *ResourceDataEntry
+}
+
+// IsLeaf reports whether the resource node is a leaf.
+func (node *ResourceNode) IsLeaf() bool {
+	return node.Data != nil
+}
+
+func (node *ResourceNode) String() string {
+	if len(node.Name) > 0 {
+		return node.Name
+	}
+	return fmt.Sprintf("#%d", node.ID)
+}
+
+// Walk traverses the resource tree rooted at node in depth-first order, calling
+// f for each node with the path of nodes leading up to it (excluding the root).
+func (node *ResourceNode) Walk(f func(path []*ResourceNode, node *ResourceNode)) {
+	var walk func(path []*ResourceNode, n *ResourceNode)
+	walk = func(path []*ResourceNode, n *ResourceNode) {
+		f(path, n)
+		for _, child := range n.Children {
+			walk(append(path[:len(path):len(path)], child), child)
+		}
+	}
+	for _, child := range node.Children {
+		walk([]*ResourceNode{child}, child)
+	}
+}
+
+// Resource types.
+const (
+	ResourceTypeCursor       = 1
+	ResourceTypeBitmap       = 2
+	ResourceTypeIcon         = 3
+	ResourceTypeMenu         = 4
+	ResourceTypeDialog       = 5
+	ResourceTypeString       = 6
+	ResourceTypeFontDir      = 7
+	ResourceTypeFont         = 8
+	ResourceTypeAccelerator  = 9
+	ResourceTypeRCData       = 10
+	ResourceTypeMessageTable = 11
+	ResourceTypeGroupCursor  = 12
+	ResourceTypeGroupIcon    = 14
+	ResourceTypeVersion      = 16
+	ResourceTypeDlgInclude   = 17
+	ResourceTypePlugPlay     = 19
+	ResourceTypeVXD          = 20
+	ResourceTypeAniCursor    = 21
+	ResourceTypeAniIcon      = 22
+	ResourceTypeHTML         = 23
+	ResourceTypeManifest     = 24
+)
+
+// resourceTypeName is a map from resource type to string description.
+var resourceTypeName = map[uint32]string{
+	ResourceTypeCursor:       "CURSOR",
+	ResourceTypeBitmap:       "BITMAP",
+	ResourceTypeIcon:         "ICON",
+	ResourceTypeMenu:         "MENU",
+	ResourceTypeDialog:       "DIALOG",
+	ResourceTypeString:       "STRING",
+	ResourceTypeFontDir:      "FONTDIR",
+	ResourceTypeFont:         "FONT",
+	ResourceTypeAccelerator:  "ACCELERATOR",
+	ResourceTypeRCData:       "RCDATA",
+	ResourceTypeMessageTable: "MESSAGETABLE",
+	ResourceTypeGroupCursor:  "GROUP_CURSOR",
+	ResourceTypeGroupIcon:    "GROUP_ICON",
+	ResourceTypeVersion:      "VERSION",
+	ResourceTypeDlgInclude:   "DLGINCLUDE",
+	ResourceTypePlugPlay:     "PLUGPLAY",
+	ResourceTypeVXD:          "VXD",
+	ResourceTypeAniCursor:    "ANICURSOR",
+	ResourceTypeAniIcon:      "ANIICON",
+	ResourceTypeHTML:         "HTML",
+	ResourceTypeManifest:     "MANIFEST",
+}
+
+// ResourceTypeName returns the name of the given predefined resource type, or
+// an empty string if unknown.
+func ResourceTypeName(typ uint32) string {
+	return resourceTypeName[typ]
+}
+
+// Resources returns the root of the resource directory tree of file, or nil if
+// file has no resources.
+func (file *File) Resources() (root *ResourceNode, err error) {
+	if !file.rsrcParsed {
+		err = file.parseStruct(file.parseResources)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.rsrc, nil
+}
+
+// ResourceData returns the contents of the given resource leaf.
+func (file *File) ResourceData(data *ResourceDataEntry) ([]byte, error) {
+	off, n, err := file.relAddrToOffset(data.RelAddr)
+	if err != nil {
+		return nil, &FormatError{Struct: "resource data", Offset: -1, Err: err}
+	}
+	if int64(data.Size) > n {
+		return nil, formatError("resource data", off, "size (%d) exceeds section bounds (%d)", data.Size, n)
+	}
+	if err := file.checkAlloc("resource data", int64(data.Size)); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, data.Size)
+	if _, err := file.r.ReadAt(buf, off); err != nil {
+		return nil, readError("resource data", off, err)
+	}
+	return buf, nil
+}
+
+// parseResources parses the resource directory tree of file.
+func (file *File) parseResources() error {
+	dataDir, err := file.dataDir(DataDirResourceTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 {
+		file.rsrcParsed = true
+		return nil
+	}
+	root := new(ResourceNode)
+	visited := make(map[uint32]bool)
+	// In tolerant mode, the nodes parsed before encountering a malformed
+	// directory are kept.
+	if err := file.parseResourceDir(root, dataDir.RelAddr, 0, 0, visited); err != nil && !file.tolerate(err) {
+		return err
+	}
+	file.rsrc = root
+	file.rsrcParsed = true
+	return nil
+}
+
+// parseResourceDir parses the resource directory table at the given offset,
+// relative to the start of the resource section located at base.
+func (file *File) parseResourceDir(node *ResourceNode, base, off uint32, depth int, visited map[uint32]bool) error {
+	// The standard layout of the resource tree uses three levels: type, name and
+	// language.
+	limits := file.conf.limits
+	if err := checkLimit(limitResourceDepth, "resource directory tree", int64(depth+1), int64(limits.MaxResourceDepth)); err != nil {
+		return err
+	}
+	if visited[off] {
+		return formatError("resource directory table", -1, "loop in resource tree at resource offset 0x%X", off)
+	}
+	visited[off] = true
+	dir := new(ResourceDirectory)
+	if err := file.readRelAddr(base+off, dir, "resource directory table"); err != nil {
+		return err
+	}
+	const resourceDirSize = 16
+	node.Dir = dir
+	node.dirRelAddr = base + off
+	n := int(dir.NNameEntry) + int(dir.NIDEntry)
+	if err := checkLimit(limitDirEntries, "resource directory entries", int64(n), int64(limits.MaxDirEntries)); err != nil {
+		return err
+	}
+	entries := make([]resourceDirEntry, n)
+	if err := file.readRelAddr(base+off+resourceDirSize, entries, "resource directory entries"); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		child := new(ResourceNode)
+		node.Children = append(node.Children, child)
+		if entry.NameOrID&0x80000000 != 0 {
+			name, err := file.readResourceString(base + entry.NameOrID&0x7FFFFFFF)
+			if err != nil {
+				return err
+			}
+			child.Name = name
+		} else {
+			child.ID = entry.NameOrID
+		}
+		if entry.Offset&0x80000000 != 0 {
+			if err := file.parseResourceDir(child, base, entry.Offset&0x7FFFFFFF, depth+1, visited); err != nil {
+				return err
+			}
+		} else {
+			child.Data = new(ResourceDataEntry)
+			if err := file.readRelAddr(base+entry.Offset, child.Data, "resource data entry"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readResourceString reads a length-prefixed UTF-16 resource directory string
+// from the given address, relative to the image base.
+func (file *File) readResourceString(relAddr uint32) (string, error) {
+	var n uint16
+	if err := file.readRelAddr(relAddr, &n, "resource name"); err != nil {
+		return "", err
+	}
+	s := make([]uint16, n)
+	if err := file.readRelAddr(relAddr+2, s, "resource name"); err != nil {
+		return "", err
+	}
+	return string(utf16.Decode(s)), nil
+}
@@ -0,0 +1,205 @@
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Rich header signatures.
+const (
+	// richMagic is the signature which terminates the masked Rich header;
+	// "Rich".
+	richMagic = 0x68636952
+	// dansMagic is the (masked) signature which starts the Rich header; "DanS".
+	dansMagic = 0x536E6144
+)
+
+// RichHeader represents the undocumented Rich header, which is located in the
+// DOS stub of executables produced by the Microsoft linker. It identifies the
+// tools that were used to produce the object files of the image.
+//
+// ref: http://bytepointer.com/articles/the_microsoft_rich_header.htm
+type RichHeader struct {
+	// File offset of the Rich header (i.e. of the masked "DanS" signature).
+	Offset uint32
+	// The XOR key used to mask the Rich header. The key is also the checksum of
+	// the Rich header.
+	Key uint32
+	// Tool entries.
+	Entries []RichEntry
+	// Checksum computed from the DOS header, DOS stub and the tool entries.
+	Checksum uint32
+}
+
+// ValidChecksum reports whether the computed checksum of the Rich header
+// matches its XOR key.
+func (rich *RichHeader) ValidChecksum() bool {
+	return rich.Checksum == rich.Key
+}
+
+// A RichEntry specifies the number of object files produced by a given tool
+// (product ID and build number) which contributed to the image.
+type RichEntry struct {
+	// Product identifier of the tool.
+	ProdID RichProdID
+	// Build number of the tool.
+	Build uint16
+	// Number of object files produced by the tool.
+	Count uint32
+}
+
+// CompID returns the comp.id value of the entry; the product ID and build
+// number combined into a 32-bit value.
+func (entry RichEntry) CompID() uint32 {
+	return uint32(entry.ProdID)<<16 | uint32(entry.Build)
+}
+
+// VSVersion returns the Visual Studio version of the tool, or an empty string
+// if unknown.
+func (entry RichEntry) VSVersion() string {
+	// Product IDs of Visual Studio 2015 and later are shared, so use the build
+	// number to tell them apart.
+	if entry.ProdID >= RichProdIDAliasObj1400 && entry.ProdID <= RichProdIDUtc1900_POGO_O_CPP {
+		for _, r := range vsBuildRanges {
+			if entry.Build >= r.min && entry.Build <= r.max {
+				return r.version
+			}
+		}
+		return "Visual Studio 2015 or later"
+	}
+	for _, r := range vsProdIDRanges {
+		if entry.ProdID >= r.min && entry.ProdID <= r.max {
+			return r.version
+		}
+	}
+	return ""
+}
+
+func (entry RichEntry) String() string {
+	if v := entry.VSVersion(); len(v) > 0 {
+		return fmt.Sprintf("%v build %d (%s) count=%d", entry.ProdID, entry.Build, v, entry.Count)
+	}
+	return fmt.Sprintf("%v build %d count=%d", entry.ProdID, entry.Build, entry.Count)
+}
+
+// RichHeader returns the Rich header of file, or nil if not present.
+func (file *File) RichHeader() (rich *RichHeader, err error) {
+	if !file.richParsed {
+		err = file.parseStruct(file.parseRichHeader)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.richHdr, nil
+}
+
+// parseRichHeader parses the Rich header of file.
+func (file *File) parseRichHeader() error {
+	doshdr, err := file.DOSHeader()
+	if err != nil {
+		return err
+	}
+
+	// Read DOS header and DOS stub.
+	size := int64(doshdr.PEHdrOffset)
+	if size <= dosHdrSize {
+		file.richParsed = true
+		return nil
+	}
+	if err := file.checkAlloc("DOS stub", size); err != nil {
+		return err
+	}
+	sr := io.NewSectionReader(file.r, 0, size)
+	buf := make([]byte, size)
+	_, err = io.ReadFull(sr, buf)
+	if err != nil {
+		if err := readError("DOS stub", 0, err); !file.tolerate(err) {
+			return err
+		}
+		file.richParsed = true
+		return nil
+	}
+	rich, err := ParseRichHeader(buf)
+	if err != nil && !file.tolerate(err) {
+		return err
+	}
+	file.richHdr = rich
+	file.richParsed = true
+	return nil
+}
+
+// ParseRichHeader parses the Rich header contained within buf, which holds the
+// contents of the file from offset zero up to the PE header. A nil Rich header
+// is returned if not present.
+func ParseRichHeader(buf []byte) (*RichHeader, error) {
+	// Locate the "Rich" signature, which is followed by the XOR key. The Rich
+	// header is dword aligned.
+	end := -1
+	for i := dosHdrSize; i+8 <= len(buf); i += 4 {
+		if binary.LittleEndian.Uint32(buf[i:]) == richMagic {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		return nil, nil
+	}
+	key := binary.LittleEndian.Uint32(buf[end+4:])
+
+	// Locate the masked "DanS" signature by searching backwards.
+	start := -1
+	for i := end - 4; i >= dosHdrSize; i -= 4 {
+		if binary.LittleEndian.Uint32(buf[i:])^key == dansMagic {
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return nil, formatError("Rich header", int64(end), "unable to locate \"DanS\" signature")
+	}
+
+	// The "DanS" signature is followed by three zero padding dwords, and then
+	// by pairs of comp.id and count dwords.
+	const hdrSize = 16
+	if (end-start-hdrSize)%8 != 0 || end-start < hdrSize {
+		return nil, formatError("Rich header", int64(start), "invalid size %d", end-start)
+	}
+	rich := &RichHeader{
+		Offset: uint32(start),
+		Key:    key,
+	}
+	for i := start + hdrSize; i < end; i += 8 {
+		compID := binary.LittleEndian.Uint32(buf[i:]) ^ key
+		count := binary.LittleEndian.Uint32(buf[i+4:]) ^ key
+		entry := RichEntry{
+			ProdID: RichProdID(compID >> 16),
+			Build:  uint16(compID),
+			Count:  count,
+		}
+		rich.Entries = append(rich.Entries, entry)
+	}
+	rich.Checksum = richChecksum(buf[:start], rich.Entries)
+	return rich, nil
+}
+
+// richChecksum returns the checksum of the Rich header, based on the contents
+// of the file preceding the Rich header (with the PEHdrOffset field of the DOS
+// header zeroed) and the tool entries.
+func richChecksum(buf []byte, entries []RichEntry) uint32 {
+	// File offset of the PEHdrOffset field of the DOS header.
+	const peHdrOffsetOff = 0x3C
+	sum := uint32(len(buf))
+	for i, b := range buf {
+		if i >= peHdrOffsetOff && i < peHdrOffsetOff+4 {
+			continue
+		}
+		sum += bits.RotateLeft32(uint32(b), i)
+	}
+	for _, entry := range entries {
+		sum += bits.RotateLeft32(entry.CompID(), int(entry.Count%32))
+	}
+	return sum
+}
@@ -0,0 +1,97 @@
+package pe
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// richStub is the DOS header, DOS stub and Rich header of cli-32.exe of
+// setuptools, linked with Visual Studio 2008.
+const richStub = "4d5a90000300000004000000ffff0000b800000000000000400000000000000000000000000000000000000000000000000000000000000000000000e00000000e1fba0e00b409cd21b8014ccd21546869732070726f6772616d2063616e6e6f742062652072756e20696e20444f53206d6f64652e0d0d0a24000000000000005953fe6a1d3290391d3290391d3290393af4eb391e3290391d32913946329039a07d0639193290390360143939329039036005390f329039036013396d329039036001391c329039526963681d32903900000000000000000000000000000000"
+
+func TestParseRichHeader(t *testing.T) {
+	buf, err := hex.DecodeString(richStub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rich, err := ParseRichHeader(buf)
+	if err != nil {
+		t.Fatalf("unable to parse Rich header; %v", err)
+	}
+	if rich == nil {
+		t.Fatal("Rich header not found")
+	}
+	if rich.Offset != 0x80 {
+		t.Errorf("offset mismatch; expected 0x80, got 0x%X", rich.Offset)
+	}
+	const key = 0x3990321D
+	if rich.Key != key {
+		t.Errorf("key mismatch; expected 0x%08X, got 0x%08X", uint32(key), rich.Key)
+	}
+	if rich.Checksum != key || !rich.ValidChecksum() {
+		t.Errorf("checksum mismatch; expected 0x%08X, got 0x%08X", uint32(key), rich.Checksum)
+	}
+	golden := []struct {
+		entry   RichEntry
+		name    string
+		version string
+	}{
+		{entry: RichEntry{ProdID: RichProdIDImplib800, Build: 50727, Count: 3}, name: "Implib800", version: "Visual Studio 2005"},
+		{entry: RichEntry{ProdID: RichProdIDImport0, Build: 0, Count: 91}, name: "Import0", version: ""},
+		{entry: RichEntry{ProdID: RichProdIDAliasObj900, Build: 20413, Count: 4}, name: "AliasObj900", version: "Visual Studio 2008"},
+		{entry: RichEntry{ProdID: RichProdIDUtc1500_CPP, Build: 21022, Count: 36}, name: "Utc1500_CPP", version: "Visual Studio 2008"},
+		{entry: RichEntry{ProdID: RichProdIDMasm900, Build: 21022, Count: 18}, name: "Masm900", version: "Visual Studio 2008"},
+		{entry: RichEntry{ProdID: RichProdIDUtc1500_C, Build: 21022, Count: 112}, name: "Utc1500_C", version: "Visual Studio 2008"},
+		{entry: RichEntry{ProdID: RichProdIDLinker900, Build: 21022, Count: 1}, name: "Linker900", version: "Visual Studio 2008"},
+	}
+	if len(rich.Entries) != len(golden) {
+		t.Fatalf("number of entries mismatch; expected %d, got %d", len(golden), len(rich.Entries))
+	}
+	for i, g := range golden {
+		entry := rich.Entries[i]
+		if entry != g.entry {
+			t.Errorf("entry %d: mismatch; expected %v, got %v", i, g.entry, entry)
+			continue
+		}
+		if got := entry.ProdID.String(); got != g.name {
+			t.Errorf("entry %d: product name mismatch; expected %q, got %q", i, g.name, got)
+		}
+		if got := entry.VSVersion(); got != g.version {
+			t.Errorf("entry %d: Visual Studio version mismatch; expected %q, got %q", i, g.version, got)
+		}
+	}
+
+	// Corrupt the DOS stub.
+	buf[0x50] ^= 0xFF
+	rich, err = ParseRichHeader(buf)
+	if err != nil {
+		t.Fatalf("unable to parse corrupted Rich header; %v", err)
+	}
+	if rich.ValidChecksum() {
+		t.Errorf("checksum of corrupted DOS stub unexpectedly valid")
+	}
+}
+
+func TestRichEntryVSVersion(t *testing.T) {
+	golden := []struct {
+		entry RichEntry
+		want  string
+	}{
+		{entry: RichEntry{ProdID: RichProdIDUtc1400_C}, want: "Visual Studio 2005"},
+		{entry: RichEntry{ProdID: RichProdIDUtc1500_C}, want: "Visual Studio 2008"},
+		{entry: RichEntry{ProdID: RichProdIDUtc1700_POGO_O_CPP}, want: "Visual Studio 2012"},
+		{entry: RichEntry{ProdID: RichProdIDAliasObj1200}, want: "Visual Studio 2013"},
+		{entry: RichEntry{ProdID: RichProdIDUtc1800_POGO_O_CPP}, want: "Visual Studio 2013"},
+		// 12.10 toolset.
+		{entry: RichEntry{ProdID: RichProdIDAliasObj1210}, want: "Visual Studio 2013"},
+		{entry: RichEntry{ProdID: RichProdIDUtc1810_POGO_O_CPP}, want: "Visual Studio 2013"},
+		{entry: RichEntry{ProdID: RichProdIDAliasObj1400, Build: 24215}, want: "Visual Studio 2015"},
+		{entry: RichEntry{ProdID: RichProdIDAliasObj1400, Build: 0}, want: "Visual Studio 2015 or later"},
+		{entry: RichEntry{ProdID: RichProdIDUnknown}, want: ""},
+	}
+	for _, g := range golden {
+		if got := g.entry.VSVersion(); got != g.want {
+			t.Errorf("%v: Visual Studio version mismatch; expected %q, got %q", g.entry.ProdID, g.want, got)
+		}
+	}
+}
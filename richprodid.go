@@ -0,0 +1,594 @@
+package pe
+
+import "fmt"
+
+// RichProdID specifies the product identifier of a tool in the Rich header.
+//
+// ref: https://github.com/dishather/richprint/blob/master/comp_id.txt
+type RichProdID uint16
+
+// Rich header product identifiers.
+const (
+	RichProdIDUnknown RichProdID = iota
+	RichProdIDImport0
+	RichProdIDLinker510
+	RichProdIDCvtomf510
+	RichProdIDLinker600
+	RichProdIDCvtomf600
+	RichProdIDCvtres500
+	RichProdIDUtc11_Basic
+	RichProdIDUtc11_C
+	RichProdIDUtc12_Basic
+	RichProdIDUtc12_C
+	RichProdIDUtc12_CPP
+	RichProdIDAliasObj60
+	RichProdIDVisualBasic60
+	RichProdIDMasm613
+	RichProdIDMasm710
+	RichProdIDLinker511
+	RichProdIDCvtomf511
+	RichProdIDMasm614
+	RichProdIDLinker512
+	RichProdIDCvtomf512
+	RichProdIDUtc12_C_Std
+	RichProdIDUtc12_CPP_Std
+	RichProdIDUtc12_C_Book
+	RichProdIDUtc12_CPP_Book
+	RichProdIDImplib700
+	RichProdIDCvtomf700
+	RichProdIDUtc13_Basic
+	RichProdIDUtc13_C
+	RichProdIDUtc13_CPP
+	RichProdIDLinker610
+	RichProdIDCvtomf610
+	RichProdIDLinker601
+	RichProdIDCvtomf601
+	RichProdIDUtc12_1_Basic
+	RichProdIDUtc12_1_C
+	RichProdIDUtc12_1_CPP
+	RichProdIDLinker620
+	RichProdIDCvtomf620
+	RichProdIDAliasObj70
+	RichProdIDLinker621
+	RichProdIDCvtomf621
+	RichProdIDMasm615
+	RichProdIDUtc13_LTCG_C
+	RichProdIDUtc13_LTCG_CPP
+	RichProdIDMasm620
+	RichProdIDILAsm100
+	RichProdIDUtc12_2_Basic
+	RichProdIDUtc12_2_C
+	RichProdIDUtc12_2_CPP
+	RichProdIDUtc12_2_C_Std
+	RichProdIDUtc12_2_CPP_Std
+	RichProdIDUtc12_2_C_Book
+	RichProdIDUtc12_2_CPP_Book
+	RichProdIDImplib622
+	RichProdIDCvtomf622
+	RichProdIDCvtres501
+	RichProdIDUtc13_C_Std
+	RichProdIDUtc13_CPP_Std
+	RichProdIDCvtpgd1300
+	RichProdIDLinker622
+	RichProdIDLinker700
+	RichProdIDExport622
+	RichProdIDExport700
+	RichProdIDMasm700
+	RichProdIDUtc13_POGO_I_C
+	RichProdIDUtc13_POGO_I_CPP
+	RichProdIDUtc13_POGO_O_C
+	RichProdIDUtc13_POGO_O_CPP
+	RichProdIDCvtres700
+	RichProdIDCvtres710p
+	RichProdIDLinker710p
+	RichProdIDCvtomf710p
+	RichProdIDExport710p
+	RichProdIDImplib710p
+	RichProdIDMasm710p
+	RichProdIDUtc1310p_C
+	RichProdIDUtc1310p_CPP
+	RichProdIDUtc1310p_C_Std
+	RichProdIDUtc1310p_CPP_Std
+	RichProdIDUtc1310p_LTCG_C
+	RichProdIDUtc1310p_LTCG_CPP
+	RichProdIDUtc1310p_POGO_I_C
+	RichProdIDUtc1310p_POGO_I_CPP
+	RichProdIDUtc1310p_POGO_O_C
+	RichProdIDUtc1310p_POGO_O_CPP
+	RichProdIDLinker624
+	RichProdIDCvtomf624
+	RichProdIDExport624
+	RichProdIDImplib624
+	RichProdIDLinker710
+	RichProdIDCvtomf710
+	RichProdIDExport710
+	RichProdIDImplib710
+	RichProdIDCvtres710
+	RichProdIDUtc1310_C
+	RichProdIDUtc1310_CPP
+	RichProdIDUtc1310_C_Std
+	RichProdIDUtc1310_CPP_Std
+	RichProdIDUtc1310_LTCG_C
+	RichProdIDUtc1310_LTCG_CPP
+	RichProdIDUtc1310_POGO_I_C
+	RichProdIDUtc1310_POGO_I_CPP
+	RichProdIDUtc1310_POGO_O_C
+	RichProdIDUtc1310_POGO_O_CPP
+	RichProdIDAliasObj710
+	RichProdIDAliasObj710p
+	RichProdIDCvtpgd1310
+	RichProdIDCvtpgd1310p
+	RichProdIDUtc1400_C
+	RichProdIDUtc1400_CPP
+	RichProdIDUtc1400_C_Std
+	RichProdIDUtc1400_CPP_Std
+	RichProdIDUtc1400_LTCG_C
+	RichProdIDUtc1400_LTCG_CPP
+	RichProdIDUtc1400_POGO_I_C
+	RichProdIDUtc1400_POGO_I_CPP
+	RichProdIDUtc1400_POGO_O_C
+	RichProdIDUtc1400_POGO_O_CPP
+	RichProdIDCvtpgd1400
+	RichProdIDLinker800
+	RichProdIDCvtomf800
+	RichProdIDExport800
+	RichProdIDImplib800
+	RichProdIDCvtres800
+	RichProdIDMasm800
+	RichProdIDAliasObj800
+	RichProdIDPhoenixPrerelease
+	RichProdIDUtc1400_CVTCIL_C
+	RichProdIDUtc1400_CVTCIL_CPP
+	RichProdIDUtc1400_LTCG_MSIL
+	RichProdIDUtc1500_C
+	RichProdIDUtc1500_CPP
+	RichProdIDUtc1500_C_Std
+	RichProdIDUtc1500_CPP_Std
+	RichProdIDUtc1500_CVTCIL_C
+	RichProdIDUtc1500_CVTCIL_CPP
+	RichProdIDUtc1500_LTCG_C
+	RichProdIDUtc1500_LTCG_CPP
+	RichProdIDUtc1500_LTCG_MSIL
+	RichProdIDUtc1500_POGO_I_C
+	RichProdIDUtc1500_POGO_I_CPP
+	RichProdIDUtc1500_POGO_O_C
+	RichProdIDUtc1500_POGO_O_CPP
+	RichProdIDCvtpgd1500
+	RichProdIDLinker900
+	RichProdIDExport900
+	RichProdIDImplib900
+	RichProdIDCvtres900
+	RichProdIDMasm900
+	RichProdIDAliasObj900
+	RichProdIDResource
+	RichProdIDAliasObj1000
+	RichProdIDCvtpgd1600
+	RichProdIDCvtres1000
+	RichProdIDExport1000
+	RichProdIDImplib1000
+	RichProdIDLinker1000
+	RichProdIDMasm1000
+	RichProdIDPhx1600_C
+	RichProdIDPhx1600_CPP
+	RichProdIDPhx1600_CVTCIL_C
+	RichProdIDPhx1600_CVTCIL_CPP
+	RichProdIDPhx1600_LTCG_C
+	RichProdIDPhx1600_LTCG_CPP
+	RichProdIDPhx1600_LTCG_MSIL
+	RichProdIDPhx1600_POGO_I_C
+	RichProdIDPhx1600_POGO_I_CPP
+	RichProdIDPhx1600_POGO_O_C
+	RichProdIDPhx1600_POGO_O_CPP
+	RichProdIDUtc1600_C
+	RichProdIDUtc1600_CPP
+	RichProdIDUtc1600_CVTCIL_C
+	RichProdIDUtc1600_CVTCIL_CPP
+	RichProdIDUtc1600_LTCG_C
+	RichProdIDUtc1600_LTCG_CPP
+	RichProdIDUtc1600_LTCG_MSIL
+	RichProdIDUtc1600_POGO_I_C
+	RichProdIDUtc1600_POGO_I_CPP
+	RichProdIDUtc1600_POGO_O_C
+	RichProdIDUtc1600_POGO_O_CPP
+	RichProdIDAliasObj1010
+	RichProdIDCvtpgd1610
+	RichProdIDCvtres1010
+	RichProdIDExport1010
+	RichProdIDImplib1010
+	RichProdIDLinker1010
+	RichProdIDMasm1010
+	RichProdIDUtc1610_C
+	RichProdIDUtc1610_CPP
+	RichProdIDUtc1610_CVTCIL_C
+	RichProdIDUtc1610_CVTCIL_CPP
+	RichProdIDUtc1610_LTCG_C
+	RichProdIDUtc1610_LTCG_CPP
+	RichProdIDUtc1610_LTCG_MSIL
+	RichProdIDUtc1610_POGO_I_C
+	RichProdIDUtc1610_POGO_I_CPP
+	RichProdIDUtc1610_POGO_O_C
+	RichProdIDUtc1610_POGO_O_CPP
+	RichProdIDAliasObj1100
+	RichProdIDCvtpgd1700
+	RichProdIDCvtres1100
+	RichProdIDExport1100
+	RichProdIDImplib1100
+	RichProdIDLinker1100
+	RichProdIDMasm1100
+	RichProdIDUtc1700_C
+	RichProdIDUtc1700_CPP
+	RichProdIDUtc1700_CVTCIL_C
+	RichProdIDUtc1700_CVTCIL_CPP
+	RichProdIDUtc1700_LTCG_C
+	RichProdIDUtc1700_LTCG_CPP
+	RichProdIDUtc1700_LTCG_MSIL
+	RichProdIDUtc1700_POGO_I_C
+	RichProdIDUtc1700_POGO_I_CPP
+	RichProdIDUtc1700_POGO_O_C
+	RichProdIDUtc1700_POGO_O_CPP
+	RichProdIDAliasObj1200
+	RichProdIDCvtpgd1800
+	RichProdIDCvtres1200
+	RichProdIDExport1200
+	RichProdIDImplib1200
+	RichProdIDLinker1200
+	RichProdIDMasm1200
+	RichProdIDUtc1800_C
+	RichProdIDUtc1800_CPP
+	RichProdIDUtc1800_CVTCIL_C
+	RichProdIDUtc1800_CVTCIL_CPP
+	RichProdIDUtc1800_LTCG_C
+	RichProdIDUtc1800_LTCG_CPP
+	RichProdIDUtc1800_LTCG_MSIL
+	RichProdIDUtc1800_POGO_I_C
+	RichProdIDUtc1800_POGO_I_CPP
+	RichProdIDUtc1800_POGO_O_C
+	RichProdIDUtc1800_POGO_O_CPP
+	RichProdIDAliasObj1210
+	RichProdIDCvtpgd1810
+	RichProdIDCvtres1210
+	RichProdIDExport1210
+	RichProdIDImplib1210
+	RichProdIDLinker1210
+	RichProdIDMasm1210
+	RichProdIDUtc1810_C
+	RichProdIDUtc1810_CPP
+	RichProdIDUtc1810_CVTCIL_C
+	RichProdIDUtc1810_CVTCIL_CPP
+	RichProdIDUtc1810_LTCG_C
+	RichProdIDUtc1810_LTCG_CPP
+	RichProdIDUtc1810_LTCG_MSIL
+	RichProdIDUtc1810_POGO_I_C
+	RichProdIDUtc1810_POGO_I_CPP
+	RichProdIDUtc1810_POGO_O_C
+	RichProdIDUtc1810_POGO_O_CPP
+	RichProdIDAliasObj1400
+	RichProdIDCvtpgd1900
+	RichProdIDCvtres1400
+	RichProdIDExport1400
+	RichProdIDImplib1400
+	RichProdIDLinker1400
+	RichProdIDMasm1400
+	RichProdIDUtc1900_C
+	RichProdIDUtc1900_CPP
+	RichProdIDUtc1900_CVTCIL_C
+	RichProdIDUtc1900_CVTCIL_CPP
+	RichProdIDUtc1900_LTCG_C
+	RichProdIDUtc1900_LTCG_CPP
+	RichProdIDUtc1900_LTCG_MSIL
+	RichProdIDUtc1900_POGO_I_C
+	RichProdIDUtc1900_POGO_I_CPP
+	RichProdIDUtc1900_POGO_O_C
+	RichProdIDUtc1900_POGO_O_CPP
+)
+
+// richProdIDName is a map from RichProdID to string description.
+var richProdIDName = map[RichProdID]string{
+	RichProdIDUnknown:             "Unknown",
+	RichProdIDImport0:             "Import0",
+	RichProdIDLinker510:           "Linker510",
+	RichProdIDCvtomf510:           "Cvtomf510",
+	RichProdIDLinker600:           "Linker600",
+	RichProdIDCvtomf600:           "Cvtomf600",
+	RichProdIDCvtres500:           "Cvtres500",
+	RichProdIDUtc11_Basic:         "Utc11_Basic",
+	RichProdIDUtc11_C:             "Utc11_C",
+	RichProdIDUtc12_Basic:         "Utc12_Basic",
+	RichProdIDUtc12_C:             "Utc12_C",
+	RichProdIDUtc12_CPP:           "Utc12_CPP",
+	RichProdIDAliasObj60:          "AliasObj60",
+	RichProdIDVisualBasic60:       "VisualBasic60",
+	RichProdIDMasm613:             "Masm613",
+	RichProdIDMasm710:             "Masm710",
+	RichProdIDLinker511:           "Linker511",
+	RichProdIDCvtomf511:           "Cvtomf511",
+	RichProdIDMasm614:             "Masm614",
+	RichProdIDLinker512:           "Linker512",
+	RichProdIDCvtomf512:           "Cvtomf512",
+	RichProdIDUtc12_C_Std:         "Utc12_C_Std",
+	RichProdIDUtc12_CPP_Std:       "Utc12_CPP_Std",
+	RichProdIDUtc12_C_Book:        "Utc12_C_Book",
+	RichProdIDUtc12_CPP_Book:      "Utc12_CPP_Book",
+	RichProdIDImplib700:           "Implib700",
+	RichProdIDCvtomf700:           "Cvtomf700",
+	RichProdIDUtc13_Basic:         "Utc13_Basic",
+	RichProdIDUtc13_C:             "Utc13_C",
+	RichProdIDUtc13_CPP:           "Utc13_CPP",
+	RichProdIDLinker610:           "Linker610",
+	RichProdIDCvtomf610:           "Cvtomf610",
+	RichProdIDLinker601:           "Linker601",
+	RichProdIDCvtomf601:           "Cvtomf601",
+	RichProdIDUtc12_1_Basic:       "Utc12_1_Basic",
+	RichProdIDUtc12_1_C:           "Utc12_1_C",
+	RichProdIDUtc12_1_CPP:         "Utc12_1_CPP",
+	RichProdIDLinker620:           "Linker620",
+	RichProdIDCvtomf620:           "Cvtomf620",
+	RichProdIDAliasObj70:          "AliasObj70",
+	RichProdIDLinker621:           "Linker621",
+	RichProdIDCvtomf621:           "Cvtomf621",
+	RichProdIDMasm615:             "Masm615",
+	RichProdIDUtc13_LTCG_C:        "Utc13_LTCG_C",
+	RichProdIDUtc13_LTCG_CPP:      "Utc13_LTCG_CPP",
+	RichProdIDMasm620:             "Masm620",
+	RichProdIDILAsm100:            "ILAsm100",
+	RichProdIDUtc12_2_Basic:       "Utc12_2_Basic",
+	RichProdIDUtc12_2_C:           "Utc12_2_C",
+	RichProdIDUtc12_2_CPP:         "Utc12_2_CPP",
+	RichProdIDUtc12_2_C_Std:       "Utc12_2_C_Std",
+	RichProdIDUtc12_2_CPP_Std:     "Utc12_2_CPP_Std",
+	RichProdIDUtc12_2_C_Book:      "Utc12_2_C_Book",
+	RichProdIDUtc12_2_CPP_Book:    "Utc12_2_CPP_Book",
+	RichProdIDImplib622:           "Implib622",
+	RichProdIDCvtomf622:           "Cvtomf622",
+	RichProdIDCvtres501:           "Cvtres501",
+	RichProdIDUtc13_C_Std:         "Utc13_C_Std",
+	RichProdIDUtc13_CPP_Std:       "Utc13_CPP_Std",
+	RichProdIDCvtpgd1300:          "Cvtpgd1300",
+	RichProdIDLinker622:           "Linker622",
+	RichProdIDLinker700:           "Linker700",
+	RichProdIDExport622:           "Export622",
+	RichProdIDExport700:           "Export700",
+	RichProdIDMasm700:             "Masm700",
+	RichProdIDUtc13_POGO_I_C:      "Utc13_POGO_I_C",
+	RichProdIDUtc13_POGO_I_CPP:    "Utc13_POGO_I_CPP",
+	RichProdIDUtc13_POGO_O_C:      "Utc13_POGO_O_C",
+	RichProdIDUtc13_POGO_O_CPP:    "Utc13_POGO_O_CPP",
+	RichProdIDCvtres700:           "Cvtres700",
+	RichProdIDCvtres710p:          "Cvtres710p",
+	RichProdIDLinker710p:          "Linker710p",
+	RichProdIDCvtomf710p:          "Cvtomf710p",
+	RichProdIDExport710p:          "Export710p",
+	RichProdIDImplib710p:          "Implib710p",
+	RichProdIDMasm710p:            "Masm710p",
+	RichProdIDUtc1310p_C:          "Utc1310p_C",
+	RichProdIDUtc1310p_CPP:        "Utc1310p_CPP",
+	RichProdIDUtc1310p_C_Std:      "Utc1310p_C_Std",
+	RichProdIDUtc1310p_CPP_Std:    "Utc1310p_CPP_Std",
+	RichProdIDUtc1310p_LTCG_C:     "Utc1310p_LTCG_C",
+	RichProdIDUtc1310p_LTCG_CPP:   "Utc1310p_LTCG_CPP",
+	RichProdIDUtc1310p_POGO_I_C:   "Utc1310p_POGO_I_C",
+	RichProdIDUtc1310p_POGO_I_CPP: "Utc1310p_POGO_I_CPP",
+	RichProdIDUtc1310p_POGO_O_C:   "Utc1310p_POGO_O_C",
+	RichProdIDUtc1310p_POGO_O_CPP: "Utc1310p_POGO_O_CPP",
+	RichProdIDLinker624:           "Linker624",
+	RichProdIDCvtomf624:           "Cvtomf624",
+	RichProdIDExport624:           "Export624",
+	RichProdIDImplib624:           "Implib624",
+	RichProdIDLinker710:           "Linker710",
+	RichProdIDCvtomf710:           "Cvtomf710",
+	RichProdIDExport710:           "Export710",
+	RichProdIDImplib710:           "Implib710",
+	RichProdIDCvtres710:           "Cvtres710",
+	RichProdIDUtc1310_C:           "Utc1310_C",
+	RichProdIDUtc1310_CPP:         "Utc1310_CPP",
+	RichProdIDUtc1310_C_Std:       "Utc1310_C_Std",
+	RichProdIDUtc1310_CPP_Std:     "Utc1310_CPP_Std",
+	RichProdIDUtc1310_LTCG_C:      "Utc1310_LTCG_C",
+	RichProdIDUtc1310_LTCG_CPP:    "Utc1310_LTCG_CPP",
+	RichProdIDUtc1310_POGO_I_C:    "Utc1310_POGO_I_C",
+	RichProdIDUtc1310_POGO_I_CPP:  "Utc1310_POGO_I_CPP",
+	RichProdIDUtc1310_POGO_O_C:    "Utc1310_POGO_O_C",
+	RichProdIDUtc1310_POGO_O_CPP:  "Utc1310_POGO_O_CPP",
+	RichProdIDAliasObj710:         "AliasObj710",
+	RichProdIDAliasObj710p:        "AliasObj710p",
+	RichProdIDCvtpgd1310:          "Cvtpgd1310",
+	RichProdIDCvtpgd1310p:         "Cvtpgd1310p",
+	RichProdIDUtc1400_C:           "Utc1400_C",
+	RichProdIDUtc1400_CPP:         "Utc1400_CPP",
+	RichProdIDUtc1400_C_Std:       "Utc1400_C_Std",
+	RichProdIDUtc1400_CPP_Std:     "Utc1400_CPP_Std",
+	RichProdIDUtc1400_LTCG_C:      "Utc1400_LTCG_C",
+	RichProdIDUtc1400_LTCG_CPP:    "Utc1400_LTCG_CPP",
+	RichProdIDUtc1400_POGO_I_C:    "Utc1400_POGO_I_C",
+	RichProdIDUtc1400_POGO_I_CPP:  "Utc1400_POGO_I_CPP",
+	RichProdIDUtc1400_POGO_O_C:    "Utc1400_POGO_O_C",
+	RichProdIDUtc1400_POGO_O_CPP:  "Utc1400_POGO_O_CPP",
+	RichProdIDCvtpgd1400:          "Cvtpgd1400",
+	RichProdIDLinker800:           "Linker800",
+	RichProdIDCvtomf800:           "Cvtomf800",
+	RichProdIDExport800:           "Export800",
+	RichProdIDImplib800:           "Implib800",
+	RichProdIDCvtres800:           "Cvtres800",
+	RichProdIDMasm800:             "Masm800",
+	RichProdIDAliasObj800:         "AliasObj800",
+	RichProdIDPhoenixPrerelease:   "PhoenixPrerelease",
+	RichProdIDUtc1400_CVTCIL_C:    "Utc1400_CVTCIL_C",
+	RichProdIDUtc1400_CVTCIL_CPP:  "Utc1400_CVTCIL_CPP",
+	RichProdIDUtc1400_LTCG_MSIL:   "Utc1400_LTCG_MSIL",
+	RichProdIDUtc1500_C:           "Utc1500_C",
+	RichProdIDUtc1500_CPP:         "Utc1500_CPP",
+	RichProdIDUtc1500_C_Std:       "Utc1500_C_Std",
+	RichProdIDUtc1500_CPP_Std:     "Utc1500_CPP_Std",
+	RichProdIDUtc1500_CVTCIL_C:    "Utc1500_CVTCIL_C",
+	RichProdIDUtc1500_CVTCIL_CPP:  "Utc1500_CVTCIL_CPP",
+	RichProdIDUtc1500_LTCG_C:      "Utc1500_LTCG_C",
+	RichProdIDUtc1500_LTCG_CPP:    "Utc1500_LTCG_CPP",
+	RichProdIDUtc1500_LTCG_MSIL:   "Utc1500_LTCG_MSIL",
+	RichProdIDUtc1500_POGO_I_C:    "Utc1500_POGO_I_C",
+	RichProdIDUtc1500_POGO_I_CPP:  "Utc1500_POGO_I_CPP",
+	RichProdIDUtc1500_POGO_O_C:    "Utc1500_POGO_O_C",
+	RichProdIDUtc1500_POGO_O_CPP:  "Utc1500_POGO_O_CPP",
+	RichProdIDCvtpgd1500:          "Cvtpgd1500",
+	RichProdIDLinker900:           "Linker900",
+	RichProdIDExport900:           "Export900",
+	RichProdIDImplib900:           "Implib900",
+	RichProdIDCvtres900:           "Cvtres900",
+	RichProdIDMasm900:             "Masm900",
+	RichProdIDAliasObj900:         "AliasObj900",
+	RichProdIDResource:            "Resource",
+	RichProdIDAliasObj1000:        "AliasObj1000",
+	RichProdIDCvtpgd1600:          "Cvtpgd1600",
+	RichProdIDCvtres1000:          "Cvtres1000",
+	RichProdIDExport1000:          "Export1000",
+	RichProdIDImplib1000:          "Implib1000",
+	RichProdIDLinker1000:          "Linker1000",
+	RichProdIDMasm1000:            "Masm1000",
+	RichProdIDPhx1600_C:           "Phx1600_C",
+	RichProdIDPhx1600_CPP:         "Phx1600_CPP",
+	RichProdIDPhx1600_CVTCIL_C:    "Phx1600_CVTCIL_C",
+	RichProdIDPhx1600_CVTCIL_CPP:  "Phx1600_CVTCIL_CPP",
+	RichProdIDPhx1600_LTCG_C:      "Phx1600_LTCG_C",
+	RichProdIDPhx1600_LTCG_CPP:    "Phx1600_LTCG_CPP",
+	RichProdIDPhx1600_LTCG_MSIL:   "Phx1600_LTCG_MSIL",
+	RichProdIDPhx1600_POGO_I_C:    "Phx1600_POGO_I_C",
+	RichProdIDPhx1600_POGO_I_CPP:  "Phx1600_POGO_I_CPP",
+	RichProdIDPhx1600_POGO_O_C:    "Phx1600_POGO_O_C",
+	RichProdIDPhx1600_POGO_O_CPP:  "Phx1600_POGO_O_CPP",
+	RichProdIDUtc1600_C:           "Utc1600_C",
+	RichProdIDUtc1600_CPP:         "Utc1600_CPP",
+	RichProdIDUtc1600_CVTCIL_C:    "Utc1600_CVTCIL_C",
+	RichProdIDUtc1600_CVTCIL_CPP:  "Utc1600_CVTCIL_CPP",
+	RichProdIDUtc1600_LTCG_C:      "Utc1600_LTCG_C",
+	RichProdIDUtc1600_LTCG_CPP:    "Utc1600_LTCG_CPP",
+	RichProdIDUtc1600_LTCG_MSIL:   "Utc1600_LTCG_MSIL",
+	RichProdIDUtc1600_POGO_I_C:    "Utc1600_POGO_I_C",
+	RichProdIDUtc1600_POGO_I_CPP:  "Utc1600_POGO_I_CPP",
+	RichProdIDUtc1600_POGO_O_C:    "Utc1600_POGO_O_C",
+	RichProdIDUtc1600_POGO_O_CPP:  "Utc1600_POGO_O_CPP",
+	RichProdIDAliasObj1010:        "AliasObj1010",
+	RichProdIDCvtpgd1610:          "Cvtpgd1610",
+	RichProdIDCvtres1010:          "Cvtres1010",
+	RichProdIDExport1010:          "Export1010",
+	RichProdIDImplib1010:          "Implib1010",
+	RichProdIDLinker1010:          "Linker1010",
+	RichProdIDMasm1010:            "Masm1010",
+	RichProdIDUtc1610_C:           "Utc1610_C",
+	RichProdIDUtc1610_CPP:         "Utc1610_CPP",
+	RichProdIDUtc1610_CVTCIL_C:    "Utc1610_CVTCIL_C",
+	RichProdIDUtc1610_CVTCIL_CPP:  "Utc1610_CVTCIL_CPP",
+	RichProdIDUtc1610_LTCG_C:      "Utc1610_LTCG_C",
+	RichProdIDUtc1610_LTCG_CPP:    "Utc1610_LTCG_CPP",
+	RichProdIDUtc1610_LTCG_MSIL:   "Utc1610_LTCG_MSIL",
+	RichProdIDUtc1610_POGO_I_C:    "Utc1610_POGO_I_C",
+	RichProdIDUtc1610_POGO_I_CPP:  "Utc1610_POGO_I_CPP",
+	RichProdIDUtc1610_POGO_O_C:    "Utc1610_POGO_O_C",
+	RichProdIDUtc1610_POGO_O_CPP:  "Utc1610_POGO_O_CPP",
+	RichProdIDAliasObj1100:        "AliasObj1100",
+	RichProdIDCvtpgd1700:          "Cvtpgd1700",
+	RichProdIDCvtres1100:          "Cvtres1100",
+	RichProdIDExport1100:          "Export1100",
+	RichProdIDImplib1100:          "Implib1100",
+	RichProdIDLinker1100:          "Linker1100",
+	RichProdIDMasm1100:            "Masm1100",
+	RichProdIDUtc1700_C:           "Utc1700_C",
+	RichProdIDUtc1700_CPP:         "Utc1700_CPP",
+	RichProdIDUtc1700_CVTCIL_C:    "Utc1700_CVTCIL_C",
+	RichProdIDUtc1700_CVTCIL_CPP:  "Utc1700_CVTCIL_CPP",
+	RichProdIDUtc1700_LTCG_C:      "Utc1700_LTCG_C",
+	RichProdIDUtc1700_LTCG_CPP:    "Utc1700_LTCG_CPP",
+	RichProdIDUtc1700_LTCG_MSIL:   "Utc1700_LTCG_MSIL",
+	RichProdIDUtc1700_POGO_I_C:    "Utc1700_POGO_I_C",
+	RichProdIDUtc1700_POGO_I_CPP:  "Utc1700_POGO_I_CPP",
+	RichProdIDUtc1700_POGO_O_C:    "Utc1700_POGO_O_C",
+	RichProdIDUtc1700_POGO_O_CPP:  "Utc1700_POGO_O_CPP",
+	RichProdIDAliasObj1200:        "AliasObj1200",
+	RichProdIDCvtpgd1800:          "Cvtpgd1800",
+	RichProdIDCvtres1200:          "Cvtres1200",
+	RichProdIDExport1200:          "Export1200",
+	RichProdIDImplib1200:          "Implib1200",
+	RichProdIDLinker1200:          "Linker1200",
+	RichProdIDMasm1200:            "Masm1200",
+	RichProdIDUtc1800_C:           "Utc1800_C",
+	RichProdIDUtc1800_CPP:         "Utc1800_CPP",
+	RichProdIDUtc1800_CVTCIL_C:    "Utc1800_CVTCIL_C",
+	RichProdIDUtc1800_CVTCIL_CPP:  "Utc1800_CVTCIL_CPP",
+	RichProdIDUtc1800_LTCG_C:      "Utc1800_LTCG_C",
+	RichProdIDUtc1800_LTCG_CPP:    "Utc1800_LTCG_CPP",
+	RichProdIDUtc1800_LTCG_MSIL:   "Utc1800_LTCG_MSIL",
+	RichProdIDUtc1800_POGO_I_C:    "Utc1800_POGO_I_C",
+	RichProdIDUtc1800_POGO_I_CPP:  "Utc1800_POGO_I_CPP",
+	RichProdIDUtc1800_POGO_O_C:    "Utc1800_POGO_O_C",
+	RichProdIDUtc1800_POGO_O_CPP:  "Utc1800_POGO_O_CPP",
+	RichProdIDAliasObj1210:        "AliasObj1210",
+	RichProdIDCvtpgd1810:          "Cvtpgd1810",
+	RichProdIDCvtres1210:          "Cvtres1210",
+	RichProdIDExport1210:          "Export1210",
+	RichProdIDImplib1210:          "Implib1210",
+	RichProdIDLinker1210:          "Linker1210",
+	RichProdIDMasm1210:            "Masm1210",
+	RichProdIDUtc1810_C:           "Utc1810_C",
+	RichProdIDUtc1810_CPP:         "Utc1810_CPP",
+	RichProdIDUtc1810_CVTCIL_C:    "Utc1810_CVTCIL_C",
+	RichProdIDUtc1810_CVTCIL_CPP:  "Utc1810_CVTCIL_CPP",
+	RichProdIDUtc1810_LTCG_C:      "Utc1810_LTCG_C",
+	RichProdIDUtc1810_LTCG_CPP:    "Utc1810_LTCG_CPP",
+	RichProdIDUtc1810_LTCG_MSIL:   "Utc1810_LTCG_MSIL",
+	RichProdIDUtc1810_POGO_I_C:    "Utc1810_POGO_I_C",
+	RichProdIDUtc1810_POGO_I_CPP:  "Utc1810_POGO_I_CPP",
+	RichProdIDUtc1810_POGO_O_C:    "Utc1810_POGO_O_C",
+	RichProdIDUtc1810_POGO_O_CPP:  "Utc1810_POGO_O_CPP",
+	RichProdIDAliasObj1400:        "AliasObj1400",
+	RichProdIDCvtpgd1900:          "Cvtpgd1900",
+	RichProdIDCvtres1400:          "Cvtres1400",
+	RichProdIDExport1400:          "Export1400",
+	RichProdIDImplib1400:          "Implib1400",
+	RichProdIDLinker1400:          "Linker1400",
+	RichProdIDMasm1400:            "Masm1400",
+	RichProdIDUtc1900_C:           "Utc1900_C",
+	RichProdIDUtc1900_CPP:         "Utc1900_CPP",
+	RichProdIDUtc1900_CVTCIL_C:    "Utc1900_CVTCIL_C",
+	RichProdIDUtc1900_CVTCIL_CPP:  "Utc1900_CVTCIL_CPP",
+	RichProdIDUtc1900_LTCG_C:      "Utc1900_LTCG_C",
+	RichProdIDUtc1900_LTCG_CPP:    "Utc1900_LTCG_CPP",
+	RichProdIDUtc1900_LTCG_MSIL:   "Utc1900_LTCG_MSIL",
+	RichProdIDUtc1900_POGO_I_C:    "Utc1900_POGO_I_C",
+	RichProdIDUtc1900_POGO_I_CPP:  "Utc1900_POGO_I_CPP",
+	RichProdIDUtc1900_POGO_O_C:    "Utc1900_POGO_O_C",
+	RichProdIDUtc1900_POGO_O_CPP:  "Utc1900_POGO_O_CPP",
+}
+
+func (id RichProdID) String() string {
+	if s, ok := richProdIDName[id]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown product ID: 0x%04X", uint16(id))
+}
+
+// vsProdIDRanges maps ranges of product IDs to Visual Studio versions.
+var vsProdIDRanges = []struct {
+	min, max RichProdID
+	version  string
+}{
+	// Product IDs of the 12.10 toolset (e.g. 18.10 compilers) shipped with
+	// updates of Visual Studio 2013.
+	{min: RichProdIDAliasObj1210, max: RichProdIDUtc1810_POGO_O_CPP, version: "Visual Studio 2013"},
+	{min: RichProdIDAliasObj1200, max: RichProdIDUtc1800_POGO_O_CPP, version: "Visual Studio 2013"},
+	{min: RichProdIDAliasObj1100, max: RichProdIDUtc1700_POGO_O_CPP, version: "Visual Studio 2012"},
+	{min: RichProdIDAliasObj1010, max: RichProdIDUtc1610_POGO_O_CPP, version: "Visual Studio 2010 SP1"},
+	{min: RichProdIDAliasObj1000, max: RichProdIDUtc1600_POGO_O_CPP, version: "Visual Studio 2010"},
+	{min: RichProdIDUtc1500_C, max: RichProdIDAliasObj900, version: "Visual Studio 2008"},
+	{min: RichProdIDUtc1400_C, max: RichProdIDUtc1400_LTCG_MSIL, version: "Visual Studio 2005"},
+	{min: RichProdIDLinker710, max: RichProdIDCvtpgd1310p, version: "Visual Studio .NET 2003"},
+}
+
+// vsBuildRanges maps ranges of build numbers to Visual Studio versions, for
+// the product IDs shared by Visual Studio 2015 and later.
+var vsBuildRanges = []struct {
+	min, max uint16
+	version  string
+}{
+	{min: 23026, max: 24999, version: "Visual Studio 2015"},
+	{min: 25000, max: 27499, version: "Visual Studio 2017"},
+	{min: 27500, max: 30699, version: "Visual Studio 2019"},
+	{min: 30700, max: 39999, version: "Visual Studio 2022"},
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
 )
 
@@ -14,8 +15,12 @@ const sectHdrSize = 40
 
 // SectHeader represents a section header.
 type SectHeader struct {
-	// Section name.
+	// Section name. Long names of the form "/n" are resolved using the COFF
+	// string table.
 	Name string
+	// Section name as stored in the section header; differs from Name for
+	// long names.
+	RawName string
 	// The total size of the section when loaded into memory. The raw section is
 	// zero-padded to fit.
 	VirtSize uint32
@@ -247,7 +252,7 @@ func (flags SectFlag) String() string {
 // SectHeaders returns the section headers of file.
 func (file *File) SectHeaders() (sectHdrs []*SectHeader, err error) {
 	if file.sectHdrs == nil {
-		err = file.parseSectHeaders()
+		err = file.parseStruct(file.parseSectHeaders)
 		if err != nil {
 			return nil, err
 		}
@@ -260,28 +265,45 @@ func (file *File) SectHeaders() (sectHdrs []*SectHeader, err error) {
 func (file *File) parseSectHeaders() error {
 	// The file header (and optional header) is immediately followed by section
 	// headers.
-	doshdr, err := file.DOSHeader()
+	sectHdrsOff, err := file.sectHdrsOffset()
 	if err != nil {
 		return err
 	}
-	optoff := int64(doshdr.PEHdrOffset) + fileHdrSize
 	fileHdr, err := file.FileHeader()
 	if err != nil {
 		return err
 	}
-	sectHdrsOff := optoff + int64(fileHdr.OptHdrSize)
-	sectHdrsSize := int64(fileHdr.NSection) * sectHdrSize
-	sr := io.NewSectionReader(file.r, sectHdrsOff, sectHdrsSize)
+	// In tolerant mode, section headers located entirely past the end of the
+	// file are dropped, and a partial section header is zero-filled.
+	n := int64(fileHdr.NSection)
+	if file.conf.tolerant {
+		end, err := file.r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		if avail := (end - sectHdrsOff + sectHdrSize - 1) / sectHdrSize; n > avail {
+			if avail < 0 {
+				avail = 0
+			}
+			file.addAnomaly(AnomalyTruncated, SeverityError, sectHdrsOff, "section table of %d entries extends past end of file; clamped to %d", n, avail)
+			n = avail
+		}
+	}
+	buf, err := file.readHeader("section table", sectHdrsOff, n*sectHdrSize)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(buf)
 
 	// Parse section headers.
-	file.sectHdrs = make([]*SectHeader, fileHdr.NSection)
-	for i := range file.sectHdrs {
+	sectHdrs := make([]*SectHeader, n)
+	for i := range sectHdrs {
 		var sectHdr sectHeader
-		if err := binary.Read(sr, binary.LittleEndian, &sectHdr); err != nil {
-			return fmt.Errorf("pe.File.parseSectHeaders: error reading section header; %v", err)
-		}
-		file.sectHdrs[i] = &SectHeader{
-			Name:           parseString(sectHdr.Name[:]),
+		binary.Read(r, binary.LittleEndian, &sectHdr)
+		name := parseString(sectHdr.Name[:])
+		sectHdrs[i] = &SectHeader{
+			Name:           name,
+			RawName:        name,
 			VirtSize:       sectHdr.VirtSize,
 			RelAddr:        sectHdr.RelAddr,
 			Size:           sectHdr.Size,
@@ -294,15 +316,250 @@ func (file *File) parseSectHeaders() error {
 		}
 	}
 
+	// Resolve long section names.
+	for _, sectHdr := range sectHdrs {
+		if !strings.HasPrefix(sectHdr.RawName, "/") {
+			continue
+		}
+		name, err := file.longSectName(sectHdr.RawName)
+		if err != nil {
+			if file.tolerate(err) {
+				continue
+			}
+			return err
+		}
+		sectHdr.Name = name
+	}
+
+	file.sectHdrs = sectHdrs
 	return nil
 }
 
+// sectHdrsOffset returns the file offset of the section table, which directly
+// follows the optional header.
+func (file *File) sectHdrsOffset() (int64, error) {
+	optoff, err := file.optHdrOffset()
+	if err != nil {
+		return 0, err
+	}
+	return optoff + int64(file.fileHdr.OptHdrSize), nil
+}
+
+// COFF symbol table entry size.
+const symSize = 18
+
+// longSectName returns the long section name referred to by the given section
+// header name, which is either of the form "/n", where n is a decimal offset
+// into the COFF string table, or "//n", where n is a base64 encoded offset.
+func (file *File) longSectName(rawName string) (string, error) {
+	var off uint64
+	var err error
+	if strings.HasPrefix(rawName, "//") {
+		off, err = parseBase64(rawName[2:])
+	} else {
+		off, err = strconv.ParseUint(rawName[1:], 10, 32)
+	}
+	if err != nil {
+		// Not a long name reference; keep the raw section name.
+		return rawName, nil
+	}
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return "", err
+	}
+	if fileHdr.SymTblOffset == 0 {
+		return rawName, nil
+	}
+	// The string table immediately follows the symbol table.
+	strTblOff := int64(fileHdr.SymTblOffset) + int64(fileHdr.NSymbol)*symSize
+	sr := io.NewSectionReader(file.r, strTblOff+int64(off), maxStringLen)
+	buf := make([]byte, maxStringLen)
+	n, err := io.ReadFull(sr, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", readError("COFF string table", strTblOff+int64(off), err)
+	}
+	return parseString(buf[:n]), nil
+}
+
+// parseBase64 parses the given base64 encoded string table offset, as used by
+// long section names of the form "//n".
+func parseBase64(s string) (uint64, error) {
+	var off uint64
+	for _, c := range []byte(s) {
+		i := strings.IndexByte(base64Alphabet, c)
+		if i == -1 {
+			return 0, strconv.ErrSyntax
+		}
+		off = off<<6 | uint64(i)
+	}
+	return off, nil
+}
+
+// base64Alphabet is the alphabet of base64 encoded string table offsets.
+const base64Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// SectHeader returns the first section header (in section table order) with
+// the given name, or nil if not present. Long section names are matched against
+// the name resolved from the COFF string table.
+func (file *File) SectHeader(name string) (*SectHeader, error) {
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	for _, sectHdr := range sectHdrs {
+		if sectHdr.Name == name {
+			return sectHdr, nil
+		}
+	}
+	return nil, nil
+}
+
+// SectHeaderByRelAddr returns the section header of the section containing the
+// given address, relative to the image base, or nil if the address is not
+// contained within any section (e.g. if it falls within the headers).
+//
+// The extent of a section in memory is VirtSize bytes, or Size bytes if VirtSize
+// is zero. If multiple sections overlap at the address, the first section in
+// section table order is returned.
+func (file *File) SectHeaderByRelAddr(relAddr uint32) (*SectHeader, error) {
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	for _, sectHdr := range sectHdrs {
+		if sectHdr.containsRelAddr(relAddr) {
+			return sectHdr, nil
+		}
+	}
+	return nil, nil
+}
+
+// SectHeaderByOffset returns the section header of the section containing the
+// given file offset, or nil if the offset is not contained within any section
+// (e.g. if it falls within the headers or the overlay).
+//
+// The extent of a section in the file is Size bytes starting at Offset;
+// sections without raw data never match. In mapped layout, the extent is the
+// virtual size of the section starting at its address. If multiple sections
+// overlap at the offset, the first section in section table order is returned.
+func (file *File) SectHeaderByOffset(off int64) (*SectHeader, error) {
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return nil, err
+	}
+	for _, sectHdr := range sectHdrs {
+		if start, size := file.sectExtent(sectHdr); size > 0 && off >= start && off < start+size {
+			return sectHdr, nil
+		}
+	}
+	return nil, nil
+}
+
+// RelAddrToOffset returns the file offset of the given address, relative to the
+// image base. Addresses not contained within any section but preceding the
+// first section map to identical offsets within the headers. An error is
+// returned if the address is not backed by file data (e.g. it falls within the
+// zero-filled tail of a section).
+func (file *File) RelAddrToOffset(relAddr uint32) (int64, error) {
+	off, _, err := file.relAddrToOffset(relAddr)
+	return off, err
+}
+
+// OffsetToRelAddr returns the address, relative to the image base, of the given
+// file offset. Offsets not contained within any section but preceding the
+// first section map to identical addresses within the headers.
+func (file *File) OffsetToRelAddr(off int64) (uint32, error) {
+	sectHdr, err := file.SectHeaderByOffset(off)
+	if err != nil {
+		return 0, err
+	}
+	if sectHdr != nil {
+		start, _ := file.sectExtent(sectHdr)
+		return sectHdr.RelAddr + uint32(off-start), nil
+	}
+	if hdrEnd, err := file.hdrEnd(); err != nil {
+		return 0, err
+	} else if off < hdrEnd {
+		return uint32(off), nil
+	}
+	return 0, formatError("section table", -1, "no section contains file offset 0x%X", off)
+}
+
+// hdrEnd returns the end of the headers; i.e. the lowest address (relative to
+// the image base) of any section, or the size of the headers if the image has
+// no sections.
+func (file *File) hdrEnd() (int64, error) {
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		return 0, err
+	}
+	end := int64(-1)
+	for _, sectHdr := range sectHdrs {
+		if end == -1 || int64(sectHdr.RelAddr) < end {
+			end = int64(sectHdr.RelAddr)
+		}
+	}
+	if end != -1 {
+		return end, nil
+	}
+	fileHdr, err := file.FileHeader()
+	if err != nil {
+		return 0, err
+	}
+	if fileHdr.OptHdrSize == 0 {
+		return 0, nil
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return 0, err
+	}
+	return int64(opthdr.HdrSize), nil
+}
+
+// virtSize returns the size of the section when loaded into memory; VirtSize,
+// or Size if VirtSize is zero.
+func (sectHdr *SectHeader) virtSize() uint32 {
+	if sectHdr.VirtSize == 0 {
+		return sectHdr.Size
+	}
+	return sectHdr.VirtSize
+}
+
+// containsRelAddr reports whether the section contains the given address,
+// relative to the image base.
+func (sectHdr *SectHeader) containsRelAddr(relAddr uint32) bool {
+	return relAddr >= sectHdr.RelAddr && relAddr-sectHdr.RelAddr < sectHdr.virtSize()
+}
+
+// sectExtent returns the offset and size of the contents of the section within
+// the underlying reader, as determined by the image layout. Sections without
+// contents have a size of zero.
+func (file *File) sectExtent(sectHdr *SectHeader) (off, size int64) {
+	if file.conf.layout == LayoutMapped {
+		return int64(sectHdr.RelAddr), int64(sectHdr.virtSize())
+	}
+	if sectHdr.Offset == 0 {
+		return 0, 0
+	}
+	return int64(sectHdr.Offset), int64(sectHdr.Size)
+}
+
 // Section returns the contents of the provided section.
 func (file *File) Section(sectHdr *SectHeader) (data []byte, err error) {
-	sr := io.NewSectionReader(file.r, int64(sectHdr.Offset), int64(sectHdr.Size))
+	sr := file.SectionReader(sectHdr)
+	if err := file.checkAlloc("section contents", sr.Size()); err != nil {
+		return nil, err
+	}
 	return ioutil.ReadAll(sr)
 }
 
+// SectionReader returns a reader of the contents of the provided section, for
+// processing large sections with bounded memory.
+func (file *File) SectionReader(sectHdr *SectHeader) *io.SectionReader {
+	off, size := file.sectExtent(sectHdr)
+	return io.NewSectionReader(file.r, off, size)
+}
+
 // ### [ Helper functions ] ####################################################
 
 // parseString returns a Go version of the NULL-terminated string.
@@ -0,0 +1,255 @@
+package pe
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSectionReader(t *testing.T) {
+	img := synthImage{}.bytes()
+	for _, layout := range []Layout{LayoutFile, LayoutMapped} {
+		data := img
+		if layout == LayoutMapped {
+			file, err := New(bytes.NewReader(img))
+			if err != nil {
+				t.Fatal(err)
+			}
+			load, err := file.Load(synthImageBase)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = load.Mem
+		}
+		file, err := New(bytes.NewReader(data), WithLayout(layout))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sectHdrs, err := file.SectHeaders()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, sectHdr := range sectHdrs {
+			want, err := file.Section(sectHdr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(file.SectionReader(sectHdr))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("layout %d, section %q: contents mismatch", layout, sectHdr.Name)
+			}
+		}
+	}
+}
+
+func TestDOSStubReader(t *testing.T) {
+	img := synthImage{}.bytes()
+	file, err := New(bytes.NewReader(img))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr, err := file.DOSStubReader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := img[dosHdrSize:synthPEHdrOffset]; !bytes.Equal(got, want) {
+		t.Errorf("DOS stub mismatch; expected %d bytes, got %d bytes", len(want), len(got))
+	}
+	stub, err := file.DOSStub()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stub, got) {
+		t.Errorf("DOS stub reader mismatch")
+	}
+}
+
+// synthSectTable returns a synthetic COFF object file of 0x600 bytes with the
+// given section headers, followed by a COFF string table holding the given
+// strings, if any.
+func synthSectTable(sectHdrs []sectHeader, strs string) []byte {
+	const strTblOff = 0x600
+	b := make(synthBuffer, strTblOff)
+	fileHdr := FileHeader{
+		Arch:     ArchAMD64,
+		NSection: uint16(len(sectHdrs)),
+	}
+	if len(strs) > 0 {
+		fileHdr.SymTblOffset = strTblOff
+		b = append(b, make([]byte, 4+len(strs))...)
+		b.put32(strTblOff, uint32(4+len(strs)))
+		b.putString(strTblOff+4, strs)
+	}
+	b.putStruct(0, fileHdr)
+	for i, sectHdr := range sectHdrs {
+		b.putStruct(coffHdrSize+i*sectHdrSize, sectHdr)
+	}
+	return b
+}
+
+// rawSectName returns a section name field holding the given raw name.
+func rawSectName(rawName string) (name [8]byte) {
+	copy(name[:], rawName)
+	return name
+}
+
+func TestLongSectName(t *testing.T) {
+	// String table offsets: 4 ".text$long", 15 ".data$long".
+	const strs = ".text$long\x00.data$long\x00"
+	golden := []struct {
+		// Raw section name.
+		rawName string
+		// Specifies whether to include the string table.
+		noStrTbl bool
+		// Expected resolved name; or empty if an error is expected.
+		want string
+	}{
+		{rawName: ".text", want: ".text"},
+		// Decimal offset.
+		{rawName: "/4", want: ".text$long"},
+		{rawName: "/15", want: ".data$long"},
+		// Base64 offset.
+		{rawName: "//AAAAAE", want: ".text$long"},
+		{rawName: "//P", want: ".data$long"},
+		// Not a long name reference; the raw name is kept.
+		{rawName: "/text", want: "/text"},
+		{rawName: "//AA*A", want: "//AA*A"},
+		{rawName: "/", want: "/"},
+		// No string table; the raw name is kept.
+		{rawName: "/4", noStrTbl: true, want: "/4"},
+		// Offset past the end of the file.
+		{rawName: "/9999999", want: ""},
+	}
+	for _, g := range golden {
+		s := strs
+		if g.noStrTbl {
+			s = ""
+		}
+		data := synthSectTable([]sectHeader{{Name: rawSectName(g.rawName)}}, s)
+		for _, tolerant := range []bool{false, true} {
+			file, err := New(bytes.NewReader(data), WithTolerant(tolerant))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sectHdrs, err := file.SectHeaders()
+			want := g.want
+			if len(want) == 0 {
+				if !tolerant {
+					if err == nil {
+						t.Errorf("%q: expected error", g.rawName)
+					}
+					continue
+				}
+				// In tolerant mode, the raw name is kept.
+				want = g.rawName
+			}
+			if err != nil {
+				t.Errorf("%q: tolerant=%v: %v", g.rawName, tolerant, err)
+				continue
+			}
+			if sectHdrs[0].Name != want || sectHdrs[0].RawName != g.rawName {
+				t.Errorf("%q: tolerant=%v: name mismatch; expected %q (raw %q), got %q (raw %q)", g.rawName, tolerant, want, g.rawName, sectHdrs[0].Name, sectHdrs[0].RawName)
+			}
+			// Sections are looked up by resolved name.
+			if sectHdr, err := file.SectHeader(want); err != nil || sectHdr != sectHdrs[0] {
+				t.Errorf("%q: tolerant=%v: lookup of %q mismatch; got %v (%v)", g.rawName, tolerant, want, sectHdr, err)
+			}
+		}
+	}
+	if file, err := New(bytes.NewReader(synthSectTable([]sectHeader{{Name: rawSectName("/4")}}, strs))); err != nil {
+		t.Fatal(err)
+	} else if sectHdr, err := file.SectHeader("/4"); err != nil || sectHdr != nil {
+		t.Errorf("unexpected section of raw name %q; got %v (%v)", "/4", sectHdr, err)
+	}
+}
+
+func TestSectHeaderByAddr(t *testing.T) {
+	data := synthSectTable([]sectHeader{
+		// Virtual size exceeding the raw size.
+		{Name: rawSectName(".a"), VirtSize: 0x800, RelAddr: 0x1000, Size: 0x200, Offset: 0x200},
+		// Zero virtual size; the raw size is used.
+		{Name: rawSectName(".b"), RelAddr: 0x2000, Size: 0x100, Offset: 0x400},
+		// Overlapping .b in memory.
+		{Name: rawSectName(".c"), VirtSize: 0x200, RelAddr: 0x2080, Size: 0x100, Offset: 0x500},
+		// Uninitialized data.
+		{Name: rawSectName(".d"), VirtSize: 0x100, RelAddr: 0x3000},
+		// Overlapping .b and .c in the file.
+		{Name: rawSectName(".e"), VirtSize: 0x100, RelAddr: 0x4000, Size: 0x100, Offset: 0x480},
+	}, "")
+	file, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	relAddrs := []struct {
+		relAddr uint32
+		// Expected section name; or empty if not contained within any section.
+		want string
+	}{
+		{relAddr: 0x0},    // headers
+		{relAddr: 0x0FFF}, // headers
+		{relAddr: 0x1000, want: ".a"},
+		{relAddr: 0x11FF, want: ".a"},
+		{relAddr: 0x17FF, want: ".a"}, // zero-filled tail
+		{relAddr: 0x1800},             // gap
+		{relAddr: 0x2000, want: ".b"},
+		{relAddr: 0x2080, want: ".b"}, // overlap; first in table order
+		{relAddr: 0x20FF, want: ".b"},
+		{relAddr: 0x2100, want: ".c"}, // past the raw size of .b
+		{relAddr: 0x227F, want: ".c"},
+		{relAddr: 0x2280}, // gap
+		{relAddr: 0x3000, want: ".d"},
+		{relAddr: 0x40FF, want: ".e"},
+		{relAddr: 0x4100}, // past the last section
+	}
+	for _, g := range relAddrs {
+		sectHdr, err := file.SectHeaderByRelAddr(g.relAddr)
+		if err != nil {
+			t.Errorf("address 0x%X: %v", g.relAddr, err)
+			continue
+		}
+		if got := sectName(sectHdr); got != g.want {
+			t.Errorf("address 0x%X: section mismatch; expected %q, got %q", g.relAddr, g.want, got)
+		}
+	}
+	offs := []struct {
+		off int64
+		// Expected section name; or empty if not contained within any section.
+		want string
+	}{
+		{off: 0x0},   // headers
+		{off: 0x1FF}, // headers
+		{off: 0x200, want: ".a"},
+		{off: 0x3FF, want: ".a"},
+		{off: 0x400, want: ".b"},
+		{off: 0x480, want: ".b"}, // overlap; first in table order
+		{off: 0x4FF, want: ".b"},
+		{off: 0x500, want: ".c"}, // overlap; first in table order
+		{off: 0x5FF, want: ".c"},
+		{off: 0x600}, // past the last section
+	}
+	for _, g := range offs {
+		sectHdr, err := file.SectHeaderByOffset(g.off)
+		if err != nil {
+			t.Errorf("offset 0x%X: %v", g.off, err)
+			continue
+		}
+		if got := sectName(sectHdr); got != g.want {
+			t.Errorf("offset 0x%X: section mismatch; expected %q, got %q", g.off, g.want, got)
+		}
+	}
+}
+
+// sectName returns the name of the given section, or an empty string if nil.
+func sectName(sectHdr *SectHeader) string {
+	if sectHdr == nil {
+		return ""
+	}
+	return sectHdr.Name
+}
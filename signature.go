@@ -0,0 +1,697 @@
+package pe
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Signature identifies a packer, protector, installer, compiler or linker by a
+// set of conditions, all of which must hold for the signature to match.
+type Signature struct {
+	// Name of the identified tool (e.g. "UPX").
+	Name string
+	// Kind of the identified tool (e.g. "packer", "protector", "installer",
+	// "compiler" or "linker"); or empty if unspecified.
+	Kind string
+	// Byte patterns located at the entry point.
+	EntryPoint []*BytePattern
+	// Byte patterns located anywhere in the file.
+	Patterns []*BytePattern
+	// Byte patterns located anywhere in the overlay.
+	Overlay []*BytePattern
+	// Section name patterns, as accepted by path.Match (e.g. ".vmp[0-9]").
+	Sections []string
+	// Imported DLLs (e.g. "msvcrt.dll") or functions (e.g.
+	// "msvcrt.dll!__getmainargs"); DLL names are case-insensitive.
+	Imports []string
+	// Rich header product ID name prefixes (e.g. "Utc1900").
+	Rich []string
+	// Indices of data directories which must be present.
+	DataDirs []int
+}
+
+// Condition weights, used to score matching signatures. Byte patterns are
+// weighted by their number of fixed bytes.
+const (
+	sectionWeight = 4
+	importWeight  = 3
+	richWeight    = 2
+	dataDirWeight = 2
+)
+
+// SigMatch represents a signature matching a file.
+type SigMatch struct {
+	// Matching signature.
+	Sig *Signature
+	// Score of the match; the sum of the weights of the conditions of the
+	// signature. Signatures with more specific conditions score higher.
+	Score int
+	// Descriptions of the matching conditions.
+	Evidence []string
+}
+
+func (m *SigMatch) String() string {
+	if len(m.Sig.Kind) > 0 {
+		return fmt.Sprintf("%s (%s, score %d)", m.Sig.Name, m.Sig.Kind, m.Score)
+	}
+	return fmt.Sprintf("%s (score %d)", m.Sig.Name, m.Score)
+}
+
+// SigDB is a database of signatures.
+//
+// The text format of signature databases is a superset of the PEiD database
+// format. Each signature starts with its name in square brackets, followed by
+// one condition per line of the form "key = value". Blank lines and lines
+// starting with '#' or ';' are ignored.
+//
+//	[UPX]
+//	kind = packer
+//	ep = 60 BE ?? ?? ?? ?? 8D BE ?? ?? ?? ?? 57
+//	section = UPX0
+//
+// The following keys are recognized.
+//
+//	kind       kind of the identified tool
+//	ep         byte pattern located at the entry point
+//	pattern    byte pattern located anywhere in the file
+//	overlay    byte pattern located anywhere in the overlay
+//	section    section name pattern
+//	import     imported DLL or function ("dll!func")
+//	rich       Rich header product ID name prefix
+//	datadir    data directory which must be present (e.g. "clr" or "14")
+//	signature  byte pattern located at the entry point if ep_only is true,
+//	           and anywhere in the file otherwise (PEiD)
+//	ep_only    "true" or "false" (PEiD)
+//
+// Byte patterns consist of space-separated hexadecimal bytes, where "??"
+// matches any byte and '?' matches any nibble, and of double-quoted Go string
+// literals (e.g. EF BE AD DE "NullsoftInst").
+type SigDB struct {
+	// Signatures of the database.
+	Sigs []*Signature
+}
+
+// defaultSigs holds the text of the embedded signature database.
+//
+//go:embed signatures.txt
+var defaultSigs string
+
+// DefaultSigDB is the signature database used by File.Identify. It is
+// initialized with embedded signatures of common packers, protectors,
+// installers, compilers and linkers.
+var DefaultSigDB = mustParseSigDB(defaultSigs)
+
+// mustParseSigDB parses the given signature database, and panics on error.
+func mustParseSigDB(s string) *SigDB {
+	db, err := ParseSigDB(strings.NewReader(s))
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// LoadSigDB loads the signature database at path.
+func LoadSigDB(path string) (*SigDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseSigDB(f)
+}
+
+// ParseSigDB parses the signature database read from r.
+func ParseSigDB(r io.Reader) (*SigDB, error) {
+	db := new(SigDB)
+	var sig *Signature
+	// PEiD signature patterns and the ep_only setting of the current
+	// signature, resolved at the end of the signature.
+	var peidPats []*BytePattern
+	epOnly := false
+	var sigLine int
+	flush := func() error {
+		if sig == nil {
+			return nil
+		}
+		if epOnly {
+			sig.EntryPoint = append(sig.EntryPoint, peidPats...)
+		} else {
+			sig.Patterns = append(sig.Patterns, peidPats...)
+		}
+		if sig.empty() {
+			return fmt.Errorf("pe: signature database line %d: signature %q has no conditions", sigLine, sig.Name)
+		}
+		db.Sigs = append(db.Sigs, sig)
+		sig, peidPats, epOnly = nil, nil, false
+		return nil
+	}
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if len(line) == 0 || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("pe: signature database line %d: %s", lineNum, fmt.Sprintf(format, args...))
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, errorf("missing ']' in %q", line)
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if len(name) == 0 {
+				return nil, errorf("empty signature name")
+			}
+			sig = &Signature{Name: name}
+			sigLine = lineNum
+			continue
+		}
+		if sig == nil {
+			return nil, errorf("condition outside of signature")
+		}
+		pos := strings.IndexByte(line, '=')
+		if pos == -1 {
+			return nil, errorf("missing '=' in %q", line)
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:pos]))
+		val := strings.TrimSpace(line[pos+1:])
+		if len(val) == 0 {
+			return nil, errorf("empty value of %q", key)
+		}
+		switch key {
+		case "kind":
+			sig.Kind = val
+		case "ep", "pattern", "overlay", "signature":
+			pat, err := ParseBytePattern(val)
+			if err != nil {
+				return nil, errorf("%v", err)
+			}
+			switch key {
+			case "ep":
+				sig.EntryPoint = append(sig.EntryPoint, pat)
+			case "pattern":
+				sig.Patterns = append(sig.Patterns, pat)
+			case "overlay":
+				sig.Overlay = append(sig.Overlay, pat)
+			case "signature":
+				peidPats = append(peidPats, pat)
+			}
+		case "ep_only":
+			b, err := strconv.ParseBool(val)
+			if err != nil {
+				return nil, errorf("invalid ep_only value %q", val)
+			}
+			epOnly = b
+		case "section":
+			if _, err := path.Match(val, ""); err != nil {
+				return nil, errorf("invalid section name pattern %q", val)
+			}
+			sig.Sections = append(sig.Sections, val)
+		case "import":
+			sig.Imports = append(sig.Imports, val)
+		case "rich":
+			sig.Rich = append(sig.Rich, val)
+		case "datadir":
+			index, ok := dataDirIndex(val)
+			if !ok {
+				return nil, errorf("invalid data directory %q", val)
+			}
+			sig.DataDirs = append(sig.DataDirs, index)
+		default:
+			return nil, errorf("unknown key %q", key)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// dataDirNames maps from data directory names of signature databases to data
+// directory indices.
+var dataDirNames = map[string]int{
+	"export":      DataDirExportTable,
+	"import":      DataDirImportTable,
+	"resource":    DataDirResourceTable,
+	"exception":   DataDirExceptionTable,
+	"certificate": DataDirCertificateTable,
+	"reloc":       DataDirBaseRelocationTable,
+	"debug":       DataDirDebug,
+	"globalptr":   DataDirGlobalPtr,
+	"tls":         DataDirTLSTable,
+	"loadconfig":  DataDirLoadConfigTable,
+	"boundimport": DataDirBoundImport,
+	"iat":         DataDirIAT,
+	"delayimport": DataDirDelayImportDescriptor,
+	"clr":         DataDirCLRHeader,
+}
+
+// dataDirIndex returns the data directory index of the given name or decimal
+// index, and reports whether it is valid.
+func dataDirIndex(s string) (int, bool) {
+	if index, ok := dataDirNames[strings.ToLower(s)]; ok {
+		return index, true
+	}
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 || index >= maxDataDirs {
+		return 0, false
+	}
+	return index, true
+}
+
+// empty reports whether the signature has no conditions.
+func (sig *Signature) empty() bool {
+	return len(sig.EntryPoint) == 0 && len(sig.Patterns) == 0 && len(sig.Overlay) == 0 && len(sig.Sections) == 0 && len(sig.Imports) == 0 && len(sig.Rich) == 0 && len(sig.DataDirs) == 0
+}
+
+// BytePattern is a byte pattern with wildcards.
+type BytePattern struct {
+	// Pattern bytes.
+	bytes []byte
+	// Bit mask of the fixed bits of each pattern byte.
+	mask []byte
+}
+
+// ParseBytePattern parses the given byte pattern, as described by SigDB.
+func ParseBytePattern(s string) (*BytePattern, error) {
+	pat := new(BytePattern)
+	for s = strings.TrimSpace(s); len(s) > 0; s = strings.TrimSpace(s) {
+		// Quoted string.
+		if s[0] == '"' {
+			end := 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string in byte pattern %q", s)
+			}
+			str, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s in byte pattern; %v", s[:end+1], err)
+			}
+			for i := 0; i < len(str); i++ {
+				pat.bytes = append(pat.bytes, str[i])
+				pat.mask = append(pat.mask, 0xFF)
+			}
+			s = s[end+1:]
+			continue
+		}
+		// Hexadecimal byte.
+		if len(s) < 2 || (len(s) > 2 && s[2] != ' ' && s[2] != '\t') {
+			return nil, fmt.Errorf("invalid byte in byte pattern %q", s)
+		}
+		var b, mask byte
+		for _, c := range []byte(s[:2]) {
+			b <<= 4
+			mask <<= 4
+			switch {
+			case c == '?':
+			case '0' <= c && c <= '9':
+				b |= c - '0'
+				mask |= 0xF
+			case 'a' <= c && c <= 'f':
+				b |= c - 'a' + 10
+				mask |= 0xF
+			case 'A' <= c && c <= 'F':
+				b |= c - 'A' + 10
+				mask |= 0xF
+			default:
+				return nil, fmt.Errorf("invalid byte %q in byte pattern", s[:2])
+			}
+		}
+		pat.bytes = append(pat.bytes, b)
+		pat.mask = append(pat.mask, mask)
+		s = s[2:]
+	}
+	if len(pat.bytes) == 0 {
+		return nil, fmt.Errorf("empty byte pattern")
+	}
+	if pat.mask[0] == 0 {
+		return nil, fmt.Errorf("byte pattern starting with wildcard")
+	}
+	return pat, nil
+}
+
+// Len returns the length of the pattern in bytes.
+func (pat *BytePattern) Len() int {
+	return len(pat.bytes)
+}
+
+// weight returns the number of fixed bytes of the pattern.
+func (pat *BytePattern) weight() int {
+	n := 0
+	for _, mask := range pat.mask {
+		if mask == 0xFF {
+			n++
+		}
+	}
+	return n
+}
+
+// String returns the pattern in the format of ParseBytePattern. Runs of at
+// least four printable fixed bytes are represented as quoted strings.
+func (pat *BytePattern) String() string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(pat.bytes); {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if n := pat.printableRun(i); n >= 4 {
+			b.WriteString(strconv.Quote(string(pat.bytes[i : i+n])))
+			i += n
+			continue
+		}
+		v, mask := pat.bytes[i], pat.mask[i]
+		for _, shift := range []uint{4, 0} {
+			if (mask>>shift)&0xF == 0 {
+				b.WriteByte('?')
+			} else {
+				b.WriteByte(hex[(v>>shift)&0xF])
+			}
+		}
+		i++
+	}
+	return b.String()
+}
+
+// printableRun returns the length of the run of printable ASCII fixed bytes
+// starting at index i of the pattern.
+func (pat *BytePattern) printableRun(i int) int {
+	n := 0
+	for ; i+n < len(pat.bytes); n++ {
+		if c := pat.bytes[i+n]; pat.mask[i+n] != 0xFF || c < 0x20 || c > 0x7E {
+			break
+		}
+	}
+	return n
+}
+
+// Match reports whether data starts with the pattern.
+func (pat *BytePattern) Match(data []byte) bool {
+	if len(data) < len(pat.bytes) {
+		return false
+	}
+	for i, b := range pat.bytes {
+		if data[i]&pat.mask[i] != b {
+			return false
+		}
+	}
+	return true
+}
+
+// Index returns the index of the first instance of the pattern in data, or -1
+// if not present.
+func (pat *BytePattern) Index(data []byte) int {
+	// Skip to candidate positions using the first byte, which is fixed unless
+	// it has a wildcard nibble.
+	first := pat.bytes[0]
+	fixed := pat.mask[0] == 0xFF
+	for i := 0; i+len(pat.bytes) <= len(data); i++ {
+		if fixed {
+			j := bytes.IndexByte(data[i:len(data)-len(pat.bytes)+1], first)
+			if j == -1 {
+				return -1
+			}
+			i += j
+		}
+		if pat.Match(data[i:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Identify returns the signatures of DefaultSigDB matching file, ranked by
+// score.
+func (file *File) Identify() ([]*SigMatch, error) {
+	return DefaultSigDB.Match(file)
+}
+
+// Match returns the signatures of the database matching file, ranked by score
+// in descending order. Only the best match of signatures sharing a name is
+// returned. Malformed structures of file fail the conditions depending on
+// them, rather than the match as a whole.
+func (db *SigDB) Match(file *File) ([]*SigMatch, error) {
+	facts, err := file.sigFacts(db)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*SigMatch
+	for _, sig := range db.Sigs {
+		if m := facts.match(sig); m != nil {
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Sig.Name < matches[j].Sig.Name
+	})
+	// Keep the best match of each name.
+	seen := make(map[string]bool)
+	ranked := matches[:0]
+	for _, m := range matches {
+		if seen[m.Sig.Name] {
+			continue
+		}
+		seen[m.Sig.Name] = true
+		ranked = append(ranked, m)
+	}
+	return ranked, nil
+}
+
+// sigFacts holds the properties of a file which signatures are matched
+// against.
+type sigFacts struct {
+	// Bytes at the entry point; or nil if not present.
+	ep []byte
+	// Byte patterns located in the file.
+	found map[*BytePattern]bool
+	// Byte patterns located in the overlay.
+	foundOverlay map[*BytePattern]bool
+	// Section names.
+	sectNames []string
+	// Imported DLLs and functions, in lowercase.
+	imps map[string]bool
+	// Rich header product ID names.
+	richNames []string
+	// Present data directories.
+	dataDirs map[int]bool
+}
+
+// sigFacts collects the properties of file required to match the signatures
+// of db.
+func (file *File) sigFacts(db *SigDB) (*sigFacts, error) {
+	facts := &sigFacts{
+		imps:     make(map[string]bool),
+		dataDirs: make(map[int]bool),
+	}
+	var epLen int
+	var pats, overlayPats []*BytePattern
+	for _, sig := range db.Sigs {
+		for _, pat := range sig.EntryPoint {
+			if pat.Len() > epLen {
+				epLen = pat.Len()
+			}
+		}
+		pats = append(pats, sig.Patterns...)
+		overlayPats = append(overlayPats, sig.Overlay...)
+	}
+
+	// Entry point.
+	if opthdr, err := file.OptHeader(); err == nil && opthdr != nil && opthdr.EntryRelAddr != 0 && epLen > 0 {
+		if off, n, err := file.relAddrToOffset(opthdr.EntryRelAddr); err == nil {
+			if n > int64(epLen) {
+				n = int64(epLen)
+			}
+			buf := make([]byte, n)
+			nread, err := file.r.ReadAt(buf, off)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			facts.ep = buf[:nread]
+		}
+	}
+
+	// Byte patterns of the file and overlay.
+	size, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if facts.found, err = scanPatterns(file.r, 0, size, pats); err != nil {
+		return nil, err
+	}
+	facts.foundOverlay = make(map[*BytePattern]bool)
+	if len(overlayPats) > 0 {
+		if off, size, err := file.overlayExtent(); err == nil && size > 0 {
+			if facts.foundOverlay, err = scanPatterns(file.r, off, size, overlayPats); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Sections.
+	if sectHdrs, err := file.SectHeaders(); err == nil {
+		for _, sectHdr := range sectHdrs {
+			facts.sectNames = append(facts.sectNames, sectHdr.Name)
+		}
+	}
+
+	// Imports.
+	if imps, err := file.Imports(); err == nil {
+		for _, imp := range imps {
+			dll := strings.ToLower(imp.DLL)
+			facts.imps[dll] = true
+			for _, fn := range imp.Funcs {
+				if len(fn.Name) > 0 {
+					facts.imps[dll+"!"+strings.ToLower(fn.Name)] = true
+				}
+			}
+		}
+	}
+
+	// Rich header.
+	if rich, err := file.RichHeader(); err == nil && rich != nil {
+		for _, entry := range rich.Entries {
+			facts.richNames = append(facts.richNames, entry.ProdID.String())
+		}
+	}
+
+	// Data directories.
+	for index := 0; index < maxDataDirs; index++ {
+		dataDir, err := file.dataDir(index)
+		if err != nil {
+			break
+		}
+		if dataDir.RelAddr != 0 && dataDir.Size != 0 {
+			facts.dataDirs[index] = true
+		}
+	}
+	return facts, nil
+}
+
+// scanPatterns locates the given byte patterns in size bytes of r, starting at
+// off, and returns the set of located patterns.
+func scanPatterns(r io.ReaderAt, off, size int64, pats []*BytePattern) (map[*BytePattern]bool, error) {
+	found := make(map[*BytePattern]bool)
+	if len(pats) == 0 {
+		return found, nil
+	}
+	maxLen := 0
+	for _, pat := range pats {
+		if pat.Len() > maxLen {
+			maxLen = pat.Len()
+		}
+	}
+	// Read overlapping chunks, so that patterns crossing chunk boundaries are
+	// located.
+	err := scanChunks(r, off, off+size, maxLen-1, func(cur int64, chunk []byte, last bool) bool {
+		for _, pat := range pats {
+			if !found[pat] && pat.Index(chunk) != -1 {
+				found[pat] = true
+			}
+		}
+		return len(found) < len(pats)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// match returns the match of sig, or nil if any condition fails.
+func (facts *sigFacts) match(sig *Signature) *SigMatch {
+	m := &SigMatch{Sig: sig}
+	for _, pat := range sig.EntryPoint {
+		if !pat.Match(facts.ep) {
+			return nil
+		}
+		m.Score += pat.weight()
+		m.Evidence = append(m.Evidence, fmt.Sprintf("entry point matches %v", pat))
+	}
+	for _, pat := range sig.Patterns {
+		if !facts.found[pat] {
+			return nil
+		}
+		m.Score += pat.weight()
+		m.Evidence = append(m.Evidence, fmt.Sprintf("file contains %v", pat))
+	}
+	for _, pat := range sig.Overlay {
+		if !facts.foundOverlay[pat] {
+			return nil
+		}
+		m.Score += pat.weight()
+		m.Evidence = append(m.Evidence, fmt.Sprintf("overlay contains %v", pat))
+	}
+	for _, pattern := range sig.Sections {
+		name, ok := facts.matchSection(pattern)
+		if !ok {
+			return nil
+		}
+		m.Score += sectionWeight
+		m.Evidence = append(m.Evidence, fmt.Sprintf("section %q", name))
+	}
+	for _, imp := range sig.Imports {
+		if !facts.imps[strings.ToLower(imp)] {
+			return nil
+		}
+		m.Score += importWeight
+		m.Evidence = append(m.Evidence, fmt.Sprintf("imports %s", imp))
+	}
+	for _, prefix := range sig.Rich {
+		name, ok := facts.matchRich(prefix)
+		if !ok {
+			return nil
+		}
+		m.Score += richWeight
+		m.Evidence = append(m.Evidence, fmt.Sprintf("Rich header entry %s", name))
+	}
+	for _, index := range sig.DataDirs {
+		if !facts.dataDirs[index] {
+			return nil
+		}
+		m.Score += dataDirWeight
+		m.Evidence = append(m.Evidence, fmt.Sprintf("data directory %d present", index))
+	}
+	return m
+}
+
+// matchSection returns the name of the first section matching the given
+// pattern, and reports whether one was found.
+func (facts *sigFacts) matchSection(pattern string) (string, bool) {
+	for _, name := range facts.sectNames {
+		if ok, _ := path.Match(pattern, name); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// matchRich returns the first Rich header product ID name with the given
+// prefix, and reports whether one was found.
+func (facts *sigFacts) matchRich(prefix string) (string, bool) {
+	for _, name := range facts.richNames {
+		if strings.HasPrefix(name, prefix) {
+			return name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,220 @@
+package pe
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseBytePattern(t *testing.T) {
+	golden := []struct {
+		in   string
+		want string
+		data []byte
+		err  bool
+	}{
+		{in: "60 be ?? ?? 8D", want: "60 BE ?? ?? 8D", data: []byte{0x60, 0xBE, 1, 2, 0x8D}},
+		{in: "E? ?5", want: "E? ?5", data: []byte{0xE8, 0x75}},
+		{in: `EF "AB\x00"`, want: "EF 41 42 00", data: []byte{0xEF, 'A', 'B', 0}},
+		{in: `"a b"  00`, want: "61 20 62 00", data: []byte("a b\x00")},
+		{in: `FF 20 47 6F "\\x" 00`, want: `FF " Go\\x" 00`, data: []byte("\xFF Go\\x\x00")},
+		{in: "", err: true},
+		{in: "?? 60", err: true},
+		{in: "6", err: true},
+		{in: "600", err: true},
+		{in: "GG", err: true},
+		{in: `"abc`, err: true},
+	}
+	for _, g := range golden {
+		pat, err := ParseBytePattern(g.in)
+		if g.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", g.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error; %v", g.in, err)
+			continue
+		}
+		if got := pat.String(); got != g.want {
+			t.Errorf("%q: pattern mismatch; expected %q, got %q", g.in, g.want, got)
+		}
+		if !pat.Match(g.data) {
+			t.Errorf("%q: expected match of % X", g.in, g.data)
+		}
+		if pat.Match(g.data[:len(g.data)-1]) {
+			t.Errorf("%q: unexpected match of truncated data", g.in)
+		}
+	}
+}
+
+func TestBytePatternIndex(t *testing.T) {
+	pat, err := ParseBytePattern("AA ?? CC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := []struct {
+		data []byte
+		want int
+	}{
+		{data: []byte{0xAA, 0xBB, 0xCC}, want: 0},
+		{data: []byte{0xAA, 0xAA, 0xAA, 0x00, 0xCC}, want: 2},
+		{data: []byte{0xAA, 0xBB, 0xDD, 0xAA, 0xBB}, want: -1},
+		{data: nil, want: -1},
+	}
+	for _, g := range golden {
+		if got := pat.Index(g.data); got != g.want {
+			t.Errorf("% X: index mismatch; expected %d, got %d", g.data, g.want, got)
+		}
+	}
+}
+
+func TestParseSigDB(t *testing.T) {
+	const db = `
+# Comment.
+; PEiD comment.
+[PEiD entry point]
+signature = 60 BE
+ep_only = true
+
+[PEiD anywhere]
+signature = 60 BE
+ep_only = false
+
+[Structural]
+kind = packer
+section = UPX?
+import = kernel32.dll!ExitProcess
+rich = Utc
+datadir = clr
+datadir = 9
+overlay = "ov"
+`
+	sigdb, err := ParseSigDB(strings.NewReader(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sigdb.Sigs) != 3 {
+		t.Fatalf("expected 3 signatures, got %d", len(sigdb.Sigs))
+	}
+	if sig := sigdb.Sigs[0]; len(sig.EntryPoint) != 1 || len(sig.Patterns) != 0 {
+		t.Errorf("%s: expected entry point pattern", sig.Name)
+	}
+	if sig := sigdb.Sigs[1]; len(sig.EntryPoint) != 0 || len(sig.Patterns) != 1 {
+		t.Errorf("%s: expected pattern", sig.Name)
+	}
+	sig := sigdb.Sigs[2]
+	if sig.Kind != "packer" || len(sig.Sections) != 1 || len(sig.Imports) != 1 || len(sig.Rich) != 1 || len(sig.Overlay) != 1 {
+		t.Errorf("%s: conditions mismatch; got %+v", sig.Name, sig)
+	}
+	if len(sig.DataDirs) != 2 || sig.DataDirs[0] != DataDirCLRHeader || sig.DataDirs[1] != DataDirTLSTable {
+		t.Errorf("%s: data directories mismatch; got %v", sig.Name, sig.DataDirs)
+	}
+
+	// Invalid databases.
+	for _, db := range []string{
+		"kind = packer",
+		"[A",
+		"[]",
+		"[A]\n",
+		"[A]\nfoo = bar",
+		"[A]\nsection",
+		"[A]\nsection =",
+		"[A]\nsection = [",
+		"[A]\nep = ?? 00",
+		"[A]\ndatadir = 16",
+		"[A]\nsignature = 00\nep_only = maybe",
+	} {
+		if _, err := ParseSigDB(strings.NewReader(db)); err == nil {
+			t.Errorf("%q: expected error, got nil", db)
+		}
+	}
+}
+
+func TestDefaultSigDB(t *testing.T) {
+	if len(DefaultSigDB.Sigs) == 0 {
+		t.Fatal("empty default signature database")
+	}
+	file, err := New(bytes.NewReader(synthImage{rich: true}.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := file.Identify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, m := range matches {
+		names = append(names, m.Sig.Name)
+	}
+	if got, want := strings.Join(names, ","), "Microsoft Linker,Microsoft Visual C/C++"; got != want {
+		t.Errorf("matches mismatch; expected %q, got %q", want, got)
+	}
+}
+
+func TestSigDBMatch(t *testing.T) {
+	img := synthImage{rich: true}.bytes()
+	ep := img[sectOff(synthSectRelAddr):][:4]
+	db := `
+[Entry point]
+ep = ` + fmt.Sprintf("% X", ep) + `
+
+[Entry point]
+ep = ` + fmt.Sprintf("% X", ep[:2]) + `
+
+[Structure]
+section = .d*
+import = KERNEL32.dll!ExitProcess
+rich = Utc1900
+datadir = export
+
+[Overlay]
+overlay = "OVERLAY"
+
+[Anywhere]
+pattern = "synth.dll"
+
+[Missing section]
+section = .text
+import = kernel32.dll
+
+[Missing import]
+import = user32.dll
+`
+	sigdb, err := ParseSigDB(strings.NewReader(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := append(img, "xxOVERLAYxx"...)
+	file, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches, err := sigdb.Match(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name  string
+		score int
+	}{
+		{name: "Structure", score: sectionWeight + importWeight + richWeight + dataDirWeight},
+		{name: "Anywhere", score: 9},
+		{name: "Overlay", score: 7},
+		{name: "Entry point", score: 4},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("expected %d matches, got %v", len(want), matches)
+	}
+	for i, m := range matches {
+		if m.Sig.Name != want[i].name || m.Score != want[i].score {
+			t.Errorf("match %d mismatch; expected %s (score %d), got %v", i, want[i].name, want[i].score, m)
+		}
+		if len(m.Evidence) == 0 {
+			t.Errorf("%s: missing evidence", m.Sig.Name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package pe
+
+import (
+	"io"
+	"math"
+)
+
+// Stats holds byte statistics of a block of data, such as the contents of a
+// section or the overlay. Stats implements the io.Writer interface to
+// accumulate statistics of streamed data.
+type Stats struct {
+	// Number of bytes.
+	Size int64
+	// Number of occurrences of each byte value.
+	Hist [256]int64
+}
+
+// Entropy threshold of high entropy data, in bits per byte. Compressed and
+// encrypted data typically exceeds 7.2 bits per byte, while code and data of
+// unpacked images rarely exceed 6.8 bits per byte.
+const highEntropy = 7.2
+
+// Critical value of the chi-square distribution with 255 degrees of freedom at
+// the 1% significance level. The byte distribution of data with a lower
+// chi-square value is indistinguishable from uniformly random data.
+const chiSquareCritical = 310.457
+
+// Minimum size of data for the chi-square test to be meaningful; an expected
+// count of five occurrences per byte value.
+const minChiSquareSize = 5 * 256
+
+// NewStats returns the byte statistics of data.
+func NewStats(data []byte) *Stats {
+	s := new(Stats)
+	s.Write(data)
+	return s
+}
+
+// ReadStats returns the byte statistics of the data read from r until EOF.
+func ReadStats(r io.Reader) (*Stats, error) {
+	s := new(Stats)
+	if _, err := io.Copy(s, r); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write adds the bytes of p to the statistics. It always returns len(p), nil.
+func (s *Stats) Write(p []byte) (n int, err error) {
+	for _, b := range p {
+		s.Hist[b]++
+	}
+	s.Size += int64(len(p))
+	return len(p), nil
+}
+
+// Entropy returns the Shannon entropy of the data, in bits per byte; ranging
+// from 0 (constant data) to 8 (uniformly random data).
+func (s *Stats) Entropy() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	var e float64
+	for _, n := range s.Hist {
+		if n == 0 {
+			continue
+		}
+		p := float64(n) / float64(s.Size)
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// ChiSquare returns Pearson's chi-square statistic of the byte distribution of
+// the data, tested against a uniform distribution. Uniformly random (e.g.
+// encrypted) data scores close to 255, while compressed data scores higher and
+// plain code and data score orders of magnitude higher.
+func (s *Stats) ChiSquare() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	expected := float64(s.Size) / 256
+	var chi float64
+	for _, n := range s.Hist {
+		d := float64(n) - expected
+		chi += d * d / expected
+	}
+	return chi
+}
+
+// HighEntropy reports whether the data has high entropy, as is typical of
+// compressed or encrypted data.
+func (s *Stats) HighEntropy() bool {
+	return s.Entropy() >= highEntropy
+}
+
+// Uniform reports whether the byte distribution of the data is
+// indistinguishable from uniformly random data, as is typical of encrypted
+// data. Data smaller than 1280 bytes is never considered uniform, as it is too
+// small for the test to be meaningful.
+func (s *Stats) Uniform() bool {
+	return s.Size >= minChiSquareSize && s.ChiSquare() < chiSquareCritical
+}
+
+// SectionStats returns the byte statistics of the contents of the provided
+// section.
+func (file *File) SectionStats(sectHdr *SectHeader) (*Stats, error) {
+	s, err := ReadStats(file.SectionReader(sectHdr))
+	if err != nil {
+		off, _ := file.sectExtent(sectHdr)
+		return nil, readError("section "+sectHdr.Name, off, err)
+	}
+	return s, nil
+}
+
+// OverlayStats returns the byte statistics of the overlay of file.
+func (file *File) OverlayStats() (*Stats, error) {
+	off, size, err := file.overlayExtent()
+	if err != nil {
+		return nil, err
+	}
+	s, err := ReadStats(io.NewSectionReader(file.r, off, size))
+	if err != nil {
+		return nil, readError("overlay", off, err)
+	}
+	return s, nil
+}
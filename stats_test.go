@@ -0,0 +1,83 @@
+package pe
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	random := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+	golden := []struct {
+		name        string
+		data        []byte
+		entropy     float64
+		highEntropy bool
+		uniform     bool
+	}{
+		{name: "empty", data: nil, entropy: 0},
+		{name: "zero", data: make([]byte, 4096), entropy: 0},
+		{name: "two values", data: bytes.Repeat([]byte{0, 1}, 2048), entropy: 1},
+		{name: "all values", data: bytes.Repeat(allBytes(), 16), entropy: 8, highEntropy: true, uniform: true},
+		{name: "random", data: random, entropy: -1, highEntropy: true, uniform: true},
+	}
+	for _, g := range golden {
+		s := NewStats(g.data)
+		if s.Size != int64(len(g.data)) {
+			t.Errorf("%s: size mismatch; expected %d, got %d", g.name, len(g.data), s.Size)
+		}
+		if g.entropy >= 0 && math.Abs(s.Entropy()-g.entropy) > 1e-9 {
+			t.Errorf("%s: entropy mismatch; expected %v, got %v", g.name, g.entropy, s.Entropy())
+		}
+		if got := s.HighEntropy(); got != g.highEntropy {
+			t.Errorf("%s: high entropy mismatch; expected %v, got %v", g.name, g.highEntropy, got)
+		}
+		if got := s.Uniform(); got != g.uniform {
+			t.Errorf("%s: uniform mismatch; expected %v, got %v (chi-square %v)", g.name, g.uniform, got, s.ChiSquare())
+		}
+	}
+}
+
+func TestSectionStats(t *testing.T) {
+	raw := synthImage{}.bytes()
+	file, err := New(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sectHdrs, err := file.SectHeaders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := file.SectionStats(sectHdrs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewStats(raw[synthSectOffset : synthSectOffset+synthSectSize])
+	if *stats != *want {
+		t.Errorf("section statistics mismatch")
+	}
+
+	// Overlay.
+	file, err = New(bytes.NewReader(append(raw, "overlay"...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err = file.OverlayStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := NewStats([]byte("overlay")); *stats != *want {
+		t.Errorf("overlay statistics mismatch")
+	}
+}
+
+// allBytes returns a slice containing each byte value once.
+func allBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
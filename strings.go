@@ -0,0 +1,263 @@
+package pe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// StringEncoding specifies the encoding of an extracted string.
+type StringEncoding uint8
+
+// String encodings.
+const (
+	// ASCII.
+	StringASCII StringEncoding = iota + 1
+	// UTF-16 little-endian.
+	StringUTF16LE
+)
+
+// stringEncodingName is a map from StringEncoding to string description.
+var stringEncodingName = map[StringEncoding]string{
+	StringASCII:   "ASCII",
+	StringUTF16LE: "UTF-16LE",
+}
+
+func (enc StringEncoding) String() string {
+	if s, ok := stringEncodingName[enc]; ok {
+		return s
+	}
+	return fmt.Sprintf("unknown string encoding: 0x%02X", uint8(enc))
+}
+
+// StringTags is a bitfield which specifies the structures referencing an
+// extracted string.
+type StringTags uint8
+
+// String tags.
+const (
+	// Imported DLL or function name.
+	StringTagImport StringTags = 1 << iota
+	// Exported DLL, function or forwarder name.
+	StringTagExport
+	// Resource name or contents of a resource leaf.
+	StringTagResource
+)
+
+func (tags StringTags) String() string {
+	var names []string
+	if tags&StringTagImport != 0 {
+		names = append(names, "import")
+	}
+	if tags&StringTagExport != 0 {
+		names = append(names, "export")
+	}
+	if tags&StringTagResource != 0 {
+		names = append(names, "resource")
+	}
+	return strings.Join(names, ",")
+}
+
+// ExtractedString represents a printable string extracted from a PE file.
+type ExtractedString struct {
+	// Decoded string.
+	Value string
+	// Encoding of the string.
+	Encoding StringEncoding
+	// File offset of the string.
+	Offset int64
+	// Size of the encoded string in bytes.
+	Size int64
+	// Name of the section containing the string; or empty if located outside
+	// of sections (e.g. in the headers or overlay).
+	Section string
+	// Address of the string, relative to the image base; only valid if Mapped
+	// is set.
+	RelAddr uint32
+	// Specifies whether the string is mapped into memory when the image is
+	// loaded; false for strings located in the overlay.
+	Mapped bool
+	// Structures referencing the string.
+	Tags StringTags
+}
+
+// Strings extracts the printable ASCII and UTF-16LE strings of at least minLen
+// characters from file, and returns them ordered by offset. Printable
+// characters are those between ' ' and '~', and tab. The file is read in a
+// streaming fashion.
+func (file *File) Strings(minLen int) ([]*ExtractedString, error) {
+	if minLen < 1 {
+		minLen = 1
+	}
+	size, err := file.r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	var strs []*ExtractedString
+	ascii := &stringScanner{enc: StringASCII, minLen: minLen, strs: &strs}
+	utf16 := [2]*stringScanner{
+		{enc: StringUTF16LE, minLen: minLen, strs: &strs},
+		{enc: StringUTF16LE, minLen: minLen, strs: &strs},
+	}
+	br := bufio.NewReaderSize(io.NewSectionReader(file.r, 0, size), 1<<16)
+	var prev byte
+	for off := int64(0); ; off++ {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, readError("file contents", off, err)
+		}
+		ascii.add(off, b, isPrintable(b))
+		// Each byte completes a UTF-16 code unit of alternating alignment.
+		if off > 0 {
+			utf16[(off-1)%2].add(off-1, prev, b == 0 && isPrintable(prev))
+		}
+		prev = b
+	}
+	ascii.flush()
+	utf16[0].flush()
+	utf16[1].flush()
+	sort.SliceStable(strs, func(i, j int) bool {
+		return strs[i].Offset < strs[j].Offset
+	})
+
+	if err := file.attributeStrings(strs); err != nil {
+		return nil, err
+	}
+	return strs, nil
+}
+
+// isPrintable reports whether b is a printable ASCII character.
+func isPrintable(b byte) bool {
+	return b >= ' ' && b <= '~' || b == '\t'
+}
+
+// stringScanner accumulates printable strings of a given encoding.
+type stringScanner struct {
+	// String encoding.
+	enc StringEncoding
+	// Minimum number of characters.
+	minLen int
+	// Characters of the current string.
+	buf []byte
+	// File offset of the current string.
+	start int64
+	// Extracted strings.
+	strs *[]*ExtractedString
+}
+
+// add adds the character of the code unit at off, or terminates the current
+// string if the code unit is not printable.
+func (s *stringScanner) add(off int64, c byte, printable bool) {
+	if !printable {
+		s.flush()
+		return
+	}
+	if len(s.buf) == 0 {
+		s.start = off
+	}
+	s.buf = append(s.buf, c)
+}
+
+// flush terminates the current string, recording it if long enough.
+func (s *stringScanner) flush() {
+	if len(s.buf) >= s.minLen {
+		size := int64(len(s.buf))
+		if s.enc == StringUTF16LE {
+			size *= 2
+		}
+		str := &ExtractedString{
+			Value:    string(s.buf),
+			Encoding: s.enc,
+			Offset:   s.start,
+			Size:     size,
+		}
+		*s.strs = append(*s.strs, str)
+	}
+	s.buf = s.buf[:0]
+}
+
+// attributeStrings records the section, address and tags of the given strings.
+func (file *File) attributeStrings(strs []*ExtractedString) error {
+	// Names referenced by imports, exports and resources. Malformed structures
+	// are skipped.
+	impNames := make(map[string]bool)
+	if imps, err := file.Imports(); err == nil {
+		for _, imp := range imps {
+			impNames[strings.ToLower(imp.DLL)] = true
+			for _, fn := range imp.Funcs {
+				if !fn.ByOrdinal {
+					impNames[strings.ToLower(fn.Name)] = true
+				}
+			}
+		}
+	}
+	expNames := make(map[string]bool)
+	if exps, err := file.Exports(); err == nil && exps != nil {
+		expNames[strings.ToLower(exps.DLL)] = true
+		for _, fn := range exps.Funcs {
+			expNames[strings.ToLower(fn.Name)] = true
+			expNames[strings.ToLower(fn.Forwarder)] = true
+		}
+	}
+	rsrcNames := make(map[string]bool)
+	// File offset ranges of resource leaves.
+	var rsrcRanges [][2]int64
+	if root, err := file.Resources(); err == nil && root != nil {
+		root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+			if len(node.Name) > 0 {
+				rsrcNames[node.Name] = true
+			}
+			if !node.IsLeaf() {
+				return
+			}
+			if off, _, err := file.relAddrToOffset(node.Data.RelAddr); err == nil {
+				rsrcRanges = append(rsrcRanges, [2]int64{off, off + int64(node.Data.Size)})
+			}
+		})
+	}
+
+	imageEnd, _, err := file.tailExtent()
+	if err != nil {
+		return err
+	}
+	for _, str := range strs {
+		// Section and address.
+		sectHdr, err := file.SectHeaderByOffset(str.Offset)
+		if err != nil {
+			return err
+		}
+		if sectHdr != nil {
+			str.Section = sectHdr.Name
+		}
+		if file.conf.layout == LayoutMapped || str.Offset < imageEnd {
+			if relAddr, err := file.OffsetToRelAddr(str.Offset); err == nil {
+				str.RelAddr = relAddr
+				str.Mapped = true
+			}
+		}
+
+		// Tags.
+		lower := strings.ToLower(str.Value)
+		if impNames[lower] {
+			str.Tags |= StringTagImport
+		}
+		if expNames[lower] {
+			str.Tags |= StringTagExport
+		}
+		if rsrcNames[str.Value] {
+			str.Tags |= StringTagResource
+		}
+		for _, rng := range rsrcRanges {
+			if str.Offset >= rng[0] && str.Offset < rng[1] {
+				str.Tags |= StringTagResource
+				break
+			}
+		}
+	}
+	return nil
+}
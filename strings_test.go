@@ -0,0 +1,66 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	img := synthImage{}.bytes()
+	data := append(append([]byte(nil), img...), "\x00overlay text\x00\x00\x00w\x00i\x00d\x00e\x00\x00\x00"...)
+	file, err := New(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strs, err := file.Strings(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := make(map[string]*ExtractedString)
+	for i, str := range strs {
+		if i > 0 && str.Offset < strs[i-1].Offset {
+			t.Errorf("strings not ordered by offset")
+		}
+		if got := string(data[str.Offset : str.Offset+str.Size]); str.Encoding == StringASCII && got != str.Value {
+			t.Errorf("%q: offset mismatch; got %q at offset 0x%X", str.Value, got, str.Offset)
+		}
+		found[str.Value] = str
+	}
+	golden := []struct {
+		value    string
+		enc      StringEncoding
+		tags     StringTags
+		mapped   bool
+		section  string
+		relAddr  uint32
+		checkRel bool
+	}{
+		{value: "kernel32.dll", enc: StringASCII, tags: StringTagImport, mapped: true, section: ".data", relAddr: synthDLLRelAddr, checkRel: true},
+		{value: "ExitProcess", enc: StringASCII, tags: StringTagImport, mapped: true, section: ".data"},
+		{value: "synth.dll", enc: StringASCII, tags: StringTagExport, mapped: true, section: ".data"},
+		{value: "Foo", enc: StringASCII, tags: StringTagExport, mapped: true, section: ".data"},
+		{value: "ABC", enc: StringUTF16LE, tags: StringTagResource, mapped: true, section: ".data"},
+		{value: "DATA", enc: StringASCII, tags: StringTagResource, mapped: true, section: ".data"},
+		{value: "overlay text", enc: StringASCII},
+		{value: "wide", enc: StringUTF16LE},
+	}
+	for _, g := range golden {
+		str, ok := found[g.value]
+		if !ok {
+			t.Errorf("%q: string not found", g.value)
+			continue
+		}
+		if str.Encoding != g.enc || str.Tags != g.tags || str.Mapped != g.mapped || str.Section != g.section {
+			t.Errorf("%q: attribution mismatch; got %+v", g.value, str)
+		}
+		if g.checkRel && str.RelAddr != g.relAddr {
+			t.Errorf("%q: address mismatch; expected 0x%X, got 0x%X", g.value, g.relAddr, str.RelAddr)
+		}
+	}
+	// Strings shorter than the minimum length are skipped.
+	for _, str := range strs {
+		if len(str.Value) < 3 {
+			t.Errorf("%q: string shorter than minimum length", str.Value)
+		}
+	}
+}
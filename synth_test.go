@@ -0,0 +1,498 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// Synthetic test images.
+//
+// The images are small but complete: a single read-write-execute section holds
+// an import table (one DLL, importing a function by name and one by ordinal),
+// an export table (one named export and one forwarder), a three level resource
+// tree with a named entry, and a base relocation of a pointer to the start of
+// the section.
+//
+// Image layout (file offset, address relative to the image base):
+//
+//	0x000         DOS header
+//	0x040         DOS stub, holding the Rich header if present
+//	0x080         PE signature, COFF file header, optional header, section table
+//	0x200 0x1000  section ".data"
+//	0x200 0x1000    import directory table
+//	0x240 0x1040    import lookup table
+//	0x280 0x1080    import address table
+//	0x2C0 0x10C0    hint/name table entry
+//	0x300 0x1100    imported DLL name
+//	0x320 0x1120    export directory table
+//	0x350 0x1150    export address table
+//	0x360 0x1160    export name pointer table
+//	0x368 0x1168    export ordinal table
+//	0x370 0x1170    exported DLL name
+//	0x380 0x1180    export name
+//	0x390 0x1190    forwarder name
+//	0x400 0x1200    resource directory tree
+//	0x500 0x1300    resource data
+//	0x580 0x1380    base relocation table
+//	0x5F0 0x13F0    relocated pointer
+//	0x600         end of file
+
+// Layout of synthetic images.
+const (
+	synthPEHdrOffset = 0x80
+	synthFileAlign   = 0x200
+	synthSectAlign   = 0x1000
+	synthSectOffset  = 0x200
+	synthSectRelAddr = 0x1000
+	synthSectSize    = 0x400
+	synthImageSize   = 0x2000
+	synthImageBase   = 0x400000
+
+	synthImportRelAddr   = 0x1000
+	synthILTRelAddr      = 0x1040
+	synthIATRelAddr      = 0x1080
+	synthHintRelAddr     = 0x10C0
+	synthDLLRelAddr      = 0x1100
+	synthExportRelAddr   = 0x1120
+	synthExportSize      = 0x90
+	synthEATRelAddr      = 0x1150
+	synthNameTblRelAddr  = 0x1160
+	synthOrdTblRelAddr   = 0x1168
+	synthExpDLLRelAddr   = 0x1170
+	synthExpNameRelAddr  = 0x1180
+	synthForwarderAddr   = 0x1190
+	synthResourceRelAddr = 0x1200
+	synthResourceSize    = 0x70
+	synthRsrcDataRelAddr = 0x1300
+	synthRelocRelAddr    = 0x1380
+	synthRelocSize       = 12
+	synthPtrRelAddr      = 0x13F0
+)
+
+// synthImage specifies the variant of a synthetic PE image.
+type synthImage struct {
+	// Specifies whether to use the PE32+ format.
+	is64 bool
+	// Specifies whether to include a Rich header in the DOS stub.
+	rich bool
+}
+
+// synthBuffer is a fixed size little-endian output buffer.
+type synthBuffer []byte
+
+func (b synthBuffer) put16(off int, v uint16) { binary.LittleEndian.PutUint16(b[off:], v) }
+func (b synthBuffer) put32(off int, v uint32) { binary.LittleEndian.PutUint32(b[off:], v) }
+func (b synthBuffer) put64(off int, v uint64) { binary.LittleEndian.PutUint64(b[off:], v) }
+
+// putStruct writes the binary representation of v at the given offset.
+func (b synthBuffer) putStruct(off int, v interface{}) {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, v)
+	copy(b[off:], buf.Bytes())
+}
+
+// putString writes the NULL-terminated string s at the given offset.
+func (b synthBuffer) putString(off int, s string) {
+	copy(b[off:], s+"\x00")
+}
+
+// bytes returns the contents of the synthetic image.
+func (img synthImage) bytes() []byte {
+	const fileSize = synthSectOffset + synthSectSize
+	b := make(synthBuffer, fileSize)
+
+	// DOS header.
+	b.put16(0, dosMagic)
+	b.put32(0x3C, synthPEHdrOffset)
+	if img.rich {
+		img.putRichHeader(b)
+	}
+
+	// COFF file header.
+	off := synthPEHdrOffset
+	b.putString(off, "PE\x00")
+	off += 4
+	fileHdr := FileHeader{
+		Arch:       ArchI386,
+		NSection:   1,
+		OptHdrSize: optHdr32Size + maxDataDirs*8,
+		Flags:      FlagExecutable | Flag32bit,
+	}
+	if img.is64 {
+		fileHdr.Arch = ArchAMD64
+		fileHdr.OptHdrSize = optHdr64Size + maxDataDirs*8
+		fileHdr.Flags = FlagExecutable | FlagLargeAddr
+	}
+	b.putStruct(off, fileHdr)
+	off += coffHdrSize
+
+	// Optional header.
+	if img.is64 {
+		b.putStruct(off, OptHeader64{
+			State:            OptState64,
+			DataSize:         synthSectSize,
+			EntryRelAddr:     synthSectRelAddr,
+			CodeBase:         synthSectRelAddr,
+			ImageBase:        synthImageBase,
+			SectAlign:        synthSectAlign,
+			FileAlign:        synthFileAlign,
+			MajorOSVer:       6,
+			ImageSize:        synthImageSize,
+			HdrSize:          synthSectOffset,
+			Subsystem:        SubsystemWinCLI,
+			ReserveStackSize: 0x100000,
+			InitStackSize:    0x1000,
+			ReserveHeapSize:  0x100000,
+			InitHeapSize:     0x1000,
+			NDataDir:         maxDataDirs,
+		})
+		off += optHdr64Size
+	} else {
+		b.putStruct(off, OptHeader32{
+			State:            OptState32,
+			DataSize:         synthSectSize,
+			EntryRelAddr:     synthSectRelAddr,
+			CodeBase:         synthSectRelAddr,
+			DataBase:         synthSectRelAddr,
+			ImageBase:        synthImageBase,
+			SectAlign:        synthSectAlign,
+			FileAlign:        synthFileAlign,
+			MajorOSVer:       6,
+			ImageSize:        synthImageSize,
+			HdrSize:          synthSectOffset,
+			Subsystem:        SubsystemWinCLI,
+			ReserveStackSize: 0x100000,
+			InitStackSize:    0x1000,
+			ReserveHeapSize:  0x100000,
+			InitHeapSize:     0x1000,
+			NDataDir:         maxDataDirs,
+		})
+		off += optHdr32Size
+	}
+	dataDirs := make([]DataDirectory, maxDataDirs)
+	dataDirs[DataDirExportTable] = DataDirectory{RelAddr: synthExportRelAddr, Size: synthExportSize}
+	dataDirs[DataDirImportTable] = DataDirectory{RelAddr: synthImportRelAddr, Size: 2 * 20}
+	dataDirs[DataDirResourceTable] = DataDirectory{RelAddr: synthResourceRelAddr, Size: synthResourceSize}
+	dataDirs[DataDirBaseRelocationTable] = DataDirectory{RelAddr: synthRelocRelAddr, Size: synthRelocSize}
+	dataDirs[DataDirIAT] = DataDirectory{RelAddr: synthIATRelAddr, Size: 3 * img.ptrSize()}
+	b.putStruct(off, dataDirs)
+	off += maxDataDirs * 8
+
+	// Section table.
+	sectHdr := sectHeader{
+		VirtSize: synthSectSize,
+		RelAddr:  synthSectRelAddr,
+		Size:     synthSectSize,
+		Offset:   synthSectOffset,
+		Flags:    SectFlagCode | SectFlagData | SectFlagMemExec | SectFlagMemRead | SectFlagMemWrite,
+	}
+	copy(sectHdr.Name[:], ".data")
+	b.putStruct(off, sectHdr)
+
+	img.putImports(b)
+	img.putExports(b)
+	img.putResources(b)
+	img.putRelocs(b)
+	return b
+}
+
+// ptrSize returns the size in bytes of import lookup table entries.
+func (img synthImage) ptrSize() uint32 {
+	if img.is64 {
+		return 8
+	}
+	return 4
+}
+
+// putRichHeader writes a Rich header to the DOS stub.
+func (img synthImage) putRichHeader(b synthBuffer) {
+	entries := []RichEntry{
+		{ProdID: RichProdIDUtc1900_C, Build: 30148, Count: 3},
+		{ProdID: RichProdIDLinker1400, Build: 30148, Count: 1},
+	}
+	const start = dosHdrSize
+	key := richChecksum(b[:start], entries)
+	off := start
+	b.put32(off, dansMagic^key)
+	for i := 1; i < 4; i++ {
+		b.put32(off+4*i, key)
+	}
+	off += 16
+	for _, entry := range entries {
+		b.put32(off, entry.CompID()^key)
+		b.put32(off+4, entry.Count^key)
+		off += 8
+	}
+	b.put32(off, richMagic)
+	b.put32(off+4, key)
+}
+
+// putImports writes the import table, importing ExitProcess by name and
+// ordinal 7 by ordinal from kernel32.dll.
+func (img synthImage) putImports(b synthBuffer) {
+	b.putStruct(sectOff(synthImportRelAddr), ImportDesc{
+		ILTRelAddr:  synthILTRelAddr,
+		NameRelAddr: synthDLLRelAddr,
+		IATRelAddr:  synthIATRelAddr,
+	})
+	for _, relAddr := range []int{synthILTRelAddr, synthIATRelAddr} {
+		off := sectOff(relAddr)
+		if img.is64 {
+			b.put64(off, synthHintRelAddr)
+			b.put64(off+8, 1<<63|7)
+		} else {
+			b.put32(off, synthHintRelAddr)
+			b.put32(off+4, 1<<31|7)
+		}
+	}
+	b.put16(sectOff(synthHintRelAddr), 1)
+	b.putString(sectOff(synthHintRelAddr)+2, "ExitProcess")
+	b.putString(sectOff(synthDLLRelAddr), "kernel32.dll")
+}
+
+// putExports writes the export table, exporting Foo by name and ordinal 2 as a
+// forwarder to kernel32.ExitProcess.
+func (img synthImage) putExports(b synthBuffer) {
+	b.putStruct(sectOff(synthExportRelAddr), ExportDirectory{
+		NameRelAddr:       synthExpDLLRelAddr,
+		OrdinalBase:       1,
+		NAddr:             2,
+		NName:             1,
+		AddrTblRelAddr:    synthEATRelAddr,
+		NameTblRelAddr:    synthNameTblRelAddr,
+		OrdinalTblRelAddr: synthOrdTblRelAddr,
+	})
+	b.put32(sectOff(synthEATRelAddr), synthSectRelAddr)
+	b.put32(sectOff(synthEATRelAddr)+4, synthForwarderAddr)
+	b.put32(sectOff(synthNameTblRelAddr), synthExpNameRelAddr)
+	b.put16(sectOff(synthOrdTblRelAddr), 0)
+	b.putString(sectOff(synthExpDLLRelAddr), "synth.dll")
+	b.putString(sectOff(synthExpNameRelAddr), "Foo")
+	b.putString(sectOff(synthForwarderAddr), "kernel32.ExitProcess")
+}
+
+// putResources writes the resource directory tree, containing a single icon
+// resource named "ABC" of language 0x409.
+func (img synthImage) putResources(b synthBuffer) {
+	const subdir = 0x80000000
+	base := sectOff(synthResourceRelAddr)
+	// Type directory.
+	b.putStruct(base, ResourceDirectory{NIDEntry: 1})
+	b.putStruct(base+0x10, resourceDirEntry{NameOrID: uint32(ResourceTypeIcon), Offset: subdir | 0x18})
+	// Name directory.
+	b.putStruct(base+0x18, ResourceDirectory{NNameEntry: 1})
+	b.putStruct(base+0x28, resourceDirEntry{NameOrID: subdir | 0x60, Offset: subdir | 0x30})
+	// Language directory.
+	b.putStruct(base+0x30, ResourceDirectory{NIDEntry: 1})
+	b.putStruct(base+0x40, resourceDirEntry{NameOrID: 0x409, Offset: 0x48})
+	// Data entry.
+	b.putStruct(base+0x48, ResourceDataEntry{RelAddr: synthRsrcDataRelAddr, Size: 4})
+	// Name string.
+	name := utf16.Encode([]rune("ABC"))
+	b.put16(base+0x60, uint16(len(name)))
+	b.putStruct(base+0x62, name)
+	copy(b[sectOff(synthRsrcDataRelAddr):], "DATA")
+}
+
+// putRelocs writes the base relocation table, relocating a pointer to the
+// start of the section.
+func (img synthImage) putRelocs(b synthBuffer) {
+	off := sectOff(synthRelocRelAddr)
+	b.put32(off, synthSectRelAddr)
+	b.put32(off+4, synthRelocSize)
+	ptrOff := uint16(synthPtrRelAddr - synthSectRelAddr)
+	if img.is64 {
+		b.put16(off+8, uint16(RelocDir64)<<12|ptrOff)
+		b.put64(sectOff(synthPtrRelAddr), synthImageBase+synthSectRelAddr)
+	} else {
+		b.put16(off+8, uint16(RelocHighLow)<<12|ptrOff)
+		b.put32(sectOff(synthPtrRelAddr), synthImageBase+synthSectRelAddr)
+	}
+	// Padding.
+	b.put16(off+10, uint16(RelocAbsolute)<<12)
+}
+
+// sectOff returns the file offset of the given address, relative to the image
+// base, within the section of synthetic images.
+func sectOff(relAddr int) int {
+	return relAddr - synthSectRelAddr + synthSectOffset
+}
+
+// synthObject returns the contents of a synthetic COFF object file, with a
+// single section named through the string table and a single symbol.
+//
+// Object file layout (file offset):
+//
+//	0x00  COFF file header
+//	0x14  section table
+//	0x40  section ".text$long"
+//	0x50  symbol table
+//	0x62  string table
+func synthObject() []byte {
+	const (
+		sectOffset   = 0x40
+		sectSize     = 0x10
+		symTblOffset = 0x50
+		strTblOffset = symTblOffset + symSize
+	)
+	const longName = ".text$long"
+	b := make(synthBuffer, strTblOffset+4+len(longName)+1)
+	b.putStruct(0, FileHeader{
+		Arch:         ArchAMD64,
+		NSection:     1,
+		SymTblOffset: symTblOffset,
+		NSymbol:      1,
+	})
+	sectHdr := sectHeader{
+		Size:   sectSize,
+		Offset: sectOffset,
+		Flags:  SectFlagCode | SectFlagMemExec | SectFlagMemRead,
+	}
+	copy(sectHdr.Name[:], "/4")
+	b.putStruct(coffHdrSize, sectHdr)
+	// ret
+	b[sectOffset] = 0xC3
+	// Symbol ".text", value 0, section 1, static storage class.
+	copy(b[symTblOffset:], ".text")
+	b.put16(symTblOffset+12, 1)
+	b[symTblOffset+16] = 3
+	// String table, prefixed by its size.
+	b.put32(strTblOffset, uint32(4+len(longName)+1))
+	b.putString(strTblOffset+4, longName)
+	return b
+}
+
+// A synthSeed is a named synthetic input file.
+type synthSeed struct {
+	// Name of the seed.
+	name string
+	// Contents of the file.
+	data []byte
+	// Specifies whether the file is malformed.
+	malformed bool
+}
+
+// synthSeeds returns the synthetic input files of the fuzz corpus.
+func synthSeeds() []synthSeed {
+	pe32 := synthImage{}.bytes()
+	pe64 := synthImage{is64: true}.bytes()
+	obj := synthObject()
+	// mutate returns a copy of buf, modified by f.
+	mutate := func(buf []byte, f func(b synthBuffer)) []byte {
+		b := append(synthBuffer(nil), buf...)
+		f(b)
+		return b
+	}
+	const optHdrOff = synthPEHdrOffset + 4 + coffHdrSize
+	return []synthSeed{
+		{name: "pe32", data: pe32},
+		{name: "pe32_rich", data: synthImage{rich: true}.bytes()},
+		{name: "pe32plus", data: pe64},
+		{name: "pe32plus_rich", data: synthImage{is64: true, rich: true}.bytes()},
+		{name: "obj", data: obj},
+		{name: "empty", data: []byte{}, malformed: true},
+		{name: "mz_only", data: []byte("MZ"), malformed: true},
+		{name: "truncated_headers", data: pe32[:0x190], malformed: true},
+		{name: "truncated_section", data: pe64[:0x380], malformed: true},
+		{name: "truncated_obj", data: obj[:0x30], malformed: true},
+		{name: "bad_pe_offset", data: mutate(pe32, func(b synthBuffer) {
+			b.put32(0x3C, 0xFFFFFFF0)
+		}), malformed: true},
+		{name: "bad_signature", data: mutate(pe32, func(b synthBuffer) {
+			b.putString(synthPEHdrOffset, "NE\x00")
+		}), malformed: true},
+		{name: "many_sections", data: mutate(pe32, func(b synthBuffer) {
+			b.put16(synthPEHdrOffset+4+2, 0xFFFF)
+		}), malformed: true},
+		{name: "many_data_dirs", data: mutate(pe32, func(b synthBuffer) {
+			b.put32(optHdrOff+optHdr32Size-4, 0xFFFF)
+		}), malformed: true},
+		{name: "small_opt_header", data: mutate(pe64, func(b synthBuffer) {
+			b.put16(synthPEHdrOffset+4+16, optHdr64Size-8)
+		}), malformed: true},
+		{name: "import_out_of_bounds", data: mutate(pe32, func(b synthBuffer) {
+			b.put32(sectOff(synthImportRelAddr), 0x7FFF0000)
+		}), malformed: true},
+		{name: "import_unterminated", data: mutate(pe32, func(b synthBuffer) {
+			for off := sectOff(synthImportRelAddr); off < sectOff(synthILTRelAddr); off++ {
+				b[off] = 0xFF
+			}
+		}), malformed: true},
+		{name: "export_count", data: mutate(pe32, func(b synthBuffer) {
+			b.put32(sectOff(synthExportRelAddr)+20, 0xFFFFFFFF)
+		}), malformed: true},
+		{name: "export_ordinal", data: mutate(pe32, func(b synthBuffer) {
+			b.put16(sectOff(synthOrdTblRelAddr), 0xFFFF)
+		}), malformed: true},
+		{name: "resource_loop", data: mutate(pe32, func(b synthBuffer) {
+			b.put32(sectOff(synthResourceRelAddr)+0x14, 0x80000000)
+		}), malformed: true},
+		{name: "resource_entries", data: mutate(pe32, func(b synthBuffer) {
+			b.put16(sectOff(synthResourceRelAddr)+0x0E, 0xFFFF)
+		}), malformed: true},
+		{name: "reloc_block_size", data: mutate(pe32, func(b synthBuffer) {
+			b.put32(sectOff(synthRelocRelAddr)+4, 4)
+		}), malformed: true},
+		{name: "section_size", data: mutate(pe32, func(b synthBuffer) {
+			const sectHdrsOff = optHdrOff + optHdr32Size + maxDataDirs*8
+			b.put32(sectHdrsOff+16, 0x7365636F)
+			b.put32(sectHdrsOff+20, 0x73)
+		}), malformed: true},
+		{name: "rich_truncated", data: mutate(synthImage{rich: true}.bytes(), func(b synthBuffer) {
+			b.put32(dosHdrSize, 0)
+		}), malformed: true},
+	}
+}
+
+// synthReproImage returns a synthetic image with a debug directory holding
+// CodeView and Repro debugging information, and the given nondeterministic
+// values.
+func synthReproImage(created, checksum uint32, guid byte, hash byte) []byte {
+	const (
+		debugRelAddr     = 0x1A0 // located in the headers.
+		codeViewRelAddr  = 0x13A0
+		reproRelAddr     = 0x13C0
+		reproHashSize    = 32
+		optHdrOff        = synthPEHdrOffset + 4 + coffHdrSize
+		debugDataDirOff  = optHdrOff + optHdr32Size + DataDirDebug*8
+		checksumOff      = optHdrOff + 64
+		fileHdrCreateOff = synthPEHdrOffset + 4 + 4
+	)
+	b := synthBuffer(synthImage{}.bytes())
+	b.put32(fileHdrCreateOff, created)
+	b.put32(checksumOff, checksum)
+	b.put32(sectOff(synthExportRelAddr)+4, created)
+	b.put32(sectOff(synthResourceRelAddr)+4, created)
+	b.put32(debugDataDirOff, debugRelAddr)
+	b.put32(debugDataDirOff+4, 2*debugDirSize)
+	b.putStruct(debugRelAddr, []ImageDebugDirectory{
+		{
+			TimeDateStamp:    created,
+			Type:             ImageDebugTypeCodeView,
+			SizeOfData:       30,
+			AddressOfRawData: codeViewRelAddr,
+			PointerToRawData: uint32(sectOff(codeViewRelAddr)),
+		},
+		{
+			TimeDateStamp:    created,
+			Type:             ImageDebugTypeRepro,
+			SizeOfData:       4 + reproHashSize,
+			AddressOfRawData: reproRelAddr,
+			PointerToRawData: uint32(sectOff(reproRelAddr)),
+		},
+	})
+	off := sectOff(codeViewRelAddr)
+	b.putString(off, "RSDS")
+	for i := 0; i < 16; i++ {
+		b[off+4+i] = guid
+	}
+	b.put32(off+20, uint32(guid))
+	b.putString(off+24, "a.pdb")
+	off = sectOff(reproRelAddr)
+	b.put32(off, reproHashSize)
+	for i := 0; i < reproHashSize; i++ {
+		b[off+4+i] = hash
+	}
+	return b
+}
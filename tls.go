@@ -0,0 +1,133 @@
+package pe
+
+import "encoding/binary"
+
+// TLSDirectory represents the thread local storage (TLS) directory, which
+// specifies the TLS template data and callbacks of an image. The addresses are
+// virtual addresses (not relative to the image base), widened to 64 bits for
+// 32-bit images.
+type TLSDirectory struct {
+	// Virtual address of the start of the TLS template data.
+	RawDataStart uint64
+	// Virtual address of the end of the TLS template data.
+	RawDataEnd uint64
+	// Virtual address of the TLS index, assigned by the loader.
+	IndexAddr uint64
+	// Virtual address of the NULL-terminated array of TLS callback pointers.
+	CallbacksAddr uint64
+	// Size in bytes of the zero-filled data succeeding the template data.
+	ZeroFillSize uint32
+	// Alignment of the TLS template data.
+	Flags uint32
+}
+
+// TLS represents the thread local storage of an image.
+type TLS struct {
+	// TLS directory.
+	TLSDirectory
+	// Addresses of the TLS callback functions, relative to the image base, in
+	// the order of the callback array.
+	Callbacks []uint32
+}
+
+// TLS returns the thread local storage of file; or nil if not present.
+func (file *File) TLS() (tls *TLS, err error) {
+	if !file.tlsParsed {
+		err = file.parseStruct(file.parseTLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file.tls, nil
+}
+
+// parseTLS parses the TLS directory and callbacks of file.
+func (file *File) parseTLS() error {
+	dataDir, err := file.dataDir(DataDirTLSTable)
+	if err != nil {
+		return err
+	}
+	if dataDir.RelAddr == 0 {
+		file.tlsParsed = true
+		return nil
+	}
+	opthdr, err := file.OptHeader()
+	if err != nil {
+		return err
+	}
+
+	// Parse TLS directory.
+	tls := new(TLS)
+	if opthdr.Is64() {
+		if err := file.readRelAddr(dataDir.RelAddr, &tls.TLSDirectory, "TLS directory"); err != nil {
+			return err
+		}
+	} else {
+		var dir struct {
+			RawDataStart, RawDataEnd, IndexAddr, CallbacksAddr uint32
+			ZeroFillSize, Flags                                uint32
+		}
+		if err := file.readRelAddr(dataDir.RelAddr, &dir, "TLS directory"); err != nil {
+			return err
+		}
+		tls.TLSDirectory = TLSDirectory{
+			RawDataStart:  uint64(dir.RawDataStart),
+			RawDataEnd:    uint64(dir.RawDataEnd),
+			IndexAddr:     uint64(dir.IndexAddr),
+			CallbacksAddr: uint64(dir.CallbacksAddr),
+			ZeroFillSize:  dir.ZeroFillSize,
+			Flags:         dir.Flags,
+		}
+	}
+
+	// Parse TLS callback array. In tolerant mode, parsing stops at the first
+	// malformed entry, keeping the callbacks parsed so far.
+	fail := func(err error) error {
+		if !file.tolerate(err) {
+			return err
+		}
+		file.tls = tls
+		file.tlsParsed = true
+		return nil
+	}
+	if tls.CallbacksAddr != 0 {
+		if tls.CallbacksAddr < opthdr.ImageBase64 || tls.CallbacksAddr-opthdr.ImageBase64 > 0xFFFFFFFF {
+			return fail(formatError("TLS callback array", -1, "address 0x%X outside of image", tls.CallbacksAddr))
+		}
+		relAddr := uint32(tls.CallbacksAddr - opthdr.ImageBase64)
+		ptrSize := uint32(4)
+		if opthdr.Is64() {
+			ptrSize = 8
+		}
+		maxEntries := file.conf.limits.MaxDirEntries
+		for i := 0; ; i++ {
+			if err := checkLimit(limitDirEntries, "TLS callback array", int64(i+1), int64(maxEntries)); err != nil {
+				return fail(err)
+			}
+			buf := make([]byte, ptrSize)
+			// The NULL terminator is frequently located in the zero-filled tail
+			// of a section.
+			if err := file.readMemRelAddr(relAddr+uint32(i)*ptrSize, buf, "TLS callback array"); err != nil {
+				return fail(err)
+			}
+			var va uint64
+			if opthdr.Is64() {
+				va = binary.LittleEndian.Uint64(buf)
+			} else {
+				va = uint64(binary.LittleEndian.Uint32(buf))
+			}
+			if va == 0 {
+				break
+			}
+			if va < opthdr.ImageBase64 || va-opthdr.ImageBase64 > 0xFFFFFFFF {
+				return fail(formatError("TLS callback", -1, "address 0x%X outside of image", va))
+			}
+			tls.Callbacks = append(tls.Callbacks, uint32(va-opthdr.ImageBase64))
+		}
+	}
+
+	file.tls = tls
+	file.tlsParsed = true
+	return nil
+}
@@ -0,0 +1,93 @@
+package pe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTLS(t *testing.T) {
+	const (
+		tlsRelAddr       = 0x13A0
+		callbacksRelAddr = 0x1C0 // located in the headers.
+		dataDirOff       = synthPEHdrOffset + 4 + coffHdrSize + optHdr32Size + DataDirTLSTable*8
+	)
+	b := synthBuffer(synthImage{}.bytes())
+	b.put32(dataDirOff, tlsRelAddr)
+	b.put32(dataDirOff+4, 24)
+	off := sectOff(tlsRelAddr)
+	b.put32(off, synthImageBase+0x13F0)
+	b.put32(off+4, synthImageBase+0x13F4)
+	b.put32(off+8, synthImageBase+0x13F8)
+	b.put32(off+12, synthImageBase+callbacksRelAddr)
+	b.put32(callbacksRelAddr, synthImageBase+0x1000)
+	b.put32(callbacksRelAddr+4, synthImageBase+0x1010)
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tls, err := file.TLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tls == nil {
+		t.Fatal("expected TLS directory")
+	}
+	if tls.CallbacksAddr != synthImageBase+callbacksRelAddr {
+		t.Errorf("callback array address mismatch; expected 0x%X, got 0x%X", synthImageBase+callbacksRelAddr, tls.CallbacksAddr)
+	}
+	want := []uint32{0x1000, 0x1010}
+	if len(tls.Callbacks) != len(want) || tls.Callbacks[0] != want[0] || tls.Callbacks[1] != want[1] {
+		t.Errorf("callbacks mismatch; expected %#x, got %#x", want, tls.Callbacks)
+	}
+
+	// Callback located outside of the image.
+	b.put32(callbacksRelAddr+4, 0x10)
+	file, err = New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.TLS(); err == nil {
+		t.Errorf("expected error for TLS callback outside of image")
+	}
+	file, err = New(bytes.NewReader(b), WithTolerant(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tls, err = file.TLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tls.Callbacks) != 1 {
+		t.Errorf("expected 1 callback in tolerant mode, got %d", len(tls.Callbacks))
+	}
+}
+
+// TestTLSVirtualTail checks that the NULL terminator of the TLS callback array
+// may be located in the zero-filled tail of a section, past its raw data.
+func TestTLSVirtualTail(t *testing.T) {
+	const (
+		tlsRelAddr       = 0x13A0
+		callbacksRelAddr = synthSectRelAddr + synthSectSize - 4 // last dword of raw data.
+		dataDirOff       = synthPEHdrOffset + 4 + coffHdrSize + optHdr32Size + DataDirTLSTable*8
+		sectHdrOff       = synthPEHdrOffset + 4 + coffHdrSize + optHdr32Size + maxDataDirs*8
+	)
+	b := synthBuffer(synthImage{}.bytes())
+	b.put32(sectHdrOff+8, 2*synthSectSize) // VirtSize
+	b.put32(dataDirOff, tlsRelAddr)
+	b.put32(dataDirOff+4, 24)
+	b.put32(sectOff(tlsRelAddr)+12, synthImageBase+callbacksRelAddr)
+	b.put32(sectOff(callbacksRelAddr), synthImageBase+0x1000)
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tls, err := file.TLS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tls.Callbacks) != 1 || tls.Callbacks[0] != 0x1000 {
+		t.Errorf("callbacks mismatch; expected [0x1000], got %#x", tls.Callbacks)
+	}
+}
@@ -0,0 +1,257 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// FixedFileInfo represents the language-independent version information of a
+// file (VS_FIXEDFILEINFO).
+type FixedFileInfo struct {
+	// Signature; 0xFEEF04BD.
+	Signature uint32
+	// Version of the structure.
+	StrucVer uint32
+	// Most and least significant 32 bits of the file version.
+	FileVerMS, FileVerLS uint32
+	// Most and least significant 32 bits of the product version.
+	ProductVerMS, ProductVerLS uint32
+	// Bitmask of the valid bits of FileFlags.
+	FileFlagsMask uint32
+	// Attributes of the file (e.g. debug, prerelease, patched).
+	FileFlags uint32
+	// Operating system the file was designed for.
+	FileOS uint32
+	// General type of the file (e.g. application, DLL, driver).
+	FileType uint32
+	// Function of the file, for drivers and fonts.
+	FileSubtype uint32
+	// Most and least significant 32 bits of the file creation date.
+	FileDateMS, FileDateLS uint32
+}
+
+// fixedFileInfoSignature is the signature of VS_FIXEDFILEINFO structures.
+const fixedFileInfoSignature = 0xFEEF04BD
+
+// FileVer returns the file version in dotted notation (e.g. "10.0.19041.1").
+func (info *FixedFileInfo) FileVer() string {
+	return versionString(info.FileVerMS, info.FileVerLS)
+}
+
+// ProductVer returns the product version in dotted notation.
+func (info *FixedFileInfo) ProductVer() string {
+	return versionString(info.ProductVerMS, info.ProductVerLS)
+}
+
+// versionString returns the dotted notation of the given 64-bit version.
+func versionString(ms, ls uint32) string {
+	return fmt.Sprintf("%d.%d.%d.%d", ms>>16, ms&0xFFFF, ls>>16, ls&0xFFFF)
+}
+
+// VersionString represents a string of the language-specific version
+// information of a file.
+type VersionString struct {
+	// Language and code page of the string table, as eight hexadecimal digits
+	// (e.g. "040904B0" for U.S. English, Unicode).
+	Lang string
+	// Name of the string (e.g. "CompanyName", "FileVersion").
+	Key string
+	// Value of the string.
+	Value string
+}
+
+// VersionInfo represents the version information resource of a file
+// (VS_VERSIONINFO).
+type VersionInfo struct {
+	// Language-independent version information; or nil if not present.
+	Fixed *FixedFileInfo
+	// Strings of the string tables, in resource order.
+	Strings []*VersionString
+	// Languages and code pages supported by the file, with the language in the
+	// low 16 bits and the code page in the high 16 bits.
+	Translations []uint32
+}
+
+// Lookup returns the value of the first string with the given name (e.g.
+// "ProductName"); or an empty string if not present.
+func (info *VersionInfo) Lookup(key string) string {
+	for _, s := range info.Strings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// VersionInfo returns the version information of file, as stored in the first
+// version resource; or nil if not present.
+func (file *File) VersionInfo() (*VersionInfo, error) {
+	root, err := file.Resources()
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return nil, nil
+	}
+	var leaf *ResourceDataEntry
+	root.Walk(func(path []*ResourceNode, node *ResourceNode) {
+		if leaf == nil && node.IsLeaf() && len(path[0].Name) == 0 && path[0].ID == ResourceTypeVersion {
+			leaf = node.Data
+		}
+	})
+	if leaf == nil {
+		return nil, nil
+	}
+	data, err := file.ResourceData(leaf)
+	if err != nil {
+		return nil, err
+	}
+	return parseVersionInfo(data)
+}
+
+// versionBlock represents a block of the version information resource. Each
+// block holds a key, an optional value and child blocks.
+type versionBlock struct {
+	// Name of the block.
+	key string
+	// Value of the block.
+	value []byte
+	// Specifies whether the value is a UTF-16 string.
+	text bool
+	// Child blocks.
+	children []*versionBlock
+}
+
+// maxVersionDepth specifies the maximum nesting depth of version blocks; i.e.
+// VS_VERSIONINFO, StringFileInfo, StringTable and String.
+const maxVersionDepth = 4
+
+// parseVersionInfo parses the given version information resource.
+func parseVersionInfo(data []byte) (*VersionInfo, error) {
+	root, _, err := parseVersionBlock(data, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	if root.key != "VS_VERSION_INFO" {
+		return nil, formatError("version information", -1, "invalid key %q", root.key)
+	}
+	info := new(VersionInfo)
+	if len(root.value) > 0 {
+		fixed := new(FixedFileInfo)
+		if err := binary.Read(bytes.NewReader(root.value), binary.LittleEndian, fixed); err != nil {
+			return nil, formatError("fixed file information", -1, "%v", err)
+		}
+		if fixed.Signature != fixedFileInfoSignature {
+			return nil, formatError("fixed file information", -1, "invalid signature 0x%08X", fixed.Signature)
+		}
+		info.Fixed = fixed
+	}
+	for _, child := range root.children {
+		switch child.key {
+		case "StringFileInfo":
+			for _, table := range child.children {
+				for _, s := range table.children {
+					info.Strings = append(info.Strings, &VersionString{
+						Lang:  table.key,
+						Key:   s.key,
+						Value: decodeVersionText(s.value),
+					})
+				}
+			}
+		case "VarFileInfo":
+			for _, v := range child.children {
+				if v.key != "Translation" {
+					continue
+				}
+				for off := 0; off+4 <= len(v.value); off += 4 {
+					info.Translations = append(info.Translations, binary.LittleEndian.Uint32(v.value[off:]))
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// parseVersionBlock parses the version block at the given offset of data, and
+// returns it with the offset of the succeeding block.
+func parseVersionBlock(data []byte, off, depth int) (*versionBlock, int, error) {
+	const hdrSize = 6
+	if off+hdrSize > len(data) {
+		return nil, 0, formatError("version block", -1, "header at offset %d exceeds resource size %d", off, len(data))
+	}
+	length := int(binary.LittleEndian.Uint16(data[off:]))
+	valueLength := int(binary.LittleEndian.Uint16(data[off+2:]))
+	typ := binary.LittleEndian.Uint16(data[off+4:])
+	if length < hdrSize || off+length > len(data) {
+		// Trailing padding is frequently omitted from the last block.
+		if length < hdrSize {
+			return nil, 0, formatError("version block", -1, "invalid length %d at offset %d", length, off)
+		}
+		length = len(data) - off
+	}
+	end := off + length
+	block := &versionBlock{text: typ == 1}
+
+	// Key.
+	pos := off + hdrSize
+	var key []uint16
+	for ; pos+2 <= end; pos += 2 {
+		c := binary.LittleEndian.Uint16(data[pos:])
+		if c == 0 {
+			pos += 2
+			break
+		}
+		key = append(key, c)
+	}
+	block.key = string(utf16.Decode(key))
+	pos = align4(pos)
+
+	// Value; the length of text values is specified in UTF-16 code units.
+	if block.text {
+		valueLength *= 2
+	}
+	if valueLength > 0 {
+		if pos+valueLength > end {
+			// Text values are frequently stored with an off-by-one length.
+			if !block.text || pos >= end {
+				return nil, 0, formatError("version block", -1, "value of %q exceeds block", block.key)
+			}
+			valueLength = end - pos
+		}
+		block.value = data[pos : pos+valueLength]
+		pos = align4(pos + valueLength)
+	}
+
+	// Children.
+	if depth < maxVersionDepth {
+		for pos < end {
+			child, next, err := parseVersionBlock(data[:end], pos, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			block.children = append(block.children, child)
+			pos = next
+		}
+	}
+	return block, align4(end), nil
+}
+
+// align4 rounds off up to a multiple of 4.
+func align4(off int) int {
+	return (off + 3) &^ 3
+}
+
+// decodeVersionText decodes the given NULL-terminated UTF-16 string.
+func decodeVersionText(b []byte) string {
+	var s []uint16
+	for off := 0; off+2 <= len(b); off += 2 {
+		c := binary.LittleEndian.Uint16(b[off:])
+		if c == 0 {
+			break
+		}
+		s = append(s, c)
+	}
+	return string(utf16.Decode(s))
+}
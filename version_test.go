@@ -0,0 +1,146 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// synthVersionBlock returns a version information block with the given key,
+// value and child blocks. Text values are NULL-terminated UTF-16 strings.
+func synthVersionBlock(key string, value []byte, text bool, children ...[]byte) []byte {
+	pad := func(b []byte) []byte {
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	b := make([]byte, 6)
+	for _, c := range utf16.Encode([]rune(key + "\x00")) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	b = pad(b)
+	valueLength := len(value)
+	if text {
+		valueLength /= 2
+	}
+	b = append(b, value...)
+	for _, child := range children {
+		b = append(pad(b), child...)
+	}
+	binary.LittleEndian.PutUint16(b, uint16(len(b)))
+	binary.LittleEndian.PutUint16(b[2:], uint16(valueLength))
+	if text {
+		binary.LittleEndian.PutUint16(b[4:], 1)
+	}
+	return b
+}
+
+// synthVersionText returns the NULL-terminated UTF-16 encoding of s.
+func synthVersionText(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s + "\x00")) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+// synthFixedFileInfo returns a fixed file information structure of file version
+// 1.2.3.4.
+func synthFixedFileInfo() []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, FixedFileInfo{
+		Signature:    fixedFileInfoSignature,
+		StrucVer:     0x10000,
+		FileVerMS:    0x00010002,
+		FileVerLS:    0x00030004,
+		ProductVerMS: 0x00010002,
+	})
+	return buf.Bytes()
+}
+
+func TestParseVersionInfo(t *testing.T) {
+	data := synthVersionBlock("VS_VERSION_INFO", synthFixedFileInfo(), false,
+		synthVersionBlock("StringFileInfo", nil, true,
+			synthVersionBlock("040904B0", nil, true,
+				synthVersionBlock("CompanyName", synthVersionText("ACME"), true),
+				synthVersionBlock("FileVersion", synthVersionText("1.2.3.4"), true),
+			),
+		),
+		synthVersionBlock("VarFileInfo", nil, true,
+			synthVersionBlock("Translation", []byte{0x09, 0x04, 0xB0, 0x04}, false),
+		),
+	)
+	info, err := parseVersionInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Fixed == nil {
+		t.Fatal("expected fixed file information")
+	}
+	if got, want := info.Fixed.FileVer(), "1.2.3.4"; got != want {
+		t.Errorf("file version mismatch; expected %q, got %q", want, got)
+	}
+	if got, want := info.Fixed.ProductVer(), "1.2.0.0"; got != want {
+		t.Errorf("product version mismatch; expected %q, got %q", want, got)
+	}
+	want := []VersionString{
+		{Lang: "040904B0", Key: "CompanyName", Value: "ACME"},
+		{Lang: "040904B0", Key: "FileVersion", Value: "1.2.3.4"},
+	}
+	if len(info.Strings) != len(want) {
+		t.Fatalf("expected %d strings, got %d", len(want), len(info.Strings))
+	}
+	for i, s := range info.Strings {
+		if *s != want[i] {
+			t.Errorf("string %d mismatch; expected %+v, got %+v", i, want[i], *s)
+		}
+	}
+	if got := info.Lookup("CompanyName"); got != "ACME" {
+		t.Errorf("lookup mismatch; expected %q, got %q", "ACME", got)
+	}
+	if len(info.Translations) != 1 || info.Translations[0] != 0x04B00409 {
+		t.Errorf("translations mismatch; expected [0x4b00409], got %#x", info.Translations)
+	}
+
+	// Truncated block.
+	if _, err := parseVersionInfo(data[:4]); err == nil {
+		t.Errorf("expected error for truncated version information")
+	}
+}
+
+func TestVersionInfo(t *testing.T) {
+	// Replace the icon resource with a version resource holding only fixed
+	// file information.
+	b := synthBuffer(synthImage{}.bytes())
+	data := synthVersionBlock("VS_VERSION_INFO", synthFixedFileInfo(), false)
+	base := sectOff(synthResourceRelAddr)
+	b.putStruct(base+0x10, resourceDirEntry{NameOrID: ResourceTypeVersion, Offset: 0x80000000 | 0x18})
+	b.putStruct(base+0x48, ResourceDataEntry{RelAddr: synthRsrcDataRelAddr, Size: uint32(len(data))})
+	copy(b[sectOff(synthRsrcDataRelAddr):], data)
+
+	file, err := New(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := file.VersionInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil || info.Fixed == nil {
+		t.Fatal("expected fixed file information")
+	}
+	if got, want := info.Fixed.FileVer(), "1.2.3.4"; got != want {
+		t.Errorf("file version mismatch; expected %q, got %q", want, got)
+	}
+
+	// No version resource.
+	file, err = New(bytes.NewReader(synthImage{}.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := file.VersionInfo(); err != nil || info != nil {
+		t.Errorf("expected no version information; got %v, %v", info, err)
+	}
+}